@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"net/http"
+	"time"
 
 	subscribers "github.com/MainfluxLabs/rules-engine/engine/nats"
 	"github.com/MainfluxLabs/rules-engine/engine"
 	"github.com/MainfluxLabs/rules-engine/engine/api"
+	"github.com/MainfluxLabs/rules-engine/engine/cache"
 	"github.com/MainfluxLabs/rules-engine/engine/cassandra"
+	"github.com/MainfluxLabs/rules-engine/engine/smtp"
+	"github.com/MainfluxLabs/rules-engine/engine/webhook"
+	"github.com/mainflux/mainflux/writer"
 	"github.com/nats-io/go-nats"
 	"go.uber.org/zap"
 )
@@ -24,17 +34,74 @@ const (
 	envKeyspace   string = "RULES_ENGINE_DB_KEYSPACE"
 	defNatsURL    string = nats.DefaultURL
 	envNatsURL    string = "NATS_URL"
+	defSMTPHost   string = "localhost"
+	envSMTPHost   string = "RULES_ENGINE_SMTP_HOST"
+	defSMTPPort   string = "25"
+	envSMTPPort   string = "RULES_ENGINE_SMTP_PORT"
+	envSMTPUser   string = "RULES_ENGINE_SMTP_USERNAME"
+	envSMTPPass   string = "RULES_ENGINE_SMTP_PASSWORD"
+	defSMTPFrom   string = "rules-engine@localhost"
+	envSMTPFrom   string = "RULES_ENGINE_SMTP_FROM"
+	defSMTPSubj   string = "Mainflux rules engine alert"
+	envSMTPSubj   string = "RULES_ENGINE_SMTP_SUBJECT"
+	defSMTPBody   string = smtp.PlainBody
+	envSMTPBody   string = "RULES_ENGINE_SMTP_BODY"
+	defCtrlSubj   string = subscribers.DefaultControlSubject
+	envCtrlSubj   string = "RULES_ENGINE_CONTROL_SUBJECT"
+	defCtrlTmpl   string = subscribers.DefaultControlTemplate
+	envCtrlTmpl   string = "RULES_ENGINE_CONTROL_TEMPLATE"
+	envStaleness  string = "RULES_ENGINE_STALENESS"
+	defSamples    string = "1000"
+	envSamples    string = "RULES_ENGINE_SAMPLE_LIMIT"
+	defWatch      string = "30s"
+	envWatch      string = "RULES_ENGINE_MISSING_INTERVAL"
+	defWorkers    string = "10"
+	envWorkers    string = "RULES_ENGINE_WORKERS"
+	defQueueSize  string = "100"
+	envQueueSize  string = "RULES_ENGINE_QUEUE_SIZE"
+	defTimeout    string = "30s"
+	envTimeout    string = "RULES_ENGINE_ACTION_TIMEOUT"
+	defDrain      string = "30s"
+	envDrain      string = "RULES_ENGINE_DRAIN_TIMEOUT"
+	defAttempts   string = "3"
+	envAttempts   string = "RULES_ENGINE_RETRY_ATTEMPTS"
+	defBackoff    string = "1s"
+	envBackoff    string = "RULES_ENGINE_RETRY_BACKOFF"
+	defMaxBackoff string = "1m"
+	envMaxBackoff string = "RULES_ENGINE_RETRY_MAX_BACKOFF"
+	envRetries    string = "RULES_ENGINE_RETRY_POLICIES"
+	defHistoryTTL string = "720h"
+	envHistoryTTL string = "RULES_ENGINE_EXECUTIONS_TTL"
 	eventsSubject string = "msg.*"
 	eventsQueue   string = "event.consumer"
 	rulesSubject  string = "rules"
+	dslSubject    string = "rules.*"
 	rulesQueue    string = "rule.consumer"
+	changesSubj   string = "rule.changes"
+	lettersSubj   string = "rule.dead-letters"
+	statesSubj    string = "rule.state"
+	seenSubj      string = "rule.seen"
+	missingLease  string = "missing"
 )
 
 type config struct {
-	Port     string
-	Cluster  string
-	Keyspace string
-	NatsURL  string
+	Port      string
+	Cluster   string
+	Keyspace  string
+	NatsURL   string
+	SMTP      smtp.Config
+	Control   controlConfig
+	Staleness time.Duration
+	Samples   int
+	Watch     time.Duration
+	Dispatch  engine.DispatcherConfig
+	Drain     time.Duration
+	History   time.Duration
+}
+
+type controlConfig struct {
+	Subject  string
+	Template string
 }
 
 func main() {
@@ -43,11 +110,128 @@ func main() {
 		Cluster:  getenv(envCluster, defCluster),
 		Keyspace: getenv(envKeyspace, defKeyspace),
 		NatsURL:  getenv(envNatsURL, defNatsURL),
+		SMTP: smtp.Config{
+			Host:     getenv(envSMTPHost, defSMTPHost),
+			Port:     getenv(envSMTPPort, defSMTPPort),
+			Username: getenv(envSMTPUser, ""),
+			Password: getenv(envSMTPPass, ""),
+			From:     getenv(envSMTPFrom, defSMTPFrom),
+			Subject:  getenv(envSMTPSubj, defSMTPSubj),
+			Body:     getenv(envSMTPBody, defSMTPBody),
+		},
+		Control: controlConfig{
+			Subject:  getenv(envCtrlSubj, defCtrlSubj),
+			Template: getenv(envCtrlTmpl, defCtrlTmpl),
+		},
 	}
 
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	if cfg.SMTP.Body != smtp.PlainBody && cfg.SMTP.Body != smtp.HTMLBody {
+		logger.Error("Invalid type of the email content.", zap.String("body", cfg.SMTP.Body))
+		os.Exit(1)
+	}
+
+	if s := getenv(envStaleness, ""); s != "" {
+		staleness, err := time.ParseDuration(s)
+		if err != nil {
+			logger.Error("Invalid readings staleness limit.", zap.Error(err))
+			os.Exit(1)
+		}
+		cfg.Staleness = staleness
+	}
+
+	samples, err := strconv.Atoi(getenv(envSamples, defSamples))
+	if err != nil || samples < 2 {
+		logger.Error("Invalid limit of the aggregated samples.", zap.Error(err))
+		os.Exit(1)
+	}
+	cfg.Samples = samples
+
+	watch, err := time.ParseDuration(getenv(envWatch, defWatch))
+	if err != nil || watch <= 0 {
+		logger.Error("Invalid interval of the background checks.", zap.Error(err))
+		os.Exit(1)
+	}
+	cfg.Watch = watch
+
+	workers, err := strconv.Atoi(getenv(envWorkers, defWorkers))
+	if err != nil || workers < 1 {
+		logger.Error("Invalid number of action workers.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	queueSize, err := strconv.Atoi(getenv(envQueueSize, defQueueSize))
+	if err != nil || queueSize < 0 {
+		logger.Error("Invalid action queue size.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	timeout, err := time.ParseDuration(getenv(envTimeout, defTimeout))
+	if err != nil {
+		logger.Error("Invalid action timeout.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	drain, err := time.ParseDuration(getenv(envDrain, defDrain))
+	if err != nil {
+		logger.Error("Invalid action draining timeout.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	history, err := time.ParseDuration(getenv(envHistoryTTL, defHistoryTTL))
+	if err != nil || history < 0 {
+		logger.Error("Invalid time to live of the rule executions.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	attempts, err := strconv.Atoi(getenv(envAttempts, defAttempts))
+	if err != nil || attempts < 1 || attempts > engine.MaxRetryAttempts {
+		logger.Error("Invalid number of action attempts.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	backoff, err := engine.ParseDuration(getenv(envBackoff, defBackoff))
+	if err != nil {
+		logger.Error("Invalid retry backoff.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	maxBackoff, err := engine.ParseDuration(getenv(envMaxBackoff, defMaxBackoff))
+	if err != nil {
+		logger.Error("Invalid maximum retry backoff.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	var retries map[string]engine.RetryPolicy
+	if s := getenv(envRetries, ""); s != "" {
+		if err := json.Unmarshal([]byte(s), &retries); err != nil {
+			logger.Error("Invalid retry policies of action types.", zap.Error(err))
+			os.Exit(1)
+		}
+
+		for _, rp := range retries {
+			if rp.Attempts < 1 || rp.Attempts > engine.MaxRetryAttempts {
+				logger.Error("Invalid number of attempts in retry policies of action types.")
+				os.Exit(1)
+			}
+		}
+	}
+
+	cfg.Dispatch = engine.DispatcherConfig{
+		Workers:   workers,
+		QueueSize: queueSize,
+		Timeout:   timeout,
+		Retry:     engine.RetryPolicy{Attempts: attempts, Backoff: backoff, MaxBackoff: maxBackoff},
+		Retries:   retries,
+		Failed: func(action engine.Action, _ writer.Message, rule engine.Rule, err error) {
+			logger.Error(fmt.Sprintf("Failed to execute %s action of rule %s.", action.Type(), rule.ID), zap.Error(err))
+		},
+	}
+	cfg.Drain = drain
+	cfg.History = history
+
 	session, err := cassandra.Connect(strings.Split(cfg.Cluster, sep), cfg.Keyspace)
 	if err != nil {
 		os.Exit(1)
@@ -58,17 +242,46 @@ func main() {
 		os.Exit(1)
 	}
 
-	rulesRepo := cassandra.NewRuleRepository(session)
-	svc := engine.NewService(rulesRepo)
-
 	nc, err := nats.Connect(cfg.NatsURL)
 	if err != nil {
 		os.Exit(1)
 	}
 	defer nc.Close()
 
+	controller, err := subscribers.NewDeviceController(nc, cfg.Control.Subject, cfg.Control.Template)
+	if err != nil {
+		logger.Error("Invalid device control payload template.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	instance := instanceID()
+	rulesRepo := cache.NewRuleRepository(cassandra.NewRuleRepository(session), subscribers.NewRuleNotifier(nc, changesSubj, instance), func(userId string, err error) {
+		logger.Error(fmt.Sprintf("Failed to announce change of rules of user %s.", userId), zap.Error(err))
+	})
+	executors := engine.Executors{
+		engine.SendEmail: engine.NewSendEmailExecutor(smtp.NewMailer(cfg.SMTP)),
+		engine.TurnOff:   engine.NewTurnOffExecutor(controller),
+		engine.Webhook:   webhook.NewExecutor(&http.Client{}),
+		engine.Publish:   subscribers.NewPublishExecutor(nc),
+	}
+	deadLetters := subscribers.NewDeadLetterQueue(cassandra.NewDeadLetterRepository(session), nc, lettersSubj)
+	cfg.Dispatch.DeadLetters = deadLetters
+	dispatcher := engine.NewDispatcher(executors, cfg.Dispatch)
+	svc := engine.NewService(rulesRepo, executors,
+		engine.WithStaleness(cfg.Staleness),
+		engine.WithSampleLimit(cfg.Samples),
+		engine.WithDispatcher(dispatcher),
+		engine.WithDeadLetters(deadLetters),
+		engine.WithExecutions(cassandra.NewExecutionRepository(session, cfg.History)),
+		engine.WithSeenNotifier(subscribers.NewSeenNotifier(nc, seenSubj, instance)),
+		// Lease outlives a couple of missed renewals, after which another
+		// instance takes over checking for missing data.
+		engine.WithLease(cassandra.NewLease(session, missingLease, instance, 3*cfg.Watch)),
+	)
+
 	eventsSubscriber := subscribers.NewEventSubscriber(nc, svc, logger)
-	if _, err = eventsSubscriber.Subscribe(eventsSubject, eventsQueue); err != nil {
+	eventsSub, err := eventsSubscriber.Subscribe(eventsSubject, eventsQueue)
+	if err != nil {
 		logger.Error("Unable to subscribe on Mainflux msg.* topics.", zap.Error(err))
 		os.Exit(1)
 	}
@@ -79,8 +292,67 @@ func main() {
 		os.Exit(1)
 	}
 
-	p := fmt.Sprintf(":%s", cfg.Port)
-	http.ListenAndServe(p, api.MakeHandler(svc))
+	if _, err = rulesSubscriber.Subscribe(dslSubject, rulesQueue); err != nil {
+		logger.Error("Unable to subscribe on rules.* topics.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	statesSubscriber := subscribers.NewStatesSubscriber(nc, svc, logger)
+	if _, err = statesSubscriber.Subscribe(statesSubj, rulesQueue); err != nil {
+		logger.Error("Unable to subscribe on rule state topic.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	changesSubscriber := subscribers.NewChangesSubscriber(nc, svc, instance, logger)
+	if _, err = changesSubscriber.Subscribe(changesSubj, ""); err != nil {
+		logger.Error("Unable to subscribe on rule changes topic.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	seenSubscriber := subscribers.NewSeenSubscriber(nc, svc, instance, logger)
+	if _, err = seenSubscriber.Subscribe(seenSubj, ""); err != nil {
+		logger.Error("Unable to subscribe on reported properties topic.", zap.Error(err))
+		os.Exit(1)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go engine.Watch(watchCtx, svc, cfg.Watch, func(err error) {
+		logger.Error("Failed to check rules in the background.", zap.Error(err))
+	})
+
+	errs := make(chan error, 2)
+
+	go func() {
+		p := fmt.Sprintf(":%s", cfg.Port)
+		errs <- http.ListenAndServe(p, api.MakeHandler(svc))
+	}()
+
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+		errs <- fmt.Errorf("%s", <-c)
+	}()
+
+	err = <-errs
+	logger.Info("Rules engine service terminated.", zap.Error(err))
+
+	// Stop consuming events, so that the queued actions can be drained.
+	eventsSub.Unsubscribe()
+	stopWatch()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Drain)
+	defer cancel()
+	if err := dispatcher.Close(ctx); err != nil {
+		logger.Error("Failed to execute all of the queued actions.", zap.Error(err))
+	}
+}
+
+// instanceID identifies the instance of the service, so that it can ignore
+// the rules changes and reported properties announced by itself, and hold
+// the lease of checking for missing data.
+func instanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
 }
 
 func getenv(key, fallback string) string {
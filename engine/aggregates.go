@@ -0,0 +1,277 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// AggregateFunction determines how the samples within the window of the
+// aggregate condition are combined into the compared value.
+type AggregateFunction string
+
+const (
+	// Avg computes the average value of the samples.
+	Avg AggregateFunction = "avg"
+
+	// Min computes the minimum value of the samples.
+	Min AggregateFunction = "min"
+
+	// Max computes the maximum value of the samples.
+	Max AggregateFunction = "max"
+
+	// Count computes the number of the samples, which are usually limited
+	// by the filter, e.g. to the samples reporting true.
+	Count AggregateFunction = "count"
+
+	// Sum computes the sum of values of the samples.
+	Sum AggregateFunction = "sum"
+
+	// Delta computes the change of the value between the last two samples.
+	Delta AggregateFunction = "delta"
+
+	// Rate computes the change of the value per unit of time between the
+	// oldest and the newest sample.
+	Rate AggregateFunction = "rate"
+)
+
+// DefaultSampleLimit is the default maximum number of samples kept for the
+// single device property.
+const DefaultSampleLimit = 1000
+
+// Aggregate specifies the window of the property's samples which are combined
+// by the function before being compared by the condition. Window is either
+// time-based, covering samples received within the duration, or count-based,
+// covering the specified number of the most recent samples. Delta is always
+// computed over the last two samples, while rate is computed per unit of
+// time over the last two samples, unless time-based window is specified.
+// Samples within the window are combined only if they satisfy the filter,
+// if it is specified.
+type Aggregate struct {
+	Function AggregateFunction `json:"function"`
+	Window   Duration          `json:"window,omitempty"`
+	Samples  int               `json:"samples,omitempty"`
+	Per      Duration          `json:"per,omitempty"`
+	Filter   *Filter           `json:"filter,omitempty"`
+}
+
+// Filter limits the aggregated samples to those whose value satisfies the
+// operator. Same as in the condition, value of the filter is the left-hand
+// side of the operator. Value is either boolean, number or string, which
+// is compared against the corresponding value of the sample.
+type Filter struct {
+	Operator Operator    `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+// matches checks whether the sample satisfies the filter.
+func (f Filter) matches(s sample) bool {
+	switch v := f.Value.(type) {
+	case bool:
+		return f.Operator.Compare(v, s.flag)
+	case string:
+		return f.Operator.Compare(v, s.text)
+	case float64:
+		return f.Operator.Compare(v, s.value)
+	}
+
+	return false
+}
+
+func (a Aggregate) String() string {
+	s := string(a.Function)
+	if a.Per > 0 {
+		s += fmt.Sprintf(" per %s", time.Duration(a.Per))
+	}
+
+	span, size := a.bounds()
+	if size > 0 {
+		return s + fmt.Sprintf(" over %d samples", size)
+	}
+
+	return s + fmt.Sprintf(" over %s", span)
+}
+
+// bounds resolves time-based and count-based window of the aggregate.
+func (a Aggregate) bounds() (time.Duration, int) {
+	switch {
+	case a.Function == Delta, a.Function == Rate && a.Window == 0:
+		return 0, 2
+	}
+
+	return time.Duration(a.Window), a.Samples
+}
+
+type sample struct {
+	value float64
+	flag  bool
+	text  string
+	time  time.Time
+}
+
+func newSample(event writer.Message, now time.Time) sample {
+	return sample{event.Value, event.BoolValue, event.StringValue, now}
+}
+
+// apply combines values of the samples satisfying the filter, failing if
+// there are not enough samples to combine.
+func (a Aggregate) apply(samples []sample) (float64, bool) {
+	if a.Filter != nil {
+		filtered := make([]sample, 0, len(samples))
+		for _, s := range samples {
+			if a.Filter.matches(s) {
+				filtered = append(filtered, s)
+			}
+		}
+		samples = filtered
+	}
+
+	f := a.Function
+	switch f {
+	case Count:
+		return float64(len(samples)), true
+	case Delta, Rate:
+		if len(samples) < 2 {
+			return 0, false
+		}
+
+		first, last := samples[0], samples[len(samples)-1]
+		if f == Delta {
+			return last.value - first.value, true
+		}
+
+		elapsed := last.time.Sub(first.time)
+		if elapsed <= 0 {
+			return 0, false
+		}
+
+		return (last.value - first.value) * float64(a.Per) / float64(elapsed), true
+	}
+
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	v := samples[0].value
+	for _, s := range samples[1:] {
+		switch f {
+		case Min:
+			if s.value < v {
+				v = s.value
+			}
+		case Max:
+			if s.value > v {
+				v = s.value
+			}
+		case Avg, Sum:
+			v += s.value
+		}
+	}
+
+	if f == Avg {
+		v /= float64(len(samples))
+	}
+
+	return v, true
+}
+
+// aggregateStore keeps recent samples of the device properties aggregated
+// by the rules, oldest first. Samples are kept only as long as the widest
+// window of the rules aggregating the property requires, and never more
+// than the limit.
+type aggregateStore struct {
+	mu     sync.Mutex
+	limit  int
+	series map[string]map[readingKey][]sample
+}
+
+func newAggregateStore(limit int) *aggregateStore {
+	return &aggregateStore{
+		limit:  limit,
+		series: make(map[string]map[readingKey][]sample),
+	}
+}
+
+// record keeps sample of the event if any of the rules aggregates its
+// property. Samples of the properties no longer aggregated are dropped.
+func (as *aggregateStore) record(userId string, event writer.Message, rules []Rule, now time.Time) {
+	var (
+		span       time.Duration
+		size       int
+		aggregated bool
+	)
+
+	for _, rule := range rules {
+		for _, cnd := range rule.AllConditions() {
+			if cnd.Aggregate == nil || !cnd.refersTo(event) {
+				continue
+			}
+
+			aggregated = true
+			w, n := cnd.Aggregate.bounds()
+			if w > span {
+				span = w
+			}
+			if n > size {
+				size = n
+			}
+		}
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	key := readingKey{event.Publisher, event.Name}
+	if !aggregated {
+		delete(as.series[userId], key)
+		return
+	}
+
+	user, ok := as.series[userId]
+	if !ok {
+		user = make(map[readingKey][]sample)
+		as.series[userId] = user
+	}
+
+	samples := append(user[key], newSample(event, now))
+
+	// Drop samples which are neither within the widest time-based window,
+	// nor among the samples of the widest count-based window.
+	i := 0
+	for i < len(samples)-size && now.Sub(samples[i].time) >= span {
+		i++
+	}
+
+	if as.limit > 0 && len(samples)-i > as.limit {
+		i = len(samples) - as.limit
+	}
+
+	if i > 0 {
+		samples = append(samples[:0], samples[i:]...)
+	}
+	user[key] = samples
+}
+
+// aggregate combines samples within the window of the aggregate condition.
+func (as *aggregateStore) aggregate(userId string, cnd Condition, now time.Time) (float64, bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	samples := as.series[userId][readingKey{cnd.DeviceID, cnd.Property}]
+	w, n := cnd.Aggregate.bounds()
+
+	i := 0
+	if n > 0 && len(samples) > n {
+		i = len(samples) - n
+	}
+
+	if w > 0 {
+		for i < len(samples) && now.Sub(samples[i].time) >= w {
+			i++
+		}
+	}
+
+	return cnd.Aggregate.apply(samples[i:])
+}
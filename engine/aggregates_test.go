@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateStoreRetention(t *testing.T) {
+	start := time.Now()
+	event := writer.Message{Publisher: "thermometer", Name: "temperature"}
+	key := readingKey{event.Publisher, event.Name}
+
+	rule := func(aggregates ...Aggregate) Rule {
+		var rule Rule
+		for i := range aggregates {
+			rule.Conditions = append(rule.Conditions, Condition{DeviceID: event.Publisher, Property: event.Name, Aggregate: &aggregates[i]})
+		}
+		return rule
+	}
+
+	cases := []struct {
+		desc  string
+		limit int
+		rules []Rule
+		kept  int
+	}{
+		{"time-based window", 0, []Rule{rule(Aggregate{Function: Avg, Window: Duration(10 * time.Second)})}, 10},
+		{"count-based window", 0, []Rule{rule(Aggregate{Function: Avg, Samples: 5})}, 5},
+		{"widest time-based window", 0, []Rule{rule(Aggregate{Function: Avg, Samples: 5}, Aggregate{Function: Max, Window: Duration(20 * time.Second)})}, 20},
+		{"widest count-based window", 0, []Rule{rule(Aggregate{Function: Avg, Samples: 5}), rule(Aggregate{Function: Max, Window: Duration(3 * time.Second)}, Aggregate{Function: Min, Samples: 30})}, 30},
+		{"delta", 0, []Rule{rule(Aggregate{Function: Delta})}, 2},
+		{"rate over time-based window", 0, []Rule{rule(Aggregate{Function: Rate, Per: Duration(time.Second), Window: Duration(30 * time.Second)})}, 30},
+		{"limit", 15, []Rule{rule(Aggregate{Function: Avg, Window: Duration(time.Hour)})}, 15},
+		{"no aggregates", 0, []Rule{{Conditions: []Condition{{DeviceID: event.Publisher, Property: event.Name}}}}, 0},
+	}
+
+	for _, tc := range cases {
+		store := newAggregateStore(tc.limit)
+		for i := 0; i < 100; i++ {
+			store.record("1", event, tc.rules, start.Add(time.Duration(i)*time.Second))
+		}
+
+		assert.Equal(t, tc.kept, len(store.series["1"][key]), fmt.Sprintf("%s: unexpected number of samples", tc.desc))
+	}
+
+	store := newAggregateStore(0)
+	store.record("1", event, []Rule{rule(Aggregate{Function: Count, Samples: 5})}, start)
+	store.record("1", event, []Rule{}, start)
+	_, ok := store.series["1"][key]
+	assert.False(t, ok, "expected samples to be dropped once property is no longer aggregated")
+}
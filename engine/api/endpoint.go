@@ -4,9 +4,78 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/gocql/gocql"
 	"github.com/MainfluxLabs/rules-engine/engine"
 )
 
+func createRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(createRuleReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		rule := b.rule.ToDomain(gocql.TimeUUID().String())
+		if err := svc.SaveRule(rule); err != nil {
+			return nil, err
+		}
+
+		return createRuleRes{rule}, nil
+	}
+}
+
+func updateRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(updateRuleReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		rule := b.rule.ToDomain(b.ruleId)
+		if err := svc.UpdateRule(rule); err != nil {
+			return nil, err
+		}
+
+		// Updated rule stays enabled or disabled.
+		updated, err := svc.ViewRule(b.userId, b.ruleId)
+		if err != nil {
+			return nil, err
+		}
+
+		return viewRuleRes{*updated}, nil
+	}
+}
+
+func patchRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(patchRuleReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		existing, err := svc.ViewRule(b.userId, b.ruleId)
+		if err != nil {
+			return nil, err
+		}
+
+		spec, err := b.apply(*existing)
+		if err != nil {
+			return nil, err
+		}
+
+		rule := spec.ToDomain(b.ruleId)
+		rule.Disabled = existing.Disabled
+		if err := svc.UpdateRule(rule); err != nil {
+			return nil, err
+		}
+
+		return viewRuleRes{rule}, nil
+	}
+}
+
 func retrieveRuleEndpoint(svc engine.Service) endpoint.Endpoint {
 	return func(_ context.Context, body interface{}) (interface{}, error) {
 		b := body.(viewRuleReq)
@@ -32,7 +101,7 @@ func retrieveRulesEndpoint(svc engine.Service) endpoint.Endpoint {
 			return nil, err
 		}
 
-		rulesList, err := svc.ListRules(b.userId)
+		rulesList, err := svc.ListRules(b.userId, engine.RuleFilter{Disabled: b.disabled})
 		if err != nil {
 			return nil, err
 		}
@@ -56,3 +125,120 @@ func removeRuleEndpoint(svc engine.Service) endpoint.Endpoint {
 		return removeRes{}, nil
 	}
 }
+
+func enableRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(viewRuleReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		if err := svc.EnableRule(b.userId, b.ruleId); err != nil {
+			return nil, err
+		}
+
+		return stateRes{}, nil
+	}
+}
+
+func disableRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(viewRuleReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		if err := svc.DisableRule(b.userId, b.ruleId); err != nil {
+			return nil, err
+		}
+
+		return stateRes{}, nil
+	}
+}
+
+func simulateRuleEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(simulateReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		var rule engine.Rule
+		if b.Rule != nil {
+			rule = b.Rule.ToDomain("")
+		} else {
+			stored, err := svc.ViewRule(b.userId, b.ruleId)
+			if err != nil {
+				return nil, err
+			}
+			rule = *stored
+		}
+
+		traces, err := svc.SimulateRule(rule, b.messages())
+		if err != nil {
+			return nil, err
+		}
+
+		return newSimulateRes(traces), nil
+	}
+}
+
+func listDeadLettersEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(listDeadLettersReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		letters, err := svc.ListDeadLetters(b.userId)
+		if err != nil {
+			return nil, err
+		}
+
+		return newListDeadLettersRes(letters), nil
+	}
+}
+
+func replayDeadLetterEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(ctx context.Context, body interface{}) (interface{}, error) {
+		b := body.(replayReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		if err := svc.ReplayDeadLetter(ctx, b.userId, b.letterId); err != nil {
+			return nil, err
+		}
+
+		return replayRes{}, nil
+	}
+}
+
+func listExecutionsEndpoint(svc engine.Service) endpoint.Endpoint {
+	return func(_ context.Context, body interface{}) (interface{}, error) {
+		b := body.(listExecutionsReq)
+
+		if err := b.validate(); err != nil {
+			return nil, err
+		}
+
+		filter := engine.ExecutionFilter{
+			From:   b.from,
+			To:     b.to,
+			Offset: b.offset,
+			Limit:  b.limit,
+		}
+
+		page, err := svc.ListExecutions(b.userId, b.ruleId, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		return newListExecutionsRes(page), nil
+	}
+}
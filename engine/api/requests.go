@@ -1,8 +1,13 @@
 package api
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/asaskevich/govalidator"
 	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/schema"
+	"github.com/mainflux/mainflux/writer"
 )
 
 type apiReq interface {
@@ -23,7 +28,8 @@ func (req viewRuleReq) validate() error {
 }
 
 type listRulesReq struct {
-	userId string
+	userId   string
+	disabled *bool
 }
 
 func (req listRulesReq) validate() error {
@@ -33,3 +39,212 @@ func (req listRulesReq) validate() error {
 
 	return nil
 }
+
+type createRuleReq struct {
+	userId string
+	rule   schema.Rule
+}
+
+func (req createRuleReq) validate() error {
+	if !govalidator.IsUUID(req.userId) {
+		return engine.ErrMalformedUrl
+	}
+
+	return req.rule.Validate()
+}
+
+type updateRuleReq struct {
+	userId string
+	ruleId string
+	rule   schema.Rule
+}
+
+func (req updateRuleReq) validate() error {
+	if !govalidator.IsUUID(req.userId) || !govalidator.IsUUID(req.ruleId) {
+		return engine.ErrMalformedUrl
+	}
+
+	return req.rule.Validate()
+}
+
+// patchRuleReq holds top-level fields of the rule which replace the ones of
+// the existing rule. Fields set to null are removed.
+type patchRuleReq struct {
+	userId string
+	ruleId string
+	patch  map[string]json.RawMessage
+}
+
+func (req patchRuleReq) validate() error {
+	if !govalidator.IsUUID(req.userId) || !govalidator.IsUUID(req.ruleId) {
+		return engine.ErrMalformedUrl
+	}
+
+	if len(req.patch) == 0 {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// apply merges the patch into the existing rule, producing specification
+// of the updated rule.
+func (req patchRuleReq) apply(rule engine.Rule) (schema.Rule, error) {
+	var r schema.Rule
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return r, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return r, err
+	}
+
+	for k, v := range req.patch {
+		if string(v) == "null" {
+			delete(fields, k)
+			continue
+		}
+		fields[k] = v
+	}
+
+	if data, err = json.Marshal(fields); err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, engine.ErrMalformedEntity
+	}
+	r.UserId = req.userId
+
+	return r, r.Validate()
+}
+
+// event represents the device reading, as stored by the Mainflux writers.
+type event struct {
+	Publisher   string  `json:"publisher"`
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit,omitempty"`
+	Value       float64 `json:"value,omitempty"`
+	StringValue string  `json:"stringValue,omitempty"`
+	BoolValue   bool    `json:"boolValue,omitempty"`
+	Time        float64 `json:"time,omitempty"`
+}
+
+func newEvent(msg writer.Message) event {
+	return event{
+		Publisher:   msg.Publisher,
+		Name:        msg.Name,
+		Unit:        msg.Unit,
+		Value:       msg.Value,
+		StringValue: msg.StringValue,
+		BoolValue:   msg.BoolValue,
+		Time:        msg.Time,
+	}
+}
+
+func (e event) toMessage() writer.Message {
+	return writer.Message{
+		Publisher:   e.Publisher,
+		Name:        e.Name,
+		Unit:        e.Unit,
+		Value:       e.Value,
+		StringValue: e.StringValue,
+		BoolValue:   e.BoolValue,
+		Time:        e.Time,
+	}
+}
+
+// simulateReq specifies events to simulate the rule against. The rule is
+// either the stored one, identified by the URL, or the inline one.
+type simulateReq struct {
+	userId string
+	ruleId string
+	Rule   *schema.Rule `json:"rule"`
+	Events []event      `json:"events"`
+}
+
+func (req simulateReq) validate() error {
+	if !govalidator.IsUUID(req.userId) || (req.ruleId != "" && !govalidator.IsUUID(req.ruleId)) {
+		return engine.ErrMalformedUrl
+	}
+
+	if (req.ruleId == "") == (req.Rule == nil) || len(req.Events) == 0 {
+		return engine.ErrMalformedEntity
+	}
+
+	for _, e := range req.Events {
+		if e.Publisher == "" || e.Name == "" || e.Time < 0 {
+			return engine.ErrMalformedEntity
+		}
+	}
+
+	if req.Rule != nil {
+		return req.Rule.Validate()
+	}
+
+	return nil
+}
+
+func (req simulateReq) messages() []writer.Message {
+	var msgs []writer.Message
+	for _, e := range req.Events {
+		msgs = append(msgs, e.toMessage())
+	}
+
+	return msgs
+}
+
+type listDeadLettersReq struct {
+	userId string
+}
+
+func (req listDeadLettersReq) validate() error {
+	if !govalidator.IsUUID(req.userId) {
+		return engine.ErrMalformedUrl
+	}
+
+	return nil
+}
+
+type replayReq struct {
+	userId   string
+	letterId string
+}
+
+func (req replayReq) validate() error {
+	if !govalidator.IsUUID(req.userId) || !govalidator.IsUUID(req.letterId) {
+		return engine.ErrMalformedUrl
+	}
+
+	return nil
+}
+
+const maxLimit uint64 = 100
+
+type listExecutionsReq struct {
+	userId string
+	ruleId string
+	from   time.Time
+	to     time.Time
+	offset uint64
+	limit  uint64
+}
+
+func (req listExecutionsReq) validate() error {
+	if !govalidator.IsUUID(req.userId) || !govalidator.IsUUID(req.ruleId) {
+		return engine.ErrMalformedUrl
+	}
+
+	if req.limit == 0 || req.limit > maxLimit {
+		return engine.ErrMalformedUrl
+	}
+
+	if !req.from.IsZero() && !req.to.IsZero() && !req.from.Before(req.to) {
+		return engine.ErrMalformedUrl
+	}
+
+	return nil
+}
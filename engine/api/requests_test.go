@@ -3,6 +3,7 @@ package api
 import (
 	"testing"
 	"fmt"
+	"encoding/json"
 
 	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/assert"
@@ -38,8 +39,50 @@ func TestListRulesReqValidation(t *testing.T) {
 	}
 
 	for i, tc := range cases {
-		req := listRulesReq{tc.userId}
+		req := listRulesReq{userId: tc.userId}
 		err := req.validate()
 		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
 	}
 }
+
+func TestPatchRuleReqApply(t *testing.T) {
+	userId := gocql.TimeUUID().String()
+	deviceId := gocql.TimeUUID().String()
+	existing := engine.Rule{
+		ID:         gocql.TimeUUID().String(),
+		UserId:     userId,
+		Name:       "rule01",
+		Conditions: []engine.Condition{{DeviceID: deviceId, Property: "temp", Operator: engine.Gt, Type: engine.Numeric, Value: float64(30)}},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{engine.TurnOffAction{Name: engine.TurnOff, DeviceId: deviceId}},
+	}
+
+	cases := []struct {
+		patch   string
+		name    string
+		trigger engine.TriggerMode
+		err     error
+	}{
+		{`{"name":"rule02"}`, "rule02", engine.Rising, nil},
+		{`{"trigger":null}`, "rule01", "", nil},
+		{`{"actions":[]}`, "", "", engine.ErrMalformedEntity},
+		{`{"trigger":"edge"}`, "", "", engine.ErrMalformedEntity},
+	}
+
+	for i, tc := range cases {
+		req := patchRuleReq{userId: userId, ruleId: existing.ID}
+		json.Unmarshal([]byte(tc.patch), &req.patch)
+
+		spec, err := req.apply(existing)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		if err != nil {
+			continue
+		}
+
+		rule := spec.ToDomain(existing.ID)
+		assert.Equal(t, tc.name, rule.Name, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.trigger, rule.Trigger, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, existing.Conditions, rule.Conditions, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, existing.Actions, rule.Actions, fmt.Sprintf("failed at %d\n", i))
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/MainfluxLabs/rules-engine/engine"
 )
@@ -31,6 +32,24 @@ func (res viewRuleRes) empty() bool {
 	return false
 }
 
+type createRuleRes struct {
+	engine.Rule
+}
+
+func (res createRuleRes) code() int {
+	return http.StatusCreated
+}
+
+func (res createRuleRes) headers() map[string]string {
+	return map[string]string{
+		"Location": fmt.Sprintf("/users/%s/rules/%s", res.UserId, res.ID),
+	}
+}
+
+func (res createRuleRes) empty() bool {
+	return false
+}
+
 type listRulesRes struct {
 	Rules []engine.Rule `json:"rules"`
 	count int
@@ -63,3 +82,160 @@ func (res removeRes) headers() map[string]string {
 func (res removeRes) empty() bool {
 	return false
 }
+
+type stateRes struct{}
+
+func (res stateRes) code() int {
+	return http.StatusNoContent
+}
+
+func (res stateRes) headers() map[string]string {
+	return map[string]string{}
+}
+
+func (res stateRes) empty() bool {
+	return true
+}
+
+type traceRes struct {
+	Event event `json:"event"`
+	engine.Trace
+}
+
+type simulateRes struct {
+	Traces []traceRes `json:"traces"`
+}
+
+func newSimulateRes(traces []engine.Trace) simulateRes {
+	res := simulateRes{Traces: []traceRes{}}
+	for _, t := range traces {
+		res.Traces = append(res.Traces, traceRes{newEvent(t.Event), t})
+	}
+
+	return res
+}
+
+func (res simulateRes) code() int {
+	return http.StatusOK
+}
+
+func (res simulateRes) headers() map[string]string {
+	return map[string]string{}
+}
+
+func (res simulateRes) empty() bool {
+	return false
+}
+
+type deadLetterRes struct {
+	ID       string        `json:"id"`
+	RuleId   string        `json:"ruleId"`
+	Action   engine.Action `json:"action"`
+	Event    event         `json:"event"`
+	Attempts int           `json:"attempts"`
+	Error    string        `json:"error"`
+	Time     time.Time     `json:"time"`
+}
+
+type listDeadLettersRes struct {
+	DeadLetters []deadLetterRes `json:"deadLetters"`
+}
+
+func newListDeadLettersRes(letters []engine.DeadLetter) listDeadLettersRes {
+	res := listDeadLettersRes{DeadLetters: []deadLetterRes{}}
+	for _, dl := range letters {
+		res.DeadLetters = append(res.DeadLetters, deadLetterRes{
+			ID:       dl.ID,
+			RuleId:   dl.RuleId,
+			Action:   dl.Action,
+			Event:    newEvent(dl.Event),
+			Attempts: dl.Attempts,
+			Error:    dl.Error,
+			Time:     dl.Time,
+		})
+	}
+
+	return res
+}
+
+func (res listDeadLettersRes) code() int {
+	return http.StatusOK
+}
+
+func (res listDeadLettersRes) headers() map[string]string {
+	return map[string]string{
+		"X-Count": fmt.Sprintf("%d", len(res.DeadLetters)),
+	}
+}
+
+func (res listDeadLettersRes) empty() bool {
+	return false
+}
+
+type replayRes struct{}
+
+func (res replayRes) code() int {
+	return http.StatusNoContent
+}
+
+func (res replayRes) headers() map[string]string {
+	return map[string]string{}
+}
+
+func (res replayRes) empty() bool {
+	return true
+}
+
+type executionRes struct {
+	ID       string                `json:"id"`
+	Event    event                 `json:"event"`
+	Resolved bool                  `json:"resolved"`
+	Time     time.Time             `json:"time"`
+	Actions  []engine.ActionResult `json:"actions"`
+}
+
+type listExecutionsRes struct {
+	Total      uint64         `json:"total"`
+	Offset     uint64         `json:"offset"`
+	Limit      uint64         `json:"limit"`
+	Executions []executionRes `json:"executions"`
+}
+
+func newListExecutionsRes(page engine.ExecutionPage) listExecutionsRes {
+	res := listExecutionsRes{
+		Total:      page.Total,
+		Offset:     page.Offset,
+		Limit:      page.Limit,
+		Executions: []executionRes{},
+	}
+
+	for _, exec := range page.Executions {
+		res.Executions = append(res.Executions, executionRes{
+			ID:       exec.ID,
+			Event:    newEvent(exec.Event),
+			Resolved: exec.Resolved,
+			Time:     exec.Time,
+			Actions:  exec.Actions,
+		})
+	}
+
+	return res
+}
+
+func (res listExecutionsRes) code() int {
+	return http.StatusOK
+}
+
+func (res listExecutionsRes) headers() map[string]string {
+	return map[string]string{
+		"X-Count": fmt.Sprintf("%d", res.Total),
+	}
+}
+
+func (res listExecutionsRes) empty() bool {
+	return false
+}
+
+type errorRes struct {
+	Err string `json:"error"`
+}
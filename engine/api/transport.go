@@ -4,12 +4,20 @@ import (
 	"net/http"
 	"context"
 	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
 
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/go-zoo/bone"
 	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/dsl"
+	"github.com/MainfluxLabs/rules-engine/engine/schema"
 )
 
+const defLimit uint64 = 10
+
 // MakeHandler returns a HTTP handler for API endpoints.
 func MakeHandler(svc engine.Service) http.Handler {
 	opts := []kithttp.ServerOption{
@@ -18,6 +26,41 @@ func MakeHandler(svc engine.Service) http.Handler {
 
 	r := bone.New()
 
+	r.Post("/users/:userId/rules", kithttp.NewServer(
+		createRuleEndpoint(svc),
+		decodeCreate,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Post("/users/:userId/rules/simulate", kithttp.NewServer(
+		simulateRuleEndpoint(svc),
+		decodeSimulate,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Post("/users/:userId/rules/:ruleId/simulate", kithttp.NewServer(
+		simulateRuleEndpoint(svc),
+		decodeSimulate,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Put("/users/:userId/rules/:ruleId", kithttp.NewServer(
+		updateRuleEndpoint(svc),
+		decodeUpdate,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Patch("/users/:userId/rules/:ruleId", kithttp.NewServer(
+		patchRuleEndpoint(svc),
+		decodePatch,
+		encodeResponse,
+		opts...,
+	))
+
 	r.Get("/users/:userId/rules", kithttp.NewServer(
 		retrieveRulesEndpoint(svc),
 		decodeList,
@@ -32,6 +75,27 @@ func MakeHandler(svc engine.Service) http.Handler {
 		opts...,
 	))
 
+	r.Post("/users/:userId/rules/:ruleId/enable", kithttp.NewServer(
+		enableRuleEndpoint(svc),
+		decodeView,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Post("/users/:userId/rules/:ruleId/disable", kithttp.NewServer(
+		disableRuleEndpoint(svc),
+		decodeView,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Get("/users/:userId/rules/:ruleId/executions", kithttp.NewServer(
+		listExecutionsEndpoint(svc),
+		decodeListExecutions,
+		encodeResponse,
+		opts...,
+	))
+
 	r.Delete("/users/:userId/rules/:ruleId", kithttp.NewServer(
 		removeRuleEndpoint(svc),
 		decodeView,
@@ -39,11 +103,137 @@ func MakeHandler(svc engine.Service) http.Handler {
 		opts...,
 	))
 
+	r.Get("/users/:userId/dead-letters", kithttp.NewServer(
+		listDeadLettersEndpoint(svc),
+		decodeListDeadLetters,
+		encodeResponse,
+		opts...,
+	))
+
+	r.Post("/users/:userId/dead-letters/:letterId/replay", kithttp.NewServer(
+		replayDeadLetterEndpoint(svc),
+		decodeReplay,
+		encodeResponse,
+		opts...,
+	))
+
 	r.GetFunc("/health", engine.Health())
 
 	return r
 }
 
+func decodeCreate(_ context.Context, r *http.Request) (interface{}, error) {
+	rule, err := decodeRule(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := createRuleReq{
+		userId: bone.GetValue(r, "userId"),
+		rule:   rule,
+	}
+
+	return req, nil
+}
+
+func decodeUpdate(_ context.Context, r *http.Request) (interface{}, error) {
+	rule, err := decodeRule(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := updateRuleReq{
+		userId: bone.GetValue(r, "userId"),
+		ruleId: bone.GetValue(r, "ruleId"),
+		rule:   rule,
+	}
+
+	return req, nil
+}
+
+func decodePatch(_ context.Context, r *http.Request) (interface{}, error) {
+	if !isJSON(r) {
+		return nil, engine.ErrUnsupportedContentType
+	}
+
+	req := patchRuleReq{
+		userId: bone.GetValue(r, "userId"),
+		ruleId: bone.GetValue(r, "ruleId"),
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req.patch); err != nil {
+		return nil, engine.ErrMalformedEntity
+	}
+
+	return req, nil
+}
+
+func decodeSimulate(_ context.Context, r *http.Request) (interface{}, error) {
+	if !isJSON(r) {
+		return nil, engine.ErrUnsupportedContentType
+	}
+
+	req := simulateReq{
+		userId: bone.GetValue(r, "userId"),
+		ruleId: bone.GetValue(r, "ruleId"),
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, engine.ErrMalformedEntity
+	}
+
+	if req.Rule != nil {
+		req.Rule.UserId = req.userId
+	}
+
+	return req, nil
+}
+
+// decodeRule decodes rule specification from the request body, taking the
+// owner of the rule from the URL. Body is either JSON or single rule
+// specified using the DSL.
+func decodeRule(r *http.Request) (schema.Rule, error) {
+	var rule schema.Rule
+
+	switch {
+	case isJSON(r):
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			return rule, engine.ErrMalformedEntity
+		}
+	case isDSL(r):
+		src, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return rule, err
+		}
+
+		rules, err := dsl.Parse(string(src))
+		if err != nil {
+			return rule, err
+		}
+
+		if len(rules) != 1 {
+			return rule, engine.ErrMalformedEntity
+		}
+
+		if rule, err = schema.FromDomain(rules[0]); err != nil {
+			return rule, err
+		}
+	default:
+		return rule, engine.ErrUnsupportedContentType
+	}
+	rule.UserId = bone.GetValue(r, "userId")
+
+	return rule, nil
+}
+
+func isJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+func isDSL(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), dsl.ContentType)
+}
+
 func decodeView(_ context.Context, r *http.Request) (interface{}, error) {
 	req := viewRuleReq{
 		userId: bone.GetValue(r, "userId"),
@@ -58,6 +248,94 @@ func decodeList(_ context.Context, r *http.Request) (interface{}, error) {
 		userId: bone.GetValue(r, "userId"),
 	}
 
+	if value := r.URL.Query().Get("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, engine.ErrMalformedUrl
+		}
+		req.disabled = &disabled
+	}
+
+	return req, nil
+}
+
+func decodeListExecutions(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+
+	from, err := timeQuery(q.Get("from"))
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := timeQuery(q.Get("to"))
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := uintQuery(q.Get("offset"), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := uintQuery(q.Get("limit"), defLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	req := listExecutionsReq{
+		userId: bone.GetValue(r, "userId"),
+		ruleId: bone.GetValue(r, "ruleId"),
+		from:   from,
+		to:     to,
+		offset: offset,
+		limit:  limit,
+	}
+
+	return req, nil
+}
+
+// timeQuery parses optional RFC 3339 time of the query parameter.
+func timeQuery(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, engine.ErrMalformedUrl
+	}
+
+	return t, nil
+}
+
+// uintQuery parses optional unsigned integer of the query parameter.
+func uintQuery(value string, def uint64) (uint64, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, engine.ErrMalformedUrl
+	}
+
+	return n, nil
+}
+
+func decodeListDeadLetters(_ context.Context, r *http.Request) (interface{}, error) {
+	req := listDeadLettersReq{
+		userId: bone.GetValue(r, "userId"),
+	}
+
+	return req, nil
+}
+
+func decodeReplay(_ context.Context, r *http.Request) (interface{}, error) {
+	req := replayReq{
+		userId:   bone.GetValue(r, "userId"),
+		letterId: bone.GetValue(r, "letterId"),
+	}
+
 	return req, nil
 }
 
@@ -87,6 +365,8 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 		w.WriteHeader(http.StatusBadRequest)
 	case engine.ErrMalformedUrl:
 		w.WriteHeader(http.StatusBadRequest)
+	case engine.ErrUnsupportedContentType:
+		w.WriteHeader(http.StatusUnsupportedMediaType)
 	case engine.ErrNotFound:
 		w.WriteHeader(http.StatusNotFound)
 	default:
@@ -95,6 +375,12 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 			return
 		}
 
+		if e, ok := err.(*dsl.Error); ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(errorRes{e.Error()})
+			return
+		}
+
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
@@ -1,12 +1,19 @@
 package api
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/assert"
 	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
 )
 
 func TestHealth(t *testing.T) {
@@ -18,3 +25,265 @@ func TestHealth(t *testing.T) {
 
 	assert.Equal(t, rr.Code, http.StatusOK, "bad status code")
 }
+
+func TestSaveRules(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	handler := MakeHandler(engine.NewService(repo, engine.Executors{}))
+
+	userId := gocql.TimeUUID().String()
+	deviceId := gocql.TimeUUID().String()
+	existing := engine.Rule{ID: gocql.TimeUUID().String(), UserId: userId, Name: "rule01"}
+	repo.Save(existing)
+
+	valid := fmt.Sprintf(`{"name":"rule02","conditions":[{"deviceId":"%s","property":"temp","operator":">","value":30}],"actions":[{"name":"TURN OFF","deviceId":"%s"}]}`, deviceId, deviceId)
+	invalid := fmt.Sprintf(`{"name":"rule02","conditions":[{"deviceId":"%s","property":"temp","operator":">","value":true}],"actions":[{"name":"TURN OFF","deviceId":"%s"}]}`, deviceId, deviceId)
+	dslRule := fmt.Sprintf("RULE rule02:\n  %s[\"temp\"] > 30\nTRIGGERS\n  TURN OFF %s\n", deviceId, deviceId)
+	missing := gocql.TimeUUID().String()
+
+	cases := []struct {
+		desc        string
+		method      string
+		url         string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"create rule", "POST", fmt.Sprintf("/users/%s/rules", userId), "application/json", valid, http.StatusCreated},
+		{"create invalid rule", "POST", fmt.Sprintf("/users/%s/rules", userId), "application/json", invalid, http.StatusBadRequest},
+		{"create rule from malformed JSON", "POST", fmt.Sprintf("/users/%s/rules", userId), "application/json", "{", http.StatusBadRequest},
+		{"create rule without content type", "POST", fmt.Sprintf("/users/%s/rules", userId), "", valid, http.StatusUnsupportedMediaType},
+		{"create rule with malformed user id", "POST", "/users/malformed/rules", "application/json", valid, http.StatusBadRequest},
+		{"update rule", "PUT", fmt.Sprintf("/users/%s/rules/%s", userId, existing.ID), "application/json", valid, http.StatusOK},
+		{"update non-existing rule", "PUT", fmt.Sprintf("/users/%s/rules/%s", userId, missing), "application/json", valid, http.StatusNotFound},
+		{"create rule using DSL", "POST", fmt.Sprintf("/users/%s/rules", userId), "text/x-rules-dsl", dslRule, http.StatusCreated},
+		{"create rules using DSL", "POST", fmt.Sprintf("/users/%s/rules", userId), "text/x-rules-dsl", dslRule + dslRule, http.StatusBadRequest},
+		{"create rule using invalid DSL", "POST", fmt.Sprintf("/users/%s/rules", userId), "text/x-rules-dsl", "RULE rule02", http.StatusBadRequest},
+		{"update rule using DSL", "PUT", fmt.Sprintf("/users/%s/rules/%s", userId, existing.ID), "text/x-rules-dsl", dslRule, http.StatusOK},
+		{"patch rule", "PATCH", fmt.Sprintf("/users/%s/rules/%s", userId, existing.ID), "application/json", `{"name":"rule03"}`, http.StatusOK},
+		{"patch rule with invalid field", "PATCH", fmt.Sprintf("/users/%s/rules/%s", userId, existing.ID), "application/json", `{"trigger":"edge"}`, http.StatusBadRequest},
+		{"patch non-existing rule", "PATCH", fmt.Sprintf("/users/%s/rules/%s", userId, missing), "application/json", `{"name":"rule03"}`, http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		res := request(handler, tc.method, tc.url, tc.contentType, strings.NewReader(tc.body))
+		assert.Equal(t, tc.code, res.Code, fmt.Sprintf("%s: unexpected status code", tc.desc))
+	}
+
+	rule, _ := repo.One(userId, existing.ID)
+	assert.Equal(t, "rule03", rule.Name, "patch not applied")
+	assert.Equal(t, 1, len(rule.Conditions), "patch removed conditions")
+}
+
+func TestCreateRuleLocation(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	handler := MakeHandler(engine.NewService(repo, engine.Executors{}))
+
+	userId := gocql.TimeUUID().String()
+	deviceId := gocql.TimeUUID().String()
+	body := fmt.Sprintf(`{"conditions":[{"deviceId":"%s","property":"temp","operator":">","value":30}],"actions":[{"name":"TURN OFF","deviceId":"%s"}]}`, deviceId, deviceId)
+
+	res := request(handler, "POST", fmt.Sprintf("/users/%s/rules", userId), "application/json", strings.NewReader(body))
+	assert.Equal(t, http.StatusCreated, res.Code, "unexpected status code")
+
+	rules := repo.All(userId)
+	assert.Equal(t, 1, len(rules), "rule not saved")
+	assert.Equal(t, fmt.Sprintf("/users/%s/rules/%s", userId, rules[0].ID), res.Header().Get("Location"), "unexpected location")
+}
+
+func request(handler http.Handler, method, url, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestCreateRuleSyntaxError(t *testing.T) {
+	handler := MakeHandler(engine.NewService(mocks.NewRuleRepository(), engine.Executors{}))
+
+	userId := gocql.TimeUUID().String()
+	res := request(handler, "POST", fmt.Sprintf("/users/%s/rules", userId), "text/x-rules-dsl", strings.NewReader("RULE rule01:\nTRIGGERS"))
+	assert.Equal(t, http.StatusBadRequest, res.Code, "unexpected status code")
+	assert.Contains(t, res.Body.String(), "line 2, column 1", "missing error position")
+}
+
+func TestSimulateRule(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	handler := MakeHandler(engine.NewService(repo, engine.Executors{}))
+
+	userId := gocql.TimeUUID().String()
+	deviceId := gocql.TimeUUID().String()
+	stored := engine.Rule{
+		ID:         gocql.TimeUUID().String(),
+		UserId:     userId,
+		Conditions: []engine.Condition{{DeviceID: deviceId, Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{engine.TurnOffAction{Name: engine.TurnOff, DeviceId: deviceId}},
+	}
+	repo.Save(stored)
+
+	rule := fmt.Sprintf(`{"conditions":[{"deviceId":"%s","property":"temp","operator":"<","value":30}],"actions":[{"name":"TURN OFF","deviceId":"%s"}]}`, deviceId, deviceId)
+	events := fmt.Sprintf(`[{"publisher":"%s","name":"temp","value":35,"time":10},{"publisher":"%s","name":"temp","value":25,"time":20}]`, deviceId, deviceId)
+	simulate := fmt.Sprintf("/users/%s/rules/simulate", userId)
+
+	cases := []struct {
+		desc string
+		url  string
+		body string
+		code int
+	}{
+		{"simulate inline rule", simulate, fmt.Sprintf(`{"rule":%s,"events":%s}`, rule, events), http.StatusOK},
+		{"simulate stored rule", fmt.Sprintf("/users/%s/rules/%s/simulate", userId, stored.ID), fmt.Sprintf(`{"events":%s}`, events), http.StatusOK},
+		{"simulate non-existing rule", fmt.Sprintf("/users/%s/rules/%s/simulate", userId, gocql.TimeUUID().String()), fmt.Sprintf(`{"events":%s}`, events), http.StatusNotFound},
+		{"simulate both rules", fmt.Sprintf("/users/%s/rules/%s/simulate", userId, stored.ID), fmt.Sprintf(`{"rule":%s,"events":%s}`, rule, events), http.StatusBadRequest},
+		{"simulate without rule", simulate, fmt.Sprintf(`{"events":%s}`, events), http.StatusBadRequest},
+		{"simulate without events", simulate, fmt.Sprintf(`{"rule":%s,"events":[]}`, rule), http.StatusBadRequest},
+		{"simulate event without publisher", simulate, fmt.Sprintf(`{"rule":%s,"events":[{"name":"temp","value":35}]}`, rule), http.StatusBadRequest},
+		{"simulate invalid rule", simulate, fmt.Sprintf(`{"rule":{"conditions":[],"actions":[]},"events":%s}`, events), http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		res := request(handler, "POST", tc.url, "application/json", strings.NewReader(tc.body))
+		assert.Equal(t, tc.code, res.Code, fmt.Sprintf("%s: unexpected status code", tc.desc))
+
+		if tc.code == http.StatusOK {
+			body := res.Body.String()
+			assert.Contains(t, body, `"matched":true`, fmt.Sprintf("%s: missing matched trace", tc.desc))
+			assert.Contains(t, body, `"matched":false`, fmt.Sprintf("%s: missing unmatched trace", tc.desc))
+			assert.Contains(t, body, `"publisher":"`+deviceId+`"`, fmt.Sprintf("%s: missing event", tc.desc))
+		}
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	letters := mocks.NewDeadLetterRepository()
+	controller := mocks.NewDeviceController()
+	handler := MakeHandler(engine.NewService(mocks.NewRuleRepository(), engine.Executors{engine.TurnOff: engine.NewTurnOffExecutor(controller)}, engine.WithDeadLetters(letters)))
+
+	userId := gocql.TimeUUID().String()
+	deviceId := gocql.TimeUUID().String()
+	dl := engine.DeadLetter{
+		UserId:   userId,
+		RuleId:   gocql.TimeUUID().String(),
+		Action:   engine.TurnOffAction{Name: engine.TurnOff, DeviceId: deviceId},
+		Attempts: 3,
+		Error:    "connection closed",
+	}
+	letterId, _ := letters.Save(dl)
+
+	res := request(handler, "GET", fmt.Sprintf("/users/%s/dead-letters", userId), "", nil)
+	assert.Equal(t, http.StatusOK, res.Code, "unexpected status code")
+	assert.Equal(t, "1", res.Header().Get("X-Count"), "unexpected number of dead letters")
+	assert.Contains(t, res.Body.String(), `"error":"connection closed"`, "missing failure")
+	assert.Contains(t, res.Body.String(), `"deviceId":"`+deviceId+`"`, "missing action")
+
+	cases := []struct {
+		desc   string
+		method string
+		url    string
+		code   int
+	}{
+		{"list dead letters with malformed user id", "GET", "/users/malformed/dead-letters", http.StatusBadRequest},
+		{"replay dead letter with malformed id", "POST", fmt.Sprintf("/users/%s/dead-letters/malformed/replay", userId), http.StatusBadRequest},
+		{"replay dead letter", "POST", fmt.Sprintf("/users/%s/dead-letters/%s/replay", userId, letterId), http.StatusNoContent},
+		{"replay removed dead letter", "POST", fmt.Sprintf("/users/%s/dead-letters/%s/replay", userId, letterId), http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		res := request(handler, tc.method, tc.url, "", nil)
+		assert.Equal(t, tc.code, res.Code, fmt.Sprintf("%s: unexpected status code", tc.desc))
+	}
+
+	assert.Equal(t, 1, controller.TurnedOff(deviceId), "expected action to be replayed")
+	assert.Empty(t, letters.All(userId), "expected dead letter to be removed")
+}
+
+func TestListExecutions(t *testing.T) {
+	history := mocks.NewExecutionRepository()
+	handler := MakeHandler(engine.NewService(mocks.NewRuleRepository(), engine.Executors{}, engine.WithExecutions(history)))
+
+	userId := gocql.TimeUUID().String()
+	ruleId := gocql.TimeUUID().String()
+	start := time.Date(2018, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		history.Save(engine.Execution{
+			UserId:  userId,
+			RuleId:  ruleId,
+			Event:   writer.Message{Publisher: "thermometer", Name: "temperature", Value: 35},
+			Time:    start.Add(time.Duration(i) * time.Hour),
+			Actions: []engine.ActionResult{{Action: engine.TurnOffAction{Name: engine.TurnOff, DeviceId: "heater"}, Status: engine.Failed, Error: "connection closed"}},
+		})
+	}
+
+	url := fmt.Sprintf("/users/%s/rules/%s/executions", userId, ruleId)
+	res := request(handler, "GET", url+"?limit=1&from=2018-05-01T12:30:00Z", "", nil)
+	assert.Equal(t, http.StatusOK, res.Code, "unexpected status code")
+	assert.Equal(t, "2", res.Header().Get("X-Count"), "unexpected number of executions")
+	assert.Contains(t, res.Body.String(), `"time":"2018-05-01T14:00:00Z"`, "missing latest execution")
+	assert.NotContains(t, res.Body.String(), `"time":"2018-05-01T13:00:00Z"`, "unexpected execution beyond limit")
+	assert.Contains(t, res.Body.String(), `"status":"failed","error":"connection closed"`, "missing outcome")
+
+	cases := []struct {
+		desc  string
+		url   string
+		code  int
+		count string
+	}{
+		{"list executions", url, http.StatusOK, "3"},
+		{"list executions within range", url + "?from=2018-05-01T13:00:00Z&to=2018-05-01T14:00:00Z", http.StatusOK, "1"},
+		{"list executions of another rule", fmt.Sprintf("/users/%s/rules/%s/executions", userId, gocql.TimeUUID()), http.StatusOK, "0"},
+		{"list executions with malformed rule id", fmt.Sprintf("/users/%s/rules/malformed/executions", userId), http.StatusBadRequest, ""},
+		{"list executions with malformed time", url + "?from=yesterday", http.StatusBadRequest, ""},
+		{"list executions with empty range", url + "?from=2018-05-01T14:00:00Z&to=2018-05-01T13:00:00Z", http.StatusBadRequest, ""},
+		{"list executions with malformed offset", url + "?offset=-1", http.StatusBadRequest, ""},
+		{"list executions with zero limit", url + "?limit=0", http.StatusBadRequest, ""},
+		{"list executions with limit too large", url + "?limit=101", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range cases {
+		res := request(handler, "GET", tc.url, "", nil)
+		assert.Equal(t, tc.code, res.Code, fmt.Sprintf("%s: unexpected status code", tc.desc))
+		assert.Equal(t, tc.count, res.Header().Get("X-Count"), fmt.Sprintf("%s: unexpected number of executions", tc.desc))
+	}
+}
+
+func TestDisableRule(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	handler := MakeHandler(engine.NewService(repo, engine.Executors{}))
+
+	userId := gocql.TimeUUID().String()
+	ruleId := gocql.TimeUUID().String()
+	repo.Save(engine.Rule{ID: ruleId, UserId: userId, Name: "rule01"})
+	repo.Save(engine.Rule{ID: gocql.TimeUUID().String(), UserId: userId, Name: "rule02"})
+
+	rules := fmt.Sprintf("/users/%s/rules", userId)
+	rule := fmt.Sprintf("/users/%s/rules/%s", userId, ruleId)
+
+	cases := []struct {
+		desc   string
+		method string
+		url    string
+		code   int
+		count  string
+	}{
+		{"disable rule", "POST", rule + "/disable", http.StatusNoContent, ""},
+		{"disable disabled rule", "POST", rule + "/disable", http.StatusNoContent, ""},
+		{"list disabled rules", "GET", rules + "?disabled=true", http.StatusOK, "1"},
+		{"list enabled rules", "GET", rules + "?disabled=false", http.StatusOK, "1"},
+		{"list all rules", "GET", rules, http.StatusOK, "2"},
+		{"list rules with malformed filter", "GET", rules + "?disabled=maybe", http.StatusBadRequest, ""},
+		{"enable rule", "POST", rule + "/enable", http.StatusNoContent, ""},
+		{"list disabled rules after enabling", "GET", rules + "?disabled=true", http.StatusOK, "0"},
+		{"enable non-existent rule", "POST", fmt.Sprintf("%s/%s/enable", rules, gocql.TimeUUID()), http.StatusNotFound, ""},
+		{"disable rule with malformed id", "POST", rules + "/malformed/disable", http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range cases {
+		res := request(handler, tc.method, tc.url, "", nil)
+		assert.Equal(t, tc.code, res.Code, fmt.Sprintf("%s: unexpected status code", tc.desc))
+		assert.Equal(t, tc.count, res.Header().Get("X-Count"), fmt.Sprintf("%s: unexpected number of rules", tc.desc))
+	}
+}
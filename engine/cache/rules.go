@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+var _ engine.RuleCache = (*ruleCache)(nil)
+
+type ruleCache struct {
+	mu       sync.RWMutex
+	repo     engine.RuleRepository
+	notifier engine.RuleNotifier
+	failed   func(string, error)
+	users    map[string][]engine.Rule
+
+	// version changes on each invalidation, so that rules loaded while
+	// they were being changed are not cached.
+	version uint64
+}
+
+// NewRuleRepository instantiates rule repository which keeps rules of each
+// user in memory, loading them from the underlying repository when they are
+// requested for the first time. Saving or removing rules invalidates the
+// cached rules of their owner and notifies other instances of the service.
+// Since the rules are already stored at that point, notification failures
+// are not returned, but reported to the failed function, if specified.
+func NewRuleRepository(repo engine.RuleRepository, notifier engine.RuleNotifier, failed func(string, error)) engine.RuleCache {
+	return &ruleCache{
+		repo:     repo,
+		notifier: notifier,
+		failed:   failed,
+		users:    make(map[string][]engine.Rule),
+	}
+}
+
+func (rc *ruleCache) Save(rule engine.Rule) error {
+	if err := rc.repo.Save(rule); err != nil {
+		return err
+	}
+
+	rc.changed(rule.UserId)
+	return nil
+}
+
+func (rc *ruleCache) One(userId string, ruleId string) (*engine.Rule, error) {
+	rc.mu.RLock()
+	rules, ok := rc.users[userId]
+	rc.mu.RUnlock()
+
+	if !ok {
+		return rc.repo.One(userId, ruleId)
+	}
+
+	for _, r := range rules {
+		if r.ID == ruleId {
+			return &r, nil
+		}
+	}
+
+	return nil, engine.ErrNotFound
+}
+
+func (rc *ruleCache) All(userId string) []engine.Rule {
+	rc.mu.RLock()
+	rules, ok := rc.users[userId]
+	version := rc.version
+	rc.mu.RUnlock()
+
+	if !ok {
+		rules = rc.repo.All(userId)
+
+		rc.mu.Lock()
+		if rc.version == version {
+			rc.users[userId] = rules
+		}
+		rc.mu.Unlock()
+	}
+
+	return append([]engine.Rule{}, rules...)
+}
+
+// WatchedUsers is not served from memory, since only rules of the users who
+// used the service since it started are cached.
+func (rc *ruleCache) WatchedUsers() []string {
+	return rc.repo.WatchedUsers()
+}
+
+func (rc *ruleCache) Remove(userId string, ruleId string) error {
+	if err := rc.repo.Remove(userId, ruleId); err != nil {
+		return err
+	}
+
+	rc.changed(userId)
+	return nil
+}
+
+func (rc *ruleCache) Invalidate(userId string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	delete(rc.users, userId)
+	rc.version++
+}
+
+// changed invalidates cached rules of the user and notifies other instances
+// of the service, so that they invalidate their caches as well.
+func (rc *ruleCache) changed(userId string) {
+	rc.Invalidate(userId)
+	if err := rc.notifier.RulesChanged(userId); err != nil && rc.failed != nil {
+		rc.failed(userId, err)
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRepository counts reads from the underlying repository.
+type countingRepository struct {
+	engine.RuleRepository
+	reads int
+}
+
+func (repo *countingRepository) One(userId, ruleId string) (*engine.Rule, error) {
+	repo.reads++
+	return repo.RuleRepository.One(userId, ruleId)
+}
+
+func (repo *countingRepository) All(userId string) []engine.Rule {
+	repo.reads++
+	return repo.RuleRepository.All(userId)
+}
+
+type notifierMock struct {
+	changed []string
+	err     error
+}
+
+func (n *notifierMock) RulesChanged(userId string) error {
+	n.changed = append(n.changed, userId)
+	return n.err
+}
+
+func TestRuleCache(t *testing.T) {
+	repo := &countingRepository{RuleRepository: mocks.NewRuleRepository()}
+	notifier := &notifierMock{}
+	cache := NewRuleRepository(repo, notifier, nil)
+
+	r1 := engine.Rule{ID: "1", UserId: "1", Name: "rule01"}
+	r2 := engine.Rule{ID: "2", UserId: "1", Name: "rule02"}
+	repo.Save(r1)
+
+	cases := []struct {
+		desc  string
+		op    func()
+		rules []engine.Rule
+		reads int
+	}{
+		{"load rules", func() {}, []engine.Rule{r1}, 1},
+		{"cached rules", func() {}, []engine.Rule{r1}, 1},
+		{"saved rule", func() { cache.Save(r2) }, []engine.Rule{r1, r2}, 2},
+		{"changed by another instance", func() { repo.Remove("1", "2"); cache.Invalidate("1") }, []engine.Rule{r1}, 3},
+		{"removed rule", func() { cache.Remove("1", "1") }, []engine.Rule{}, 4},
+	}
+
+	for _, tc := range cases {
+		tc.op()
+		rules := cache.All("1")
+		assert.ElementsMatch(t, tc.rules, rules, fmt.Sprintf("%s: unexpected rules", tc.desc))
+		assert.Equal(t, tc.reads, repo.reads, fmt.Sprintf("%s: unexpected number of reads", tc.desc))
+	}
+
+	assert.Equal(t, []string{"1", "1"}, notifier.changed, "expected changes to be announced")
+}
+
+func TestRuleCacheOne(t *testing.T) {
+	repo := &countingRepository{RuleRepository: mocks.NewRuleRepository()}
+	cache := NewRuleRepository(repo, &notifierMock{}, nil)
+
+	rule := engine.Rule{ID: "1", UserId: "1", Name: "rule01"}
+	repo.Save(rule)
+
+	r, err := cache.One("1", "1")
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, rule, *r, "unexpected rule")
+	assert.Equal(t, 1, repo.reads, "expected rule to be read from repository")
+
+	cache.All("1")
+	r, err = cache.One("1", "1")
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, rule, *r, "unexpected rule")
+	_, err = cache.One("1", "2")
+	assert.Equal(t, engine.ErrNotFound, err, "expected missing rule")
+	assert.Equal(t, 2, repo.reads, "expected rules to be served from memory")
+}
+
+func TestRuleCacheNotificationFailure(t *testing.T) {
+	notifyErr := errors.New("connection closed")
+	repo := mocks.NewRuleRepository()
+
+	var failures []error
+	cache := NewRuleRepository(repo, &notifierMock{err: notifyErr}, func(userId string, err error) {
+		failures = append(failures, err)
+	})
+
+	rule := engine.Rule{ID: "1", UserId: "1"}
+	err := cache.Save(rule)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, []error{notifyErr}, failures, "expected notification failure")
+
+	_, err = repo.One("1", "1")
+	assert.Nil(t, err, "expected rule to be saved")
+
+	err = cache.Remove("1", "1")
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, []error{notifyErr, notifyErr}, failures, "expected notification failure")
+}
@@ -0,0 +1,114 @@
+package cassandra
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+	"github.com/mainflux/mainflux/writer"
+)
+
+var _ engine.DeadLetterRepository = (*deadLetterRepository)(nil)
+
+type deadLetterRepository struct {
+	session *gocql.Session
+}
+
+// NewDeadLetterRepository instantiates Cassandra dead letter repository.
+func NewDeadLetterRepository(session *gocql.Session) engine.DeadLetterRepository {
+	return &deadLetterRepository{session}
+}
+
+func (repo *deadLetterRepository) Save(dl engine.DeadLetter) (string, error) {
+	cql := `INSERT INTO dead_letters (id, user_id, rule_id, action, event, attempts, error, failed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	id := gocql.TimeUUID()
+	action, _ := json.Marshal(fromDomain([]engine.Action{dl.Action}))
+	event, _ := json.Marshal(dl.Event)
+
+	if err := repo.session.Query(cql, id, dl.UserId, dl.RuleId, action, event, dl.Attempts, dl.Error, dl.Time).Exec(); err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+func (repo *deadLetterRepository) One(userId string, id string) (*engine.DeadLetter, error) {
+	cql := `SELECT rule_id, action, event, attempts, error, failed_at FROM dead_letters WHERE user_id = ? AND id = ? LIMIT 1`
+	var action, event []byte
+
+	dl := &engine.DeadLetter{
+		ID:     id,
+		UserId: userId,
+	}
+
+	if err := repo.session.Query(cql, userId, id).Scan(&dl.RuleId, &action, &event, &dl.Attempts, &dl.Error, &dl.Time); err != nil {
+		return nil, engine.ErrNotFound
+	}
+
+	if err := deadLetterFromBlobs(action, event, dl); err != nil {
+		return nil, err
+	}
+
+	return dl, nil
+}
+
+func (repo *deadLetterRepository) All(userId string) []engine.DeadLetter {
+	cql := `SELECT id, rule_id, action, event, attempts, error, failed_at FROM dead_letters WHERE user_id = ?`
+	var (
+		id, ruleId, msg string
+		action, event   []byte
+		attempts        int
+		failedAt        time.Time
+	)
+
+	iter := repo.session.Query(cql, userId).Iter()
+	defer iter.Close()
+
+	letters := make([]engine.DeadLetter, 0)
+
+	for iter.Scan(&id, &ruleId, &action, &event, &attempts, &msg, &failedAt) {
+		dl := engine.DeadLetter{
+			ID:       id,
+			UserId:   userId,
+			RuleId:   ruleId,
+			Attempts: attempts,
+			Error:    msg,
+			Time:     failedAt,
+		}
+
+		if deadLetterFromBlobs(action, event, &dl) != nil {
+			return letters
+		}
+
+		letters = append(letters, dl)
+	}
+
+	return letters
+}
+
+func (repo *deadLetterRepository) Remove(userId string, id string) error {
+	cql := `DELETE FROM dead_letters WHERE user_id = ? AND id = ?`
+	return repo.session.Query(cql, userId, id).Exec()
+}
+
+func deadLetterFromBlobs(action, event []byte, dl *engine.DeadLetter) error {
+	actions, err := actionsFromBlob(action)
+	if err != nil {
+		return err
+	}
+
+	if len(actions) != 1 {
+		return engine.ErrMalformedEntity
+	}
+	dl.Action = actions[0]
+
+	var e writer.Message
+	if err := json.Unmarshal(event, &e); err != nil {
+		return err
+	}
+	dl.Event = e
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package cassandra
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+)
+
+// dbOutcome represents the stored outcome of the action. Actions themselves
+// are stored separately, in the same form as the actions of the rules.
+type dbOutcome struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+var _ engine.ExecutionRepository = (*executionRepository)(nil)
+
+type executionRepository struct {
+	session *gocql.Session
+	ttl     time.Duration
+}
+
+// NewExecutionRepository instantiates Cassandra execution repository. Saved
+// executions expire after the specified time to live, unless it is zero.
+func NewExecutionRepository(session *gocql.Session, ttl time.Duration) engine.ExecutionRepository {
+	return &executionRepository{session, ttl}
+}
+
+func (repo *executionRepository) Save(exec engine.Execution) (string, error) {
+	cql := `INSERT INTO executions (id, user_id, rule_id, event, resolved, actions, outcomes, executed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`
+
+	id := gocql.UUIDFromTime(exec.Time)
+	event, _ := json.Marshal(exec.Event)
+
+	var (
+		actions  []engine.Action
+		outcomes []dbOutcome
+	)
+	for _, res := range exec.Actions {
+		actions = append(actions, res.Action)
+		outcomes = append(outcomes, dbOutcome{res.Status, res.Error})
+	}
+	acts, _ := json.Marshal(fromDomain(actions))
+	outs, _ := json.Marshal(outcomes)
+
+	if err := repo.session.Query(cql, id, exec.UserId, exec.RuleId, event, exec.Resolved, acts, outs, exec.Time, int(repo.ttl.Seconds())).Exec(); err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+func (repo *executionRepository) RetrieveAll(userId, ruleId string, filter engine.ExecutionFilter) (engine.ExecutionPage, error) {
+	where := ` WHERE user_id = ? AND rule_id = ?`
+	args := []interface{}{userId, ruleId}
+
+	if !filter.From.IsZero() {
+		where += ` AND id >= minTimeuuid(?)`
+		args = append(args, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		where += ` AND id < minTimeuuid(?)`
+		args = append(args, filter.To)
+	}
+
+	page := engine.ExecutionPage{
+		Offset:     filter.Offset,
+		Limit:      filter.Limit,
+		Executions: []engine.Execution{},
+	}
+
+	if err := repo.session.Query(`SELECT COUNT(*) FROM executions`+where, args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+
+	cql := `SELECT id, event, resolved, actions, outcomes, executed_at FROM executions` + where
+	var (
+		id                      gocql.UUID
+		event, actions, results []byte
+		resolved                bool
+		executedAt              time.Time
+	)
+
+	iter := repo.session.Query(cql, args...).Iter()
+
+	var skipped uint64
+	for iter.Scan(&id, &event, &resolved, &actions, &results, &executedAt) {
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+
+		if filter.Limit > 0 && uint64(len(page.Executions)) >= filter.Limit {
+			break
+		}
+
+		exec := engine.Execution{
+			ID:       id.String(),
+			UserId:   userId,
+			RuleId:   ruleId,
+			Resolved: resolved,
+			Time:     executedAt,
+		}
+
+		if err := executionFromBlobs(event, actions, results, &exec); err != nil {
+			iter.Close()
+			return page, err
+		}
+
+		page.Executions = append(page.Executions, exec)
+	}
+
+	if err := iter.Close(); err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+func executionFromBlobs(event, actions, outcomes []byte, exec *engine.Execution) error {
+	if err := json.Unmarshal(event, &exec.Event); err != nil {
+		return err
+	}
+
+	acts, err := actionsFromBlob(actions)
+	if err != nil {
+		return err
+	}
+
+	var outs []dbOutcome
+	if err := json.Unmarshal(outcomes, &outs); err != nil {
+		return err
+	}
+
+	if len(acts) != len(outs) {
+		return engine.ErrMalformedEntity
+	}
+
+	for i, action := range acts {
+		exec.Actions = append(exec.Actions, engine.ActionResult{
+			Action: action,
+			Status: outs[i].Status,
+			Error:  outs[i].Error,
+		})
+	}
+
+	return nil
+}
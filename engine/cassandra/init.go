@@ -9,8 +9,57 @@ var tables []string = []string{
 		name text,
 		conditions blob,
 		actions blob,
+		trigger text,
+		resolved blob,
+		throttle blob,
+		retry blob,
+		disabled boolean,
+		schedule blob,
 		PRIMARY KEY ((user_id), id)
 	)`,
+	`CREATE TABLE IF NOT EXISTS watched_rules (
+		user_id uuid,
+		rule_id uuid,
+		PRIMARY KEY ((user_id), rule_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS leases (
+		name text,
+		holder text,
+		PRIMARY KEY (name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS dead_letters (
+		id timeuuid,
+		user_id uuid,
+		rule_id uuid,
+		action blob,
+		event blob,
+		attempts int,
+		error text,
+		failed_at timestamp,
+		PRIMARY KEY ((user_id), id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS executions (
+		id timeuuid,
+		user_id uuid,
+		rule_id uuid,
+		event blob,
+		resolved boolean,
+		actions blob,
+		outcomes blob,
+		executed_at timestamp,
+		PRIMARY KEY ((user_id, rule_id), id)
+	) WITH CLUSTERING ORDER BY (id DESC)`,
+}
+
+// columns are added to the tables created by the previous versions of
+// the service.
+var columns []string = []string{
+	`ALTER TABLE rules ADD trigger text`,
+	`ALTER TABLE rules ADD resolved blob`,
+	`ALTER TABLE rules ADD throttle blob`,
+	`ALTER TABLE rules ADD retry blob`,
+	`ALTER TABLE rules ADD disabled boolean`,
+	`ALTER TABLE rules ADD schedule blob`,
 }
 
 // Connect establishes connection to the Cassandra cluster.
@@ -30,5 +79,17 @@ func Initialize(session *gocql.Session) error {
 		}
 	}
 
+	for _, column := range columns {
+		if err := session.Query(column).Exec(); err != nil && !exists(err) {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// exists checks whether the error is caused by adding already existing column.
+func exists(err error) bool {
+	reqErr, ok := err.(gocql.RequestError)
+	return ok && reqErr.Code() == gocql.ErrCodeInvalid
+}
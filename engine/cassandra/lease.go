@@ -0,0 +1,39 @@
+package cassandra
+
+import (
+	"math"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+)
+
+var _ engine.Lease = (*lease)(nil)
+
+type lease struct {
+	session *gocql.Session
+	name    string
+	holder  string
+	ttl     time.Duration
+}
+
+// NewLease instantiates Cassandra lease identified by the name, which is
+// acquired by the specified holder. Lease expires after the specified time
+// to live since it is last acquired or renewed, unless it is renewed again.
+// Lightweight transactions ensure that the lease is held by a single holder.
+func NewLease(session *gocql.Session, name, holder string, ttl time.Duration) engine.Lease {
+	return &lease{session, name, holder, ttl}
+}
+
+func (l *lease) Acquire() (bool, error) {
+	ttl := int(math.Ceil(l.ttl.Seconds()))
+
+	cql := `UPDATE leases USING TTL ? SET holder = ? WHERE name = ? IF holder = ?`
+	renewed, err := l.session.Query(cql, ttl, l.holder, l.name, l.holder).MapScanCAS(make(map[string]interface{}))
+	if err != nil || renewed {
+		return renewed, err
+	}
+
+	cql = `INSERT INTO leases (name, holder) VALUES (?, ?) IF NOT EXISTS USING TTL ?`
+	return l.session.Query(cql, l.name, l.holder, ttl).MapScanCAS(make(map[string]interface{}))
+}
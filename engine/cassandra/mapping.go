@@ -1,23 +1,150 @@
 package cassandra
 
 import (
+	"bytes"
+	"encoding/json"
+
 	"github.com/fatih/structs"
 	"github.com/asaskevich/govalidator"
 	"github.com/MainfluxLabs/rules-engine/engine"
 )
 
 const (
-	sendEmail string = "SEND EMAIL"
-	turnOff   string = "TURN OFF"
+	sendEmail string = engine.SendEmail
+	turnOff   string = engine.TurnOff
+	webhook   string = engine.Webhook
+	publish   string = engine.Publish
 
 	name      string = "Name"
 	content   string = "Content"
 	recipient string = "Recipient"
 	deviceId  string = "DeviceId"
+	uri       string = "URL"
+	method    string = "Method"
+	headers   string = "Headers"
+	body      string = "Body"
+	timeout   string = "Timeout"
+	subject   string = "Subject"
+	template  string = "Template"
 )
 
 type dbAction map[string]interface{}
 
+// dbConditions represents conditions of the rule with the boolean expression.
+// Rules without expression are stored as plain list of conditions.
+type dbConditions struct {
+	Conditions []engine.Condition `json:"conditions"`
+	Expression *engine.Expression `json:"expression"`
+}
+
+func conditionsToBlob(rule engine.Rule) ([]byte, error) {
+	if rule.Expression == nil {
+		return json.Marshal(rule.Conditions)
+	}
+
+	return json.Marshal(dbConditions{rule.Conditions, rule.Expression})
+}
+
+func conditionsFromBlob(blob []byte, rule *engine.Rule) error {
+	if b := bytes.TrimSpace(blob); len(b) == 0 || b[0] != '{' {
+		return json.Unmarshal(blob, &rule.Conditions)
+	}
+
+	var dbc dbConditions
+	if err := json.Unmarshal(blob, &dbc); err != nil {
+		return err
+	}
+
+	rule.Conditions = dbc.Conditions
+	rule.Expression = dbc.Expression
+
+	return nil
+}
+
+// actionsFromBlob converts stored list of actions to the domain actions.
+// Missing list is treated as empty.
+func actionsFromBlob(blob []byte) ([]engine.Action, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+
+	var dbActions []dbAction
+	if err := json.Unmarshal(blob, &dbActions); err != nil {
+		return nil, err
+	}
+
+	return toDomain(dbActions)
+}
+
+// throttleToBlob converts throttle of the rule to the stored form. Rules
+// without throttle are stored without the blob.
+func throttleToBlob(th *engine.Throttle) ([]byte, error) {
+	if th == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(th)
+}
+
+func throttleFromBlob(blob []byte) (*engine.Throttle, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+
+	var th engine.Throttle
+	if err := json.Unmarshal(blob, &th); err != nil {
+		return nil, err
+	}
+
+	return &th, nil
+}
+
+// scheduleToBlob converts schedule of the rule to the stored form. Rules
+// without schedule are stored without the blob.
+func scheduleToBlob(s *engine.Schedule) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(s)
+}
+
+func scheduleFromBlob(blob []byte) (*engine.Schedule, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+
+	var s engine.Schedule
+	if err := json.Unmarshal(blob, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// retryToBlob converts retry policy of the rule to the stored form. Rules
+// without their own policy are stored without the blob.
+func retryToBlob(rp *engine.RetryPolicy) ([]byte, error) {
+	if rp == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(rp)
+}
+
+func retryFromBlob(blob []byte) (*engine.RetryPolicy, error) {
+	if len(blob) == 0 {
+		return nil, nil
+	}
+
+	var rp engine.RetryPolicy
+	if err := json.Unmarshal(blob, &rp); err != nil {
+		return nil, err
+	}
+
+	return &rp, nil
+}
+
 func fromDomain(actions []engine.Action) ([]dbAction) {
 	var dbActions []dbAction
 	for _, a := range actions {
@@ -60,6 +187,14 @@ func (action dbAction) toDomain() (engine.Action, error) {
 		if a, err := action.toTurnOff(); err == nil {
 			return a, nil
 		}
+	case webhook:
+		if a, err := action.toWebhook(); err == nil {
+			return a, nil
+		}
+	case publish:
+		if a, err := action.toPublish(); err == nil {
+			return a, nil
+		}
 	}
 
 	return nil, engine.ErrMalformedEntity
@@ -84,6 +219,17 @@ func (action dbAction) validate() error {
 		if id, err := requireStrProp(action, deviceId); err != nil || !govalidator.IsUUID(*id) {
 			return engine.ErrMalformedEntity
 		}
+	case webhook:
+		if _, err := requireStrProp(action, uri); err != nil {
+			return err
+		}
+		if _, err := requireStrProp(action, method); err != nil {
+			return err
+		}
+	case publish:
+		if _, err := requireStrProp(action, subject); err != nil {
+			return err
+		}
 	default:
 		return engine.ErrMalformedEntity
 	}
@@ -91,40 +237,97 @@ func (action dbAction) validate() error {
 	return nil
 }
 
-func (action dbAction) toSendEmail() (*engine.SendEmailAction, error) {
-	se := &engine.SendEmailAction{
-		Name: name,
+func (action dbAction) toSendEmail() (engine.SendEmailAction, error) {
+	se := engine.SendEmailAction{
+		Name: sendEmail,
 	}
 
 	c, err := requireStrProp(action, content)
 	if err != nil {
-		return nil, err
+		return se, err
 	}
 	se.Content = *c
 
 	r, err := requireStrProp(action, recipient)
 	if err != nil {
-		return nil, err
+		return se, err
 	}
 	se.Recipient = *r
 
 	return se, nil
 }
 
-func (action dbAction) toTurnOff() (*engine.TurnOffAction, error) {
-	se := &engine.TurnOffAction{
-		Name: name,
+func (action dbAction) toTurnOff() (engine.TurnOffAction, error) {
+	se := engine.TurnOffAction{
+		Name: turnOff,
 	}
 
 	id, err := requireStrProp(action, deviceId)
 	if err != nil {
-		return nil, err
+		return se, err
 	}
 	se.DeviceId = *id
 
 	return se, nil
 }
 
+func (action dbAction) toWebhook() (engine.WebhookAction, error) {
+	wh := engine.WebhookAction{
+		Name: webhook,
+	}
+
+	u, err := requireStrProp(action, uri)
+	if err != nil {
+		return wh, err
+	}
+	wh.URL = *u
+
+	m, err := requireStrProp(action, method)
+	if err != nil {
+		return wh, err
+	}
+	wh.Method = *m
+
+	if hdrs, ok := action[headers].(map[string]interface{}); ok && len(hdrs) > 0 {
+		wh.Headers = make(map[string]string)
+		for k, v := range hdrs {
+			if s, ok := v.(string); ok {
+				wh.Headers[k] = s
+			}
+		}
+	}
+
+	if b, ok := action[body].(string); ok {
+		wh.Body = b
+	}
+
+	if t, ok := action[timeout].(string); ok {
+		if wh.Timeout, err = engine.ParseDuration(t); err != nil {
+			return wh, err
+		}
+	}
+
+	return wh, nil
+}
+
+func (action dbAction) toPublish() (engine.PublishAction, error) {
+	p := engine.PublishAction{
+		Name: publish,
+	}
+
+	s, err := requireStrProp(action, subject)
+	if err != nil {
+		return p, err
+	}
+	p.Subject = *s
+
+	if t, ok := action[template].(string); ok {
+		p.Template = t
+	}
+
+	return p, nil
+}
+
 func requireStrProp(object map[string]interface{}, prop string) (*string, error) {
 	if p, ok := object[prop]; ok {
 		if sp, ok := p.(string); ok && sp != "" {
@@ -19,12 +19,27 @@ func NewRuleRepository(session *gocql.Session) engine.RuleRepository {
 }
 
 func (repo *ruleRepository) Save(rule engine.Rule) error {
-	cql := `INSERT INTO rules (id, user_id, name, conditions, actions) VALUES (?, ?, ?, ?, ?)`
+	cql := `INSERT INTO rules (id, user_id, name, conditions, actions, trigger, resolved, throttle, retry, disabled, schedule) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	actions, _ := json.Marshal(fromDomain(rule.Actions))
-	conditions, _ := json.Marshal(rule.Conditions)
+	resolved, _ := json.Marshal(fromDomain(rule.Resolved))
+	throttle, _ := throttleToBlob(rule.Throttle)
+	retry, _ := retryToBlob(rule.Retry)
+	schedule, _ := scheduleToBlob(rule.Schedule)
+	conditions, _ := conditionsToBlob(rule)
+
+	// Enabled rules which detect missing data are indexed, so that their
+	// owners are listed without scanning all of the rules.
+	watched := `DELETE FROM watched_rules WHERE user_id = ? AND rule_id = ?`
+	if !rule.Disabled && rule.DetectsMissing() {
+		watched = `INSERT INTO watched_rules (user_id, rule_id) VALUES (?, ?)`
+	}
+
+	batch := repo.session.NewBatch(gocql.LoggedBatch)
+	batch.Query(cql, rule.ID, rule.UserId, rule.Name, conditions, actions, string(rule.Trigger), resolved, throttle, retry, rule.Disabled, schedule)
+	batch.Query(watched, rule.UserId, rule.ID)
 
-	if err := repo.session.Query(cql, rule.ID, rule.UserId, rule.Name, conditions, actions).Exec(); err != nil {
+	if err := repo.session.ExecuteBatch(batch); err != nil {
 		return err
 	}
 
@@ -32,10 +47,10 @@ func (repo *ruleRepository) Save(rule engine.Rule) error {
 }
 
 func (repo *ruleRepository) One(userId string, ruleId string) (*engine.Rule, error) {
-	cql := `SELECT name, conditions, actions FROM rules WHERE user_id = ? AND id = ? LIMIT 1`
+	cql := `SELECT name, conditions, actions, trigger, resolved, throttle, retry, disabled, schedule FROM rules WHERE user_id = ? AND id = ? LIMIT 1`
 	var (
-		conditions, actions []byte
-		dbActions           []dbAction
+		conditions, actions, resolved, throttle, retry, schedule []byte
+		trigger                                                  string
 	)
 
 	r := &engine.Rule{
@@ -43,33 +58,46 @@ func (repo *ruleRepository) One(userId string, ruleId string) (*engine.Rule, err
 		UserId: userId,
 	}
 
-	if err := repo.session.Query(cql, userId, ruleId).Scan(&r.Name, &conditions, &actions); err != nil {
+	if err := repo.session.Query(cql, userId, ruleId).Scan(&r.Name, &conditions, &actions, &trigger, &resolved, &throttle, &retry, &r.Disabled, &schedule); err != nil {
 		return nil, engine.ErrNotFound
 	}
 
-	if err := json.Unmarshal(conditions, &r.Conditions); err != nil {
-		return nil, err
-	}
-
-	if err := json.Unmarshal(actions, &dbActions); err != nil {
+	if err := conditionsFromBlob(conditions, r); err != nil {
 		return nil, err
 	}
 
-	acts, err := toDomain(dbActions)
+	acts, err := actionsFromBlob(actions)
 	if err != nil {
 		return r, err
 	}
 	r.Actions = acts
 
+	if r.Resolved, err = actionsFromBlob(resolved); err != nil {
+		return r, err
+	}
+
+	if r.Throttle, err = throttleFromBlob(throttle); err != nil {
+		return r, err
+	}
+
+	if r.Retry, err = retryFromBlob(retry); err != nil {
+		return r, err
+	}
+
+	if r.Schedule, err = scheduleFromBlob(schedule); err != nil {
+		return r, err
+	}
+	r.Trigger = engine.TriggerMode(trigger)
+
 	return r, nil
 }
 
 func (repo *ruleRepository) All(userId string) []engine.Rule {
-	cql := `SELECT id, name, conditions, actions FROM rules WHERE user_id = ?`
+	cql := `SELECT id, name, conditions, actions, trigger, resolved, throttle, retry, disabled, schedule FROM rules WHERE user_id = ?`
 	var (
-		id, name            string
-		conditions, actions []byte
-		dbActions           []dbAction
+		id, name, trigger                                        string
+		conditions, actions, resolved, throttle, retry, schedule []byte
+		disabled                                                 bool
 	)
 
 	iter := repo.session.Query(cql, userId).Iter()
@@ -77,26 +105,40 @@ func (repo *ruleRepository) All(userId string) []engine.Rule {
 
 	rulesList := make([]engine.Rule, 0)
 
-	for iter.Scan(&id, &name, &conditions, &actions) {
+	for iter.Scan(&id, &name, &conditions, &actions, &trigger, &resolved, &throttle, &retry, &disabled, &schedule) {
 		r := engine.Rule{
-			ID:     id,
-			UserId: userId,
-			Name:   name,
+			ID:       id,
+			UserId:   userId,
+			Name:     name,
+			Disabled: disabled,
+			Trigger:  engine.TriggerMode(trigger),
+		}
+
+		if conditionsFromBlob(conditions, &r) != nil {
+			return rulesList
 		}
 
-		if json.Unmarshal(conditions, &r.Conditions) != nil {
+		acts, err := actionsFromBlob(actions)
+		if err != nil {
 			return rulesList
 		}
+		r.Actions = acts
 
-		if json.Unmarshal(actions, &dbActions) != nil {
+		if r.Resolved, err = actionsFromBlob(resolved); err != nil {
 			return rulesList
 		}
 
-		acts, err := toDomain(dbActions)
-		if err != nil {
+		if r.Throttle, err = throttleFromBlob(throttle); err != nil {
+			return rulesList
+		}
+
+		if r.Retry, err = retryFromBlob(retry); err != nil {
+			return rulesList
+		}
+
+		if r.Schedule, err = scheduleFromBlob(schedule); err != nil {
 			return rulesList
 		}
-		r.Actions = acts
 
 		rulesList = append(rulesList, r)
 	}
@@ -104,7 +146,25 @@ func (repo *ruleRepository) All(userId string) []engine.Rule {
 	return rulesList
 }
 
+func (repo *ruleRepository) WatchedUsers() []string {
+	cql := `SELECT DISTINCT user_id FROM watched_rules`
+	var userId string
+
+	iter := repo.session.Query(cql).Iter()
+	defer iter.Close()
+
+	users := make([]string, 0)
+	for iter.Scan(&userId) {
+		users = append(users, userId)
+	}
+
+	return users
+}
+
 func (repo *ruleRepository) Remove(userId string, ruleId string) error {
-	cql := `DELETE FROM rules WHERE user_id = ? AND id = ?`
-	return repo.session.Query(cql, userId, ruleId).Exec()
+	batch := repo.session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`DELETE FROM rules WHERE user_id = ? AND id = ?`, userId, ruleId)
+	batch.Query(`DELETE FROM watched_rules WHERE user_id = ? AND rule_id = ?`, userId, ruleId)
+
+	return repo.session.ExecuteBatch(batch)
 }
@@ -1,15 +1,31 @@
 package engine
 
-import "github.com/mainflux/mainflux/writer"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
 
 // Condition represents definition what needs to be satisfied in order to trigger
-// an action.
+// an action. Condition with non-zero For duration is satisfied only once it has
+// stayed satisfied continuously for that duration. Condition with aggregate
+// compares the value combined from the property's samples within the window,
+// instead of the last reading. Condition with non-zero Missing duration has
+// neither operator nor value, and is satisfied once the property has not been
+// reported for that duration. Condition with Reset threshold is hysteresis
+// condition, which once satisfied stays satisfied until the value no longer
+// satisfies the operator against the threshold.
 type Condition struct {
-	DeviceID string        `json:"deviceId"`
-	Property string        `json:"property"`
-	Operator Operator      `json:"operator"`
-	Type     ConditionType `json:"-"`
-	Value    interface{}   `json:"value"`
+	DeviceID  string        `json:"deviceId"`
+	Property  string        `json:"property"`
+	Aggregate *Aggregate    `json:"aggregate,omitempty"`
+	Operator  Operator      `json:"operator,omitempty"`
+	Type      ConditionType `json:"-"`
+	Value     interface{}   `json:"value,omitempty"`
+	Reset     *float64      `json:"reset,omitempty"`
+	For       Duration      `json:"for,omitempty"`
+	Missing   Duration      `json:"missing,omitempty"`
 }
 
 // ConditionType represent possible condition types based on value type
@@ -20,6 +36,7 @@ const (
 	String
 	Numeric
 	Between
+	Set
 )
 
 // Range represents upper and lower bound in between condition.
@@ -28,19 +45,117 @@ type Range struct {
 	To   float64 `json:"to"`
 }
 
+// UnmarshalJSON decodes condition inferring its type from the value.
+func (cnd *Condition) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		DeviceID  string      `json:"deviceId"`
+		Property  string      `json:"property"`
+		Aggregate *Aggregate  `json:"aggregate"`
+		Operator  Operator    `json:"operator"`
+		Value     interface{} `json:"value"`
+		Reset     *float64    `json:"reset"`
+		For       Duration    `json:"for"`
+		Missing   Duration    `json:"missing"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	cnd.DeviceID = raw.DeviceID
+	cnd.Property = raw.Property
+	cnd.Aggregate = raw.Aggregate
+	cnd.Operator = raw.Operator
+	cnd.Value = raw.Value
+	cnd.Reset = raw.Reset
+	cnd.For = raw.For
+	cnd.Missing = raw.Missing
+
+	if cnd.Missing > 0 && raw.Value == nil {
+		return nil
+	}
+
+	switch v := raw.Value.(type) {
+	case bool:
+		cnd.Type = Bool
+	case string:
+		cnd.Type = String
+		if cnd.Operator == Matches {
+			p, err := CompilePattern(v)
+			if err != nil {
+				return ErrMalformedEntity
+			}
+			cnd.Value = p
+		}
+	case float64:
+		cnd.Type = Numeric
+	case []interface{}:
+		set := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return ErrMalformedEntity
+			}
+			set[i] = s
+		}
+		cnd.Type = Set
+		cnd.Value = set
+	case map[string]interface{}:
+		from, fok := v["from"].(float64)
+		to, tok := v["to"].(float64)
+		if !fok || !tok {
+			return ErrMalformedEntity
+		}
+		cnd.Type = Between
+		cnd.Value = Range{From: from, To: to}
+	default:
+		return ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// refersTo checks whether condition refers to the event's publisher and property.
+func (cnd Condition) refersTo(event writer.Message) bool {
+	return cnd.DeviceID == event.Publisher && cnd.Property == event.Name
+}
+
+// isSatisfied checks the condition against the single event. Missing data
+// condition is never satisfied, since the event reports its property, while
+// hysteresis condition is checked against its value, as if it was inactive.
 func (cnd Condition) isSatisfied(event writer.Message) bool {
-	var actual interface{}
+	if cnd.Missing > 0 {
+		return false
+	}
+
+	v, ok := cnd.compared(event)
+	return ok && cnd.refersTo(event) && cnd.Operator.Compare(cnd.Value, v)
+}
 
-	satisfied := cnd.DeviceID == event.Publisher && cnd.Property == event.Name
+// compared retrieves value compared by the condition evaluated against the
+// single event. Aggregate condition compares the aggregate of the event
+// alone, which is unknown if the aggregate requires multiple samples, while
+// missing data condition compares no value.
+func (cnd Condition) compared(event writer.Message) (interface{}, bool) {
+	if cnd.Missing > 0 {
+		return nil, true
+	}
+
+	if cnd.Aggregate != nil {
+		return cnd.Aggregate.apply([]sample{newSample(event, time.Time{})})
+	}
+
+	return cnd.valueOf(event), true
+}
 
+// valueOf retrieves value of the event according to the condition type.
+func (cnd Condition) valueOf(event writer.Message) interface{} {
 	switch cnd.Type {
 	case Bool:
-		actual = event.BoolValue
-	case String:
-		actual = event.StringValue
-	case Numeric, Between:
-		actual = event.Value
+		return event.BoolValue
+	case String, Set:
+		return event.StringValue
 	}
 
-	return satisfied && cnd.Operator.Compare(cnd.Value, actual)
+	return event.Value
 }
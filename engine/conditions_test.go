@@ -8,39 +8,53 @@ import (
 )
 
 func TestIsSatisfied(t *testing.T) {
+	sensor, _ := CompilePattern(`^sensor-\d+$`)
 	cases := []struct {
 		cnd       Condition
 		event     writer.Message
 		satisfied bool
 	}{
-		{Condition{"id", "active", Eq, Bool, true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, true},
-		{Condition{"mismatchedId", "active", Eq, Bool, true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
-		{Condition{"id", "mismatchedProperty", Eq, Bool, true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
-		{Condition{"id", "active", Eq, Bool, false}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
-		{Condition{"id",  "temp", Btw, Between, Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 18}, true},
-		{Condition{"id",  "temp", Btw, Between, Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
-		{Condition{"id",  "temp", Btw, Between, Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
-		{Condition{"id",  "temp", Btw, Between, Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 30}, false},
-		{Condition{"id", "temp", Eq, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
-		{Condition{"id", "temp", Eq, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
-		{Condition{"id", "temp", Lt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 13}, false},
-		{Condition{"id", "temp", Lt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
-		{Condition{"id", "temp", Lt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
-		{Condition{"id", "temp", Lte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 10}, false},
-		{Condition{"id", "temp",  Lte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
-		{Condition{"id", "temp", Lte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
-		{Condition{"id", "temp", Gt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
-		{Condition{"id", "temp", Gt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
-		{Condition{"id", "temp", Gt, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 10}, true},
-		{Condition{"id", "temp", Gte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
-		{Condition{"id", "temp", Gte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
-		{Condition{"id", "temp", Gte, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 13}, true},
-		{Condition{"id", "temp", Neq, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
-		{Condition{"id", "temp", Neq, Numeric, float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
-		{Condition{"id",  "name", Eq, String,  "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "a"}, true},
-		{Condition{"id",  "name", Eq, String,  "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "b"}, false},
-		{Condition{"id",  "name", Neq, String,  "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "b"}, true},
-		{Condition{"id",  "name", Neq, String,  "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "a"}, false},
+		{Condition{DeviceID: "id", Property: "active", Operator: Eq, Type: Bool, Value: true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, true},
+		{Condition{DeviceID: "mismatchedId", Property: "active", Operator: Eq, Type: Bool, Value: true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
+		{Condition{DeviceID: "id", Property: "mismatchedProperty", Operator: Eq, Type: Bool, Value: true}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
+		{Condition{DeviceID: "id", Property: "active", Operator: Eq, Type: Bool, Value: false}, writer.Message{Publisher: "id", Name: "active", BoolValue: true}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Btw, Type: Between, Value: Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 18}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Btw, Type: Between, Value: Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Btw, Type: Between, Value: Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Btw, Type: Between, Value: Range{15, 20}}, writer.Message{Publisher: "id", Name: "temp", Value: 30}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Eq, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Eq, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 13}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 10}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Lte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gt, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 10}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, false},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Gte, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 13}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Neq, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 20}, true},
+		{Condition{DeviceID: "id", Property: "temp", Operator: Neq, Type: Numeric, Value: float64(15)}, writer.Message{Publisher: "id", Name: "temp", Value: 15}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: Eq, Type: String, Value: "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "a"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: Eq, Type: String, Value: "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "b"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: Neq, Type: String, Value: "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "b"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: Neq, Type: String, Value: "a"}, writer.Message{Publisher: "id", Name: "name", StringValue: "a"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: Contains, Type: String, Value: "err"}, writer.Message{Publisher: "id", Name: "name", StringValue: "disk error"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: Contains, Type: String, Value: "err"}, writer.Message{Publisher: "id", Name: "name", StringValue: "ok"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: StartsWith, Type: String, Value: "sensor-"}, writer.Message{Publisher: "id", Name: "name", StringValue: "sensor-1"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: StartsWith, Type: String, Value: "sensor-"}, writer.Message{Publisher: "id", Name: "name", StringValue: "1-sensor-"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: EndsWith, Type: String, Value: ".log"}, writer.Message{Publisher: "id", Name: "name", StringValue: "boot.log"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: EndsWith, Type: String, Value: ".log"}, writer.Message{Publisher: "id", Name: "name", StringValue: "boot.log.gz"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: Matches, Type: String, Value: sensor}, writer.Message{Publisher: "id", Name: "name", StringValue: "sensor-42"}, true},
+		{Condition{DeviceID: "id", Property: "name", Operator: Matches, Type: String, Value: sensor}, writer.Message{Publisher: "id", Name: "name", StringValue: "sensor-x"}, false},
+		{Condition{DeviceID: "id", Property: "name", Operator: Matches, Type: String, Value: `^sensor-\d+$`}, writer.Message{Publisher: "id", Name: "name", StringValue: "sensor-42"}, false},
+		{Condition{DeviceID: "id", Property: "mode", Operator: In, Type: Set, Value: []string{"auto", "eco"}}, writer.Message{Publisher: "id", Name: "mode", StringValue: "eco"}, true},
+		{Condition{DeviceID: "id", Property: "mode", Operator: In, Type: Set, Value: []string{"auto", "eco"}}, writer.Message{Publisher: "id", Name: "mode", StringValue: "off"}, false},
+		{Condition{DeviceID: "id", Property: "mode", Operator: NotIn, Type: Set, Value: []string{"auto", "eco"}}, writer.Message{Publisher: "id", Name: "mode", StringValue: "off"}, true},
+		{Condition{DeviceID: "id", Property: "mode", Operator: NotIn, Type: Set, Value: []string{"auto", "eco"}}, writer.Message{Publisher: "id", Name: "mode", StringValue: "auto"}, false},
 	}
 	for i, tc := range cases {
 		satisfied := tc.cnd.isSatisfied(tc.event)
@@ -0,0 +1,11 @@
+package engine
+
+import "context"
+
+// DeviceController specifies API for sending control commands to devices.
+type DeviceController interface {
+	// TurnOff sends command for turning off the device identified by the
+	// specified unique identifier, unless the context is done first. A non-nil
+	// error is returned to indicate command delivery failure.
+	TurnOff(ctx context.Context, deviceId string) error
+}
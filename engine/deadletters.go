@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// DeadLetter represents the action which failed after all attempts, along
+// with the event which triggered it.
+type DeadLetter struct {
+	ID       string         `json:"id"`
+	UserId   string         `json:"userId"`
+	RuleId   string         `json:"ruleId"`
+	Action   Action         `json:"action"`
+	Event    writer.Message `json:"event"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error"`
+	Time     time.Time      `json:"time"`
+}
+
+// DeadLetterRepository specifies API for keeping the failed actions, so that
+// they can be inspected and replayed.
+type DeadLetterRepository interface {
+	// Save persists the dead letter, returning its generated unique identifier.
+	// A non-nil error is returned to indicate operation failure.
+	Save(DeadLetter) (string, error)
+
+	// One retrieves specific dead letter by its owner and unique identifier.
+	// A non-nil error is returned to indicate operation failure.
+	One(string, string) (*DeadLetter, error)
+
+	// All retrieves list of dead letters for specific user.
+	All(string) []DeadLetter
+
+	// Remove removes specific dead letter. A non-nil error is returned to
+	// indicate operation failure.
+	Remove(string, string) error
+}
@@ -0,0 +1,304 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// ErrDispatcherClosed indicates that the dispatcher no longer accepts actions.
+var ErrDispatcherClosed = errors.New("dispatcher closed")
+
+// DispatcherConfig specifies the worker pool of the dispatcher.
+type DispatcherConfig struct {
+	// Workers is the number of actions executed concurrently.
+	Workers int
+
+	// QueueSize is the number of actions waiting for execution, after
+	// which dispatching blocks until the queue is drained.
+	QueueSize int
+
+	// Timeout limits duration of the single action execution, unless zero.
+	Timeout time.Duration
+
+	// Retry is the retry policy of the actions, unless overridden for the
+	// action type by Retries, or for the rule by its own policy.
+	Retry RetryPolicy
+
+	// Retries maps action types to their retry policies.
+	Retries map[string]RetryPolicy
+
+	// DeadLetters keeps the actions which failed after all attempts, if
+	// specified.
+	DeadLetters DeadLetterRepository
+
+	// Failed is called with the action which failed after all attempts, if
+	// specified. It is also called if the action cannot be kept as a dead
+	// letter, with the related error.
+	Failed func(Action, writer.Message, Rule, error)
+}
+
+type job struct {
+	action  Action
+	event   writer.Message
+	rule    Rule
+	attempt int
+
+	// done is called with the final outcome of the action, if specified.
+	done func(error)
+}
+
+// retry represents the failed job waiting for its next attempt.
+type retry struct {
+	job   job
+	err   error
+	timer *time.Timer
+}
+
+var _ ActionExecutor = (*Dispatcher)(nil)
+
+// Dispatcher executes actions asynchronously, using the bounded pool of
+// workers, so that slow executors do not stall evaluation of the rules.
+// Failed actions are retried according to their retry policy, and are kept
+// as dead letters once all attempts fail, or immediately if their error is
+// permanent.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	closed   bool
+	closing  int32
+	once     sync.Once
+	executor ActionExecutor
+	cfg      DispatcherConfig
+	jobs     chan job
+	done     chan struct{}
+
+	rmu     sync.Mutex
+	waiting map[*retry]struct{}
+	retries sync.WaitGroup
+}
+
+// NewDispatcher starts workers which execute dispatched actions using the
+// specified executor.
+func NewDispatcher(executor ActionExecutor, cfg DispatcherConfig) *Dispatcher {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+
+	d := &Dispatcher{
+		executor: executor,
+		cfg:      cfg,
+		jobs:     make(chan job, cfg.QueueSize),
+		done:     make(chan struct{}),
+		waiting:  make(map[*retry]struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range d.jobs {
+				d.run(j)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(d.done)
+	}()
+
+	return d
+}
+
+// Execute queues the action for execution. If the queue is full, it blocks
+// until the action is queued or the context is done, in which case context
+// error is returned. Failure of the action itself is not reported.
+func (d *Dispatcher) Execute(ctx context.Context, action Action, event writer.Message, rule Rule) error {
+	return d.dispatch(ctx, job{action: action, event: event, rule: rule})
+}
+
+// dispatch queues the job, same as Execute. Job's done function is called
+// once the action succeeds, or fails after all attempts, unless the job is
+// not queued.
+func (d *Dispatcher) dispatch(ctx context.Context, j job) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	select {
+	case d.jobs <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting actions and waits until the queued actions are
+// executed. Actions waiting to be retried are kept as dead letters without
+// further attempts. Context error is returned if the context is done first.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	// Pending Execute calls hold the lock until their actions are queued.
+	d.once.Do(func() {
+		atomic.StoreInt32(&d.closing, 1)
+		go func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+
+			d.closed = true
+			close(d.jobs)
+		}()
+	})
+
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	d.rmu.Lock()
+	for r := range d.waiting {
+		r.timer.Stop()
+		delete(d.waiting, r)
+		d.fail(r.job, r.err)
+		d.retries.Done()
+	}
+	d.rmu.Unlock()
+
+	retried := make(chan struct{})
+	go func() {
+		d.retries.Wait()
+		close(retried)
+	}()
+
+	select {
+	case <-retried:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// policy resolves the retry policy of the job's action.
+func (d *Dispatcher) policy(j job) RetryPolicy {
+	if j.rule.Retry != nil {
+		return *j.rule.Retry
+	}
+
+	if p, ok := d.cfg.Retries[j.action.Type()]; ok {
+		return p
+	}
+
+	return d.cfg.Retry
+}
+
+// run executes the action on the worker, so that the number of actions in
+// progress never exceeds the number of workers. Executors are required to
+// return once the context is done, which enforces the timeout.
+func (d *Dispatcher) run(j job) {
+	ctx := context.Background()
+	if d.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.cfg.Timeout)
+		defer cancel()
+	}
+
+	err := d.executor.Execute(ctx, j.action, j.event, j.rule)
+	if err == nil {
+		if j.done != nil {
+			j.done(nil)
+		}
+		return
+	}
+
+	j.attempt++
+	if j.attempt >= d.policy(j).Attempts || IsPermanent(err) {
+		d.fail(j, err)
+		return
+	}
+
+	d.schedule(j, err)
+}
+
+// schedule queues the job again once its backoff expires. Jobs failed while
+// the dispatcher is closing are not retried. Workers do not acquire the
+// lock, since they need to drain the queue for pending Execute calls.
+func (d *Dispatcher) schedule(j job, err error) {
+	if atomic.LoadInt32(&d.closing) == 1 {
+		d.fail(j, err)
+		return
+	}
+
+	r := &retry{job: j, err: err}
+
+	d.rmu.Lock()
+	defer d.rmu.Unlock()
+
+	d.retries.Add(1)
+	d.waiting[r] = struct{}{}
+	r.timer = time.AfterFunc(d.policy(j).delay(j.attempt), func() {
+		d.requeue(r)
+	})
+}
+
+func (d *Dispatcher) requeue(r *retry) {
+	d.rmu.Lock()
+	_, ok := d.waiting[r]
+	delete(d.waiting, r)
+	d.rmu.Unlock()
+
+	// Retry was already handled by Close.
+	if !ok {
+		return
+	}
+	defer d.retries.Done()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		d.fail(r.job, r.err)
+		return
+	}
+	d.jobs <- r.job
+}
+
+// fail keeps the action which failed after all attempts as a dead letter.
+func (d *Dispatcher) fail(j job, err error) {
+	if j.done != nil {
+		defer j.done(err)
+	}
+
+	if d.cfg.Failed != nil {
+		d.cfg.Failed(j.action, j.event, j.rule, err)
+	}
+
+	if d.cfg.DeadLetters == nil {
+		return
+	}
+
+	dl := DeadLetter{
+		UserId:   j.rule.UserId,
+		RuleId:   j.rule.ID,
+		Action:   j.action,
+		Event:    j.event,
+		Attempts: j.attempt,
+		Error:    err.Error(),
+		Time:     time.Now(),
+	}
+
+	if _, err := d.cfg.DeadLetters.Save(dl); err != nil && d.cfg.Failed != nil {
+		d.cfg.Failed(j.action, j.event, j.rule, err)
+	}
+}
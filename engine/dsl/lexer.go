@@ -0,0 +1,177 @@
+package dsl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	eof tokenKind = iota
+	word
+	uuid
+	number
+	duration
+	str
+	punct
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case eof:
+		return "end of input"
+	case word:
+		return "word"
+	case uuid:
+		return "UUID"
+	case number:
+		return "number"
+	case duration:
+		return "duration"
+	case str:
+		return "string"
+	}
+
+	return "symbol"
+}
+
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+func (t token) String() string {
+	if t.kind == eof {
+		return t.kind.String()
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numberPattern   = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	durationPattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$`)
+	wordPattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// symbols lists punctuation tokens, longer ones first.
+var symbols = []string{"!=", "<=", ">=", "=", "<", ">", "[", "]", "(", ")", "{", "}", ",", ":"}
+
+// lex splits the source into tokens, terminated by the end of input token.
+func lex(src string) ([]token, error) {
+	var (
+		tokens []token
+		line   = 1
+		column = 1
+	)
+
+	advance := func(s string) {
+		for _, r := range s {
+			if r == '\n' {
+				line++
+				column = 1
+				continue
+			}
+			column++
+		}
+	}
+
+	for len(src) > 0 {
+		r, size := utf8.DecodeRuneInString(src)
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			advance(src[:size])
+			src = src[size:]
+			continue
+		case r == '"':
+			lit, err := quoted(src)
+			if err != nil {
+				return nil, &Error{line, column, err.Error()}
+			}
+			text, _ := strconv.Unquote(lit)
+			tokens = append(tokens, token{str, text, line, column})
+			advance(lit)
+			src = src[len(lit):]
+			continue
+		case isWordRune(r):
+			n := strings.IndexFunc(src, func(r rune) bool { return !isWordRune(r) })
+			if n < 0 {
+				n = len(src)
+			}
+
+			text := src[:n]
+			kind, ok := classify(text)
+			if !ok {
+				return nil, &Error{line, column, fmt.Sprintf("invalid token %q", text)}
+			}
+			tokens = append(tokens, token{kind, text, line, column})
+			advance(text)
+			src = src[n:]
+			continue
+		}
+
+		matched := false
+		for _, s := range symbols {
+			if strings.HasPrefix(src, s) {
+				tokens = append(tokens, token{punct, s, line, column})
+				advance(s)
+				src = src[len(s):]
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return nil, &Error{line, column, fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+
+	return append(tokens, token{eof, "", line, column}), nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func classify(text string) (tokenKind, bool) {
+	switch {
+	case uuidPattern.MatchString(text):
+		return uuid, true
+	case numberPattern.MatchString(text):
+		return number, true
+	case durationPattern.MatchString(text):
+		return duration, true
+	case wordPattern.MatchString(text):
+		return word, true
+	}
+
+	return eof, false
+}
+
+// quoted extracts double quoted string literal from the start of the source.
+func quoted(src string) (string, error) {
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		case '"':
+			lit := src[:i+1]
+			if _, err := strconv.Unquote(lit); err != nil {
+				return "", fmt.Errorf("invalid string %s", lit)
+			}
+			return lit, nil
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string")
+}
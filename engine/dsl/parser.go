@@ -0,0 +1,1045 @@
+// Package dsl implements parser and printer of the rule specification
+// language described in doc/DSLSYNTAX.md.
+package dsl
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+// ContentType is the media type of the rules specified using the DSL.
+const ContentType = "text/x-rules-dsl"
+
+// Error represents syntax error at the specific position of the source.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+var operators = map[string]engine.Operator{
+	"=":       engine.Eq,
+	"!=":      engine.Neq,
+	"<":       engine.Lt,
+	"<=":      engine.Lte,
+	">":       engine.Gt,
+	">=":      engine.Gte,
+	"BETWEEN": engine.Btw,
+
+	"CONTAINS":    engine.Contains,
+	"STARTS WITH": engine.StartsWith,
+	"ENDS WITH":   engine.EndsWith,
+	"MATCHES":     engine.Matches,
+	"IN":          engine.In,
+	"NOT IN":      engine.NotIn,
+}
+
+// mirrored maps the ordering operators of the DSL, whose left-hand side is
+// the reading, to the engine's operators, whose left-hand side is the value,
+// and vice versa. E.g. ["temperature"] > 30 is satisfied when the reading
+// is greater than 30, i.e. when 30 is less than the reading.
+var mirrored = map[engine.Operator]engine.Operator{
+	engine.Lt:  engine.Gt,
+	engine.Lte: engine.Gte,
+	engine.Gt:  engine.Lt,
+	engine.Gte: engine.Lte,
+}
+
+var aggregates = map[string]engine.AggregateFunction{
+	"AVG":   engine.Avg,
+	"MIN":   engine.Min,
+	"MAX":   engine.Max,
+	"COUNT": engine.Count,
+	"SUM":   engine.Sum,
+	"DELTA": engine.Delta,
+	"RATE":  engine.Rate,
+}
+
+var weekdays = map[string]bool{
+	"MON": true,
+	"TUE": true,
+	"WED": true,
+	"THU": true,
+	"FRI": true,
+	"SAT": true,
+	"SUN": true,
+}
+
+var methods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// Parse parses rules from the source. Parsed rules have neither identifier
+// nor owner assigned.
+func Parse(src string) ([]engine.Rule, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	var rules []engine.Rule
+	for p.peek().kind != eof {
+		rule, err := p.rule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		return nil, p.errorf("expected %q", "RULE")
+	}
+
+	return rules, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != eof {
+		p.pos++
+	}
+
+	return t
+}
+
+// is checks whether the following tokens spell the specified words or
+// symbols.
+func (p *parser) is(texts ...string) bool {
+	for i, text := range texts {
+		if p.pos+i >= len(p.tokens) {
+			return false
+		}
+
+		t := p.tokens[p.pos+i]
+		if (t.kind != word && t.kind != punct) || t.text != text {
+			return false
+		}
+	}
+
+	return true
+}
+
+// accept consumes the following tokens if they spell the specified words
+// or symbols.
+func (p *parser) accept(texts ...string) bool {
+	if !p.is(texts...) {
+		return false
+	}
+
+	p.pos += len(texts)
+	return true
+}
+
+func (p *parser) expect(texts ...string) error {
+	if !p.accept(texts...) {
+		return p.errorf("expected %q", strings.Join(texts, " "))
+	}
+
+	return nil
+}
+
+func (p *parser) expectKind(kind tokenKind) (token, error) {
+	if p.peek().kind != kind {
+		return token{}, p.errorf("expected %s", kind)
+	}
+
+	return p.next(), nil
+}
+
+// errorf creates error positioned at the next token.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	return &Error{t.line, t.column, fmt.Sprintf(format, args...) + fmt.Sprintf(", found %s", t)}
+}
+
+func (p *parser) rule() (engine.Rule, error) {
+	var rule engine.Rule
+
+	if err := p.expect("RULE"); err != nil {
+		return rule, err
+	}
+
+	switch t := p.peek(); {
+	case t.kind == str, t.kind == word && !p.is(":"):
+		rule.Name = p.next().text
+	}
+
+	if err := p.expect(":"); err != nil {
+		return rule, err
+	}
+
+	var groups []engine.Expression
+	for !p.is("TRIGGERS") && !p.is("ACTIVE") {
+		expr, err := p.expression()
+		if err != nil {
+			return rule, err
+		}
+
+		if expr.Condition != nil {
+			rule.Conditions = append(rule.Conditions, *expr.Condition)
+			continue
+		}
+		groups = append(groups, expr)
+	}
+
+	if len(rule.Conditions) == 0 && len(groups) == 0 {
+		return rule, p.errorf("expected condition")
+	}
+
+	switch len(groups) {
+	case 0:
+	case 1:
+		rule.Expression = &groups[0]
+	default:
+		rule.Expression = &engine.Expression{Operator: engine.And, Operands: groups}
+	}
+
+	sch, err := p.schedule()
+	if err != nil {
+		return rule, err
+	}
+	rule.Schedule = sch
+
+	if err := p.expect("TRIGGERS"); err != nil {
+		return rule, err
+	}
+
+	switch {
+	case p.accept("ON", "RISE"):
+		rule.Trigger = engine.Rising
+	case p.accept("ON", "FALL"):
+		rule.Trigger = engine.Falling
+	case p.accept("ALWAYS"):
+		rule.Trigger = engine.Level
+	}
+
+	if rule.Throttle, err = p.throttle(); err != nil {
+		return rule, err
+	}
+
+	if rule.Retry, err = p.retry(); err != nil {
+		return rule, err
+	}
+
+	if rule.Actions, err = p.actions(); err != nil {
+		return rule, err
+	}
+
+	if p.accept("RESOLVED") {
+		if rule.Resolved, err = p.actions(); err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, nil
+}
+
+// schedule parses windows of the schedule separated by OR, followed by the
+// optional time zone.
+func (p *parser) schedule() (*engine.Schedule, error) {
+	if !p.accept("ACTIVE") {
+		return nil, nil
+	}
+
+	var s engine.Schedule
+	for {
+		w, err := p.window()
+		if err != nil {
+			return nil, err
+		}
+		s.Windows = append(s.Windows, w)
+
+		if !p.accept("OR") {
+			break
+		}
+	}
+
+	if p.accept("ZONE") {
+		t, err := p.expectKind(str)
+		if err != nil {
+			return nil, err
+		}
+
+		if !engine.ValidZone(t.text) {
+			return nil, &Error{t.line, t.column, fmt.Sprintf("unknown time zone %q", t.text)}
+		}
+		s.Zone = t.text
+	}
+
+	return &s, nil
+}
+
+func (p *parser) window() (engine.Window, error) {
+	var w engine.Window
+
+	if p.accept("ON") {
+		for {
+			t := p.peek()
+			if t.kind != word || !weekdays[t.text] {
+				return w, p.errorf("expected day of the week")
+			}
+			w.Days = append(w.Days, p.next().text)
+
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if !p.is("FROM") {
+		if len(w.Days) == 0 {
+			return w, p.errorf("expected %q or %q", "ON", "FROM")
+		}
+		return w, nil
+	}
+	p.next()
+
+	var err error
+	if w.From, err = p.timeOfDay(); err != nil {
+		return w, err
+	}
+
+	if err := p.expect("TO"); err != nil {
+		return w, err
+	}
+
+	t := p.peek()
+	if w.To, err = p.timeOfDay(); err != nil {
+		return w, err
+	}
+
+	if w.From == w.To && (w.From != 0 || len(w.Days) == 0) {
+		return w, &Error{t.line, t.column, "invalid time window"}
+	}
+
+	return w, nil
+}
+
+func (p *parser) timeOfDay() (engine.TimeOfDay, error) {
+	h := p.peek()
+	if h.kind != number {
+		return 0, p.errorf("expected time of day")
+	}
+	p.next()
+
+	if err := p.expect(":"); err != nil {
+		return 0, err
+	}
+
+	m, err := p.expectKind(number)
+	if err != nil {
+		return 0, err
+	}
+
+	t, err := engine.ParseTimeOfDay(h.text + ":" + m.text)
+	if err != nil {
+		return 0, &Error{h.line, h.column, fmt.Sprintf("invalid time of day %s:%s", h.text, m.text)}
+	}
+
+	return t, nil
+}
+
+func (p *parser) throttle() (*engine.Throttle, error) {
+	var (
+		th  engine.Throttle
+		set bool
+		err error
+	)
+
+	if p.accept("COOLDOWN") {
+		if th.Cooldown, err = p.duration(); err != nil {
+			return nil, err
+		}
+		set = true
+	}
+
+	if p.accept("LIMIT") {
+		t, err := p.expectKind(number)
+		if err != nil {
+			return nil, err
+		}
+
+		limit, err := strconv.Atoi(t.text)
+		if err != nil || limit <= 0 {
+			return nil, &Error{t.line, t.column, fmt.Sprintf("invalid limit %s", t.text)}
+		}
+		th.Limit = limit
+
+		if err := p.expect("PER"); err != nil {
+			return nil, err
+		}
+
+		if th.Window, err = p.duration(); err != nil {
+			return nil, err
+		}
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+
+	return &th, nil
+}
+
+func (p *parser) retry() (*engine.RetryPolicy, error) {
+	if !p.accept("RETRY") {
+		return nil, nil
+	}
+
+	t, err := p.expectKind(number)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := strconv.Atoi(t.text)
+	if err != nil || attempts <= 0 || attempts > engine.MaxRetryAttempts {
+		return nil, &Error{t.line, t.column, fmt.Sprintf("invalid number of attempts %s", t.text)}
+	}
+	rp := engine.RetryPolicy{Attempts: attempts}
+
+	if p.accept("BACKOFF") {
+		if rp.Backoff, err = p.duration(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("MAX") {
+		t := p.peek()
+		if rp.MaxBackoff, err = p.duration(); err != nil {
+			return nil, err
+		}
+
+		if rp.MaxBackoff < rp.Backoff {
+			return nil, &Error{t.line, t.column, fmt.Sprintf("maximum backoff %s is shorter than backoff", t.text)}
+		}
+	}
+
+	return &rp, nil
+}
+
+// expression parses disjunction of conjunctions.
+func (p *parser) expression() (engine.Expression, error) {
+	return p.group(engine.Or, "OR", p.conjunction)
+}
+
+func (p *parser) conjunction() (engine.Expression, error) {
+	return p.group(engine.And, "AND", p.unary)
+}
+
+// group parses operands separated by the keyword, binding them by the
+// operator if there are more than one.
+func (p *parser) group(op engine.LogicalOperator, keyword string, operand func() (engine.Expression, error)) (engine.Expression, error) {
+	first, err := operand()
+	if err != nil {
+		return first, err
+	}
+
+	operands := []engine.Expression{first}
+	for p.accept(keyword) {
+		o, err := operand()
+		if err != nil {
+			return o, err
+		}
+		operands = append(operands, o)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return engine.Expression{Operator: op, Operands: operands}, nil
+}
+
+func (p *parser) unary() (engine.Expression, error) {
+	if p.accept("NOT") {
+		operand, err := p.unary()
+		if err != nil {
+			return operand, err
+		}
+
+		return engine.Expression{Operator: engine.Not, Operands: []engine.Expression{operand}}, nil
+	}
+
+	if p.accept("(") {
+		expr, err := p.expression()
+		if err != nil {
+			return expr, err
+		}
+
+		return expr, p.expect(")")
+	}
+
+	cnd, err := p.condition()
+	if err != nil {
+		return engine.Expression{}, err
+	}
+
+	return engine.Leaf(cnd), nil
+}
+
+func (p *parser) condition() (engine.Condition, error) {
+	var cnd engine.Condition
+
+	if t := p.peek(); t.kind == word && aggregates[t.text] != "" && p.is(t.text, "(") {
+		a, err := p.aggregate(&cnd)
+		if err != nil {
+			return cnd, err
+		}
+		cnd.Aggregate = &a
+	} else if err := p.property(&cnd); err != nil {
+		return cnd, err
+	} else if p.accept("MISSING") {
+		return cnd, p.missing(&cnd)
+	}
+
+	op, ok := p.operator()
+	if !ok {
+		return cnd, p.errorf("expected operator")
+	}
+	cnd.Operator = mirror(op)
+
+	valueToken := p.peek()
+	if err := p.value(&cnd); err != nil {
+		return cnd, err
+	}
+
+	if !compatible(cnd) {
+		return cnd, &Error{valueToken.line, valueToken.column, fmt.Sprintf("operator %s can not be applied to %s", op, valueToken)}
+	}
+
+	if op == engine.Matches {
+		pattern, err := engine.CompilePattern(cnd.Value.(string))
+		if err != nil {
+			return cnd, &Error{valueToken.line, valueToken.column, fmt.Sprintf("invalid pattern %s", valueToken)}
+		}
+		cnd.Value = pattern
+	}
+
+	if cnd.Aggregate != nil && (cnd.Type == engine.Bool || cnd.Type == engine.String || cnd.Type == engine.Set) {
+		return cnd, &Error{valueToken.line, valueToken.column, fmt.Sprintf("aggregate can not be compared with %s", valueToken)}
+	}
+
+	if p.accept("RESET") {
+		t := p.peek()
+		reset, err := p.number()
+		if err != nil {
+			return cnd, err
+		}
+
+		if v, ok := cnd.Value.(float64); !ok || !engine.ValidReset(cnd.Operator, v, reset) {
+			return cnd, &Error{t.line, t.column, fmt.Sprintf("invalid reset threshold %s%s", t.text, resetSide(cnd))}
+		}
+		cnd.Reset = &reset
+	}
+
+	if t := p.peek(); cnd.Aggregate != nil && p.is("FOR") {
+		return cnd, &Error{t.line, t.column, "aggregate condition can not have duration"}
+	}
+
+	if t := p.peek(); cnd.Reset != nil && p.is("FOR") {
+		return cnd, &Error{t.line, t.column, "hysteresis condition can not have duration"}
+	}
+
+	if p.accept("FOR") {
+		var err error
+		if cnd.For, err = p.duration(); err != nil {
+			return cnd, err
+		}
+	}
+
+	return cnd, nil
+}
+
+// missing parses duration for which the property needs to be missing.
+func (p *parser) missing(cnd *engine.Condition) error {
+	if err := p.expect("FOR"); err != nil {
+		return err
+	}
+
+	t := p.peek()
+	d, err := p.duration()
+	if err != nil {
+		return err
+	}
+
+	if d == 0 {
+		return &Error{t.line, t.column, fmt.Sprintf("invalid duration %s", t.text)}
+	}
+	cnd.Missing = d
+
+	return nil
+}
+
+// property parses reference to the device property.
+func (p *parser) property(cnd *engine.Condition) error {
+	if p.peek().kind != uuid {
+		return p.errorf("expected condition")
+	}
+	cnd.DeviceID = p.next().text
+
+	if err := p.expect("["); err != nil {
+		return err
+	}
+
+	prop, err := p.expectKind(str)
+	if err != nil {
+		return err
+	}
+	if prop.text == "" {
+		return &Error{prop.line, prop.column, "empty property"}
+	}
+	cnd.Property = prop.text
+
+	return p.expect("]")
+}
+
+// aggregate parses the function applied to the device property over either
+// time-based or count-based window, e.g. AVG(<device>["temp"] OVER 10m),
+// optionally filtering the samples, e.g. COUNT(<device>["open"] = true
+// OVER 1h). Delta has no window, e.g. DELTA(<device>["temp"]), while rate
+// has the unit of time followed by the optional time-based window, e.g.
+// RATE(<device>["temp"] PER 1m OVER 10m).
+func (p *parser) aggregate(cnd *engine.Condition) (engine.Aggregate, error) {
+	a := engine.Aggregate{Function: aggregates[p.next().text]}
+
+	if err := p.expect("("); err != nil {
+		return a, err
+	}
+
+	if err := p.property(cnd); err != nil {
+		return a, err
+	}
+
+	switch a.Function {
+	case engine.Delta:
+		return a, p.expect(")")
+	case engine.Rate:
+		if err := p.expect("PER"); err != nil {
+			return a, err
+		}
+
+		var err error
+		if a.Per, err = p.span(); err != nil {
+			return a, err
+		}
+
+		if p.accept("OVER") {
+			if a.Window, err = p.span(); err != nil {
+				return a, err
+			}
+		}
+
+		return a, p.expect(")")
+	}
+
+	if op, ok := p.operator(); ok {
+		f, err := p.filter(op)
+		if err != nil {
+			return a, err
+		}
+		a.Filter = f
+	}
+
+	if err := p.expect("OVER"); err != nil {
+		return a, err
+	}
+
+	switch t := p.peek(); {
+	case t.kind == number:
+		p.next()
+		samples, err := strconv.Atoi(t.text)
+		if err != nil || samples <= 0 {
+			return a, &Error{t.line, t.column, fmt.Sprintf("invalid number of samples %s", t.text)}
+		}
+		a.Samples = samples
+
+		if err := p.expect("SAMPLES"); err != nil {
+			return a, err
+		}
+	case t.kind == duration:
+		w, err := p.span()
+		if err != nil {
+			return a, err
+		}
+		a.Window = w
+	default:
+		return a, p.errorf("expected window")
+	}
+
+	return a, p.expect(")")
+}
+
+// filter parses the boolean, number or string against which the aggregated
+// samples are compared using the operator.
+func (p *parser) filter(op engine.Operator) (*engine.Filter, error) {
+	t := p.peek()
+	cnd := engine.Condition{Operator: op}
+	if err := p.value(&cnd); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cnd.Type != engine.Bool && cnd.Type != engine.String && cnd.Type != engine.Numeric:
+		return nil, &Error{t.line, t.column, fmt.Sprintf("samples can not be filtered by %s", t)}
+	case op == engine.Matches || !compatible(cnd):
+		return nil, &Error{t.line, t.column, fmt.Sprintf("operator %s can not be applied to %s", op, t)}
+	}
+
+	return &engine.Filter{Operator: mirror(op), Value: cnd.Value}, nil
+}
+
+// span parses positive duration.
+func (p *parser) span() (engine.Duration, error) {
+	t := p.peek()
+	d, err := p.duration()
+	if err != nil {
+		return 0, err
+	}
+
+	if d == 0 {
+		return 0, &Error{t.line, t.column, fmt.Sprintf("invalid window %s", t.text)}
+	}
+
+	return d, nil
+}
+
+// operator parses the comparison operator, which may span multiple words.
+func (p *parser) operator() (engine.Operator, bool) {
+	for text, op := range operators {
+		if p.accept(strings.Fields(text)...) {
+			return op, true
+		}
+	}
+
+	return engine.Undefined, false
+}
+
+func (p *parser) value(cnd *engine.Condition) error {
+	t := p.peek()
+
+	switch {
+	case t.kind == str:
+		cnd.Type = engine.String
+		cnd.Value = p.next().text
+	case t.kind == number:
+		v, err := p.number()
+		if err != nil {
+			return err
+		}
+		cnd.Type = engine.Numeric
+		cnd.Value = v
+	case p.accept("true"):
+		cnd.Type = engine.Bool
+		cnd.Value = true
+	case p.accept("false"):
+		cnd.Type = engine.Bool
+		cnd.Value = false
+	case p.is("[") && p.tokens[p.pos+1].kind == str:
+		p.next()
+		set, err := p.set()
+		if err != nil {
+			return err
+		}
+		cnd.Type = engine.Set
+		cnd.Value = set
+	case p.accept("["):
+		from, err := p.number()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		to, err := p.number()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+		if from >= to {
+			return &Error{t.line, t.column, "lower bound of the range must be less than upper bound"}
+		}
+		cnd.Type = engine.Between
+		cnd.Value = engine.Range{From: from, To: to}
+	default:
+		return p.errorf("expected value")
+	}
+
+	return nil
+}
+
+// mirror swaps sides of the ordering operator, leaving other operators as
+// they are.
+func mirror(op engine.Operator) engine.Operator {
+	if m, ok := mirrored[op]; ok {
+		return m
+	}
+
+	return op
+}
+
+// compatible checks that the condition's operator can be applied to its value.
+func compatible(cnd engine.Condition) bool {
+	switch op := cnd.Operator; cnd.Type {
+	case engine.Bool:
+		return op == engine.Eq || op == engine.Neq
+	case engine.String:
+		switch op {
+		case engine.Eq, engine.Neq, engine.Contains, engine.StartsWith, engine.EndsWith, engine.Matches:
+			return true
+		}
+		return false
+	case engine.Set:
+		return op == engine.In || op == engine.NotIn
+	case engine.Between:
+		return op == engine.Btw
+	}
+
+	switch cnd.Operator {
+	case engine.Btw, engine.Contains, engine.StartsWith, engine.EndsWith, engine.Matches, engine.In, engine.NotIn:
+		return false
+	}
+
+	return true
+}
+
+// resetSide describes on which side of the condition's value its reset
+// threshold needs to be, if the condition can have one.
+func resetSide(cnd engine.Condition) string {
+	v, ok := cnd.Value.(float64)
+	if !ok {
+		return ""
+	}
+
+	switch mirror(cnd.Operator) {
+	case engine.Gt, engine.Gte:
+		return fmt.Sprintf(", expected threshold below %v", v)
+	case engine.Lt, engine.Lte:
+		return fmt.Sprintf(", expected threshold above %v", v)
+	}
+
+	return ""
+}
+
+// set parses strings of the set, following its opening bracket.
+func (p *parser) set() ([]string, error) {
+	var set []string
+	for {
+		t, err := p.expectKind(str)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, t.text)
+
+		if !p.accept(",") {
+			return set, p.expect("]")
+		}
+	}
+}
+
+func (p *parser) number() (float64, error) {
+	t, err := p.expectKind(number)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, &Error{t.line, t.column, fmt.Sprintf("invalid number %s", t.text)}
+	}
+
+	return v, nil
+}
+
+func (p *parser) duration() (engine.Duration, error) {
+	t := p.peek()
+	if t.kind != duration && !(t.kind == number && t.text == "0") {
+		return 0, p.errorf("expected duration")
+	}
+	p.next()
+
+	d, err := engine.ParseDuration(t.text)
+	if err != nil {
+		return 0, &Error{t.line, t.column, fmt.Sprintf("invalid duration %s", t.text)}
+	}
+
+	return d, nil
+}
+
+// actions parses non-empty list of actions.
+func (p *parser) actions() ([]engine.Action, error) {
+	var actions []engine.Action
+
+	for {
+		action, ok, err := p.action()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+		actions = append(actions, action)
+	}
+
+	if len(actions) == 0 {
+		return nil, p.errorf("expected action")
+	}
+
+	return actions, nil
+}
+
+// action parses single action, if the following tokens start one.
+func (p *parser) action() (engine.Action, bool, error) {
+	switch {
+	case p.accept("SEND", "EMAIL"):
+		a, err := p.sendEmail()
+		return a, true, err
+	case p.accept("TURN", "OFF"):
+		t, err := p.expectKind(uuid)
+		return engine.TurnOffAction{Name: engine.TurnOff, DeviceId: t.text}, true, err
+	case p.accept("WEBHOOK"):
+		a, err := p.webhook()
+		return a, true, err
+	case p.accept("PUBLISH"):
+		a, err := p.publish()
+		return a, true, err
+	}
+
+	return nil, false, nil
+}
+
+func (p *parser) sendEmail() (engine.Action, error) {
+	action := engine.SendEmailAction{Name: engine.SendEmail}
+
+	content, err := p.expectKind(str)
+	if err != nil {
+		return nil, err
+	}
+	action.Content = content.text
+
+	if err := p.expect("TO"); err != nil {
+		return nil, err
+	}
+
+	recipient, err := p.expectKind(str)
+	if err != nil {
+		return nil, err
+	}
+	action.Recipient = recipient.text
+
+	return action, nil
+}
+
+func (p *parser) webhook() (engine.Action, error) {
+	action := engine.WebhookAction{Name: engine.Webhook, Method: http.MethodPost}
+
+	if t := p.peek(); t.kind == word && methods[t.text] {
+		action.Method = p.next().text
+	}
+
+	url, err := p.expectKind(str)
+	if err != nil {
+		return nil, err
+	}
+	action.URL = url.text
+
+	if p.accept("HEADERS") {
+		if action.Headers, err = p.headers(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("BODY") {
+		body, err := p.expectKind(str)
+		if err != nil {
+			return nil, err
+		}
+		action.Body = body.text
+	}
+
+	if p.accept("TIMEOUT") {
+		if action.Timeout, err = p.duration(); err != nil {
+			return nil, err
+		}
+	}
+
+	return action, nil
+}
+
+func (p *parser) headers() (map[string]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	for !p.accept("}") {
+		if len(headers) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		name, err := p.expectKind(str)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		value, err := p.expectKind(str)
+		if err != nil {
+			return nil, err
+		}
+		headers[name.text] = value.text
+	}
+
+	return headers, nil
+}
+
+func (p *parser) publish() (engine.Action, error) {
+	action := engine.PublishAction{Name: engine.Publish}
+
+	if p.peek().kind == str {
+		action.Template = p.next().text
+	}
+
+	if err := p.expect("TO"); err != nil {
+		return nil, err
+	}
+
+	subject, err := p.expectKind(str)
+	if err != nil {
+		return nil, err
+	}
+	action.Subject = subject.text
+
+	return action, nil
+}
@@ -0,0 +1,364 @@
+package dsl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	device    = "8837ffdf-2bec-42f7-9c2d-b8cfa67661a9"
+	heater    = "45ab8a94-e0f1-4ea4-b99c-c51e16566f83"
+	simple    = "RULE rule01:\n    " + device + `["temperature"] >= 30` + "\nTRIGGERS\n    TURN OFF " + heater + "\n"
+	temp      = `["temperature"]`
+	smoke     = `["smoke"]`
+	mode      = `["mode"]`
+	sendEmail = `SEND EMAIL "Alert!" TO "person01@home.com"`
+)
+
+var (
+	hot      = engine.Condition{DeviceID: device, Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(60)}
+	smoking  = engine.Condition{DeviceID: device, Property: "smoke", Operator: engine.Eq, Type: engine.Bool, Value: true}
+	testMode = engine.Condition{DeviceID: device, Property: "mode", Operator: engine.Eq, Type: engine.String, Value: "test"}
+	turnOff  = engine.TurnOffAction{Name: engine.TurnOff, DeviceId: heater}
+	email    = engine.SendEmailAction{Name: engine.SendEmail, Content: "Alert!", Recipient: "person01@home.com"}
+)
+
+func TestParse(t *testing.T) {
+	high, low, cold := float64(27), float64(-2.5), float64(30)
+	pattern, _ := engine.CompilePattern(`^t\w+$`)
+	cases := []struct {
+		desc  string
+		src   string
+		rules []engine.Rule
+	}{
+		{
+			"simple rule",
+			simple,
+			[]engine.Rule{{
+				Name:       "rule01",
+				Conditions: []engine.Condition{{DeviceID: device, Property: "temperature", Operator: engine.Lte, Type: engine.Numeric, Value: float64(30)}},
+				Actions:    []engine.Action{turnOff},
+			}},
+		},
+		{
+			"multiple conditions and actions",
+			fmt.Sprintf(`RULE "living room": %s%s > 60 %s%s = true %s%s BETWEEN [1, 4.5] FOR 5m TRIGGERS TURN OFF %s %s`, device, temp, device, smoke, heater, `["active"]`, heater, sendEmail),
+			[]engine.Rule{{
+				Name: "living room",
+				Conditions: []engine.Condition{
+					hot,
+					smoking,
+					{DeviceID: heater, Property: "active", Operator: engine.Btw, Type: engine.Between, Value: engine.Range{From: 1, To: 4.5}, For: engine.Duration(5 * time.Minute)},
+				},
+				Actions: []engine.Action{turnOff, email},
+			}},
+		},
+		{
+			"expression precedence",
+			fmt.Sprintf(`RULE: %s%s > 60 OR %s%s = true AND NOT %s%s = "test" TRIGGERS %s`, device, temp, device, smoke, device, mode, sendEmail),
+			[]engine.Rule{{
+				Expression: &engine.Expression{Operator: engine.Or, Operands: []engine.Expression{
+					engine.Leaf(hot),
+					{Operator: engine.And, Operands: []engine.Expression{
+						engine.Leaf(smoking),
+						{Operator: engine.Not, Operands: []engine.Expression{engine.Leaf(testMode)}},
+					}},
+				}},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"parentheses and multiple expressions",
+			fmt.Sprintf(`RULE: (%s%s > 60 OR %s%s = true) AND %s%s != "test" NOT %s%s = true TRIGGERS %s`, device, temp, device, smoke, device, mode, device, smoke, sendEmail),
+			[]engine.Rule{{
+				Expression: &engine.Expression{Operator: engine.And, Operands: []engine.Expression{
+					{Operator: engine.And, Operands: []engine.Expression{
+						{Operator: engine.Or, Operands: []engine.Expression{engine.Leaf(hot), engine.Leaf(smoking)}},
+						engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.Neq, Type: engine.String, Value: "test"}),
+					}},
+					{Operator: engine.Not, Operands: []engine.Expression{engine.Leaf(smoking)}},
+				}},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"trigger, throttle and resolved actions",
+			fmt.Sprintf(`RULE r: %s%s > 60 TRIGGERS ON RISE COOLDOWN 5m LIMIT 10 PER 1h30m %s RESOLVED TURN OFF %s`, device, temp, sendEmail, heater),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{hot},
+				Trigger:    engine.Rising,
+				Throttle:   &engine.Throttle{Cooldown: engine.Duration(5 * time.Minute), Limit: 10, Window: engine.Duration(90 * time.Minute)},
+				Actions:    []engine.Action{email},
+				Resolved:   []engine.Action{turnOff},
+			}},
+		},
+		{
+			"retry policy",
+			fmt.Sprintf(`RULE r: %s%s > 60 TRIGGERS COOLDOWN 5m RETRY 5 BACKOFF 10s MAX 5m %s`, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{hot},
+				Throttle:   &engine.Throttle{Cooldown: engine.Duration(5 * time.Minute)},
+				Retry:      &engine.RetryPolicy{Attempts: 5, Backoff: engine.Duration(10 * time.Second), MaxBackoff: engine.Duration(5 * time.Minute)},
+				Actions:    []engine.Action{email},
+			}},
+		},
+		{
+			"schedule",
+			fmt.Sprintf(`RULE r: %s%s > 60 ACTIVE ON MON, TUE FROM 18:00 TO 8:00 OR ON SAT, SUN OR FROM 22:00 TO 24:00 ZONE "Europe/Belgrade" TRIGGERS %s`, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{hot},
+				Schedule: &engine.Schedule{
+					Zone: "Europe/Belgrade",
+					Windows: []engine.Window{
+						{Days: []string{"MON", "TUE"}, From: 18 * 60, To: 8 * 60},
+						{Days: []string{"SAT", "SUN"}},
+						{From: 22 * 60},
+					},
+				},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"aggregates",
+			fmt.Sprintf(`RULE r: AVG(%s%s OVER 10m) > 28 OR COUNT(%s%s OVER 1h) >= 5 MAX(%s%s OVER 5 SAMPLES) BETWEEN [1, 2] TRIGGERS %s`, device, temp, device, smoke, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name: "r",
+				Conditions: []engine.Condition{
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Max, Samples: 5}, Operator: engine.Btw, Type: engine.Between, Value: engine.Range{From: 1, To: 2}},
+				},
+				Expression: &engine.Expression{Operator: engine.Or, Operands: []engine.Expression{
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Avg, Window: engine.Duration(10 * time.Minute)}, Operator: engine.Lt, Type: engine.Numeric, Value: float64(28)}),
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "smoke", Aggregate: &engine.Aggregate{Function: engine.Count, Window: engine.Duration(time.Hour)}, Operator: engine.Lte, Type: engine.Numeric, Value: float64(5)}),
+				}},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"filtered aggregates",
+			fmt.Sprintf(`RULE r: COUNT(%s%s = true OVER 1h) >= 5 AVG(%s%s > 0 OVER 5 SAMPLES) < 10 COUNT(%s%s STARTS WITH "auto" OVER 1h) > 2 TRIGGERS %s`, device, smoke, device, temp, device, mode, sendEmail),
+			[]engine.Rule{{
+				Name: "r",
+				Conditions: []engine.Condition{
+					{DeviceID: device, Property: "smoke", Aggregate: &engine.Aggregate{Function: engine.Count, Window: engine.Duration(time.Hour), Filter: &engine.Filter{Operator: engine.Eq, Value: true}}, Operator: engine.Lte, Type: engine.Numeric, Value: float64(5)},
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Avg, Samples: 5, Filter: &engine.Filter{Operator: engine.Lt, Value: float64(0)}}, Operator: engine.Gt, Type: engine.Numeric, Value: float64(10)},
+					{DeviceID: device, Property: "mode", Aggregate: &engine.Aggregate{Function: engine.Count, Window: engine.Duration(time.Hour), Filter: &engine.Filter{Operator: engine.StartsWith, Value: "auto"}}, Operator: engine.Lt, Type: engine.Numeric, Value: float64(2)},
+				},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"delta and rate",
+			fmt.Sprintf(`RULE r: DELTA(%s%s) > 5 RATE(%s%s PER 1m) > 2 RATE(%s%s PER 1s OVER 10m) < -0.5 TRIGGERS %s`, device, temp, device, temp, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name: "r",
+				Conditions: []engine.Condition{
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Delta}, Operator: engine.Lt, Type: engine.Numeric, Value: float64(5)},
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Rate, Per: engine.Duration(time.Minute)}, Operator: engine.Lt, Type: engine.Numeric, Value: float64(2)},
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Rate, Per: engine.Duration(time.Second), Window: engine.Duration(10 * time.Minute)}, Operator: engine.Gt, Type: engine.Numeric, Value: float64(-0.5)},
+				},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"missing data",
+			fmt.Sprintf(`RULE r: %s%s MISSING FOR 10m OR %s%s > 60 TRIGGERS ON RISE %s`, device, temp, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Expression: &engine.Expression{Operator: engine.Or, Operands: []engine.Expression{
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "temperature", Missing: engine.Duration(10 * time.Minute)}),
+					engine.Leaf(hot),
+				}},
+				Trigger: engine.Rising,
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"hysteresis",
+			fmt.Sprintf(`RULE r: %s%s > 30 RESET 27 AVG(%s%s OVER 10m) <= -5 RESET -2.5 TRIGGERS %s`, device, temp, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name: "r",
+				Conditions: []engine.Condition{
+					{DeviceID: device, Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), Reset: &high},
+					{DeviceID: device, Property: "temperature", Aggregate: &engine.Aggregate{Function: engine.Avg, Window: engine.Duration(10 * time.Minute)}, Operator: engine.Gte, Type: engine.Numeric, Value: float64(-5), Reset: &low},
+				},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"hysteresis turned on above value and off below threshold",
+			fmt.Sprintf(`RULE r: %s%s > 30 RESET 27 TRIGGERS %s`, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{{DeviceID: device, Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), Reset: &high}},
+				Actions:    []engine.Action{email},
+			}},
+		},
+		{
+			"hysteresis turned on below value and off above threshold",
+			fmt.Sprintf(`RULE r: %s%s < 27 RESET 30 TRIGGERS %s`, device, temp, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{{DeviceID: device, Property: "temperature", Operator: engine.Gt, Type: engine.Numeric, Value: float64(27), Reset: &cold}},
+				Actions:    []engine.Action{email},
+			}},
+		},
+		{
+			"string operators",
+			fmt.Sprintf(`RULE r: %s%s CONTAINS "te" OR %s%s STARTS WITH "a" OR %s%s ENDS WITH "z" OR %s%s MATCHES "^t\\w+$" TRIGGERS %s`, device, mode, device, mode, device, mode, device, mode, sendEmail),
+			[]engine.Rule{{
+				Name: "r",
+				Expression: &engine.Expression{Operator: engine.Or, Operands: []engine.Expression{
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.Contains, Type: engine.String, Value: "te"}),
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.StartsWith, Type: engine.String, Value: "a"}),
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.EndsWith, Type: engine.String, Value: "z"}),
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.Matches, Type: engine.String, Value: pattern}),
+				}},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"membership operators",
+			fmt.Sprintf(`RULE r: %s%s IN ["auto", "eco"] NOT %s%s NOT IN ["test"] TRIGGERS %s`, device, mode, device, mode, sendEmail),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{{DeviceID: device, Property: "mode", Operator: engine.In, Type: engine.Set, Value: []string{"auto", "eco"}}},
+				Expression: &engine.Expression{Operator: engine.Not, Operands: []engine.Expression{
+					engine.Leaf(engine.Condition{DeviceID: device, Property: "mode", Operator: engine.NotIn, Type: engine.Set, Value: []string{"test"}}),
+				}},
+				Actions: []engine.Action{email},
+			}},
+		},
+		{
+			"webhook and publish actions",
+			fmt.Sprintf(`RULE r: %s%s > 60 TRIGGERS
+				WEBHOOK PUT "https://hooks.home.com/alerts" HEADERS {"Authorization": "Bearer token", "X-Id": "1"} BODY "{\"text\": \"{{.Value}}\"}" TIMEOUT 5s
+				WEBHOOK "https://hooks.home.com/alerts"
+				PUBLISH "[{\"n\": \"alarm\", \"vb\": true}]" TO "msg.alarms"
+				PUBLISH TO "alarms"`, device, temp),
+			[]engine.Rule{{
+				Name:       "r",
+				Conditions: []engine.Condition{hot},
+				Actions: []engine.Action{
+					engine.WebhookAction{
+						Name:    engine.Webhook,
+						URL:     "https://hooks.home.com/alerts",
+						Method:  "PUT",
+						Headers: map[string]string{"Authorization": "Bearer token", "X-Id": "1"},
+						Body:    `{"text": "{{.Value}}"}`,
+						Timeout: engine.Duration(5 * time.Second),
+					},
+					engine.WebhookAction{Name: engine.Webhook, URL: "https://hooks.home.com/alerts", Method: "POST"},
+					engine.PublishAction{Name: engine.Publish, Subject: "msg.alarms", Template: `[{"n": "alarm", "vb": true}]`},
+					engine.PublishAction{Name: engine.Publish, Subject: "alarms"},
+				},
+			}},
+		},
+		{
+			"multiple rules",
+			simple + simple,
+			[]engine.Rule{
+				{Name: "rule01", Conditions: []engine.Condition{{DeviceID: device, Property: "temperature", Operator: engine.Lte, Type: engine.Numeric, Value: float64(30)}}, Actions: []engine.Action{turnOff}},
+				{Name: "rule01", Conditions: []engine.Condition{{DeviceID: device, Property: "temperature", Operator: engine.Lte, Type: engine.Numeric, Value: float64(30)}}, Actions: []engine.Action{turnOff}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		rules, err := Parse(tc.src)
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.rules, rules, fmt.Sprintf("%s: unexpected rules", tc.desc))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		desc   string
+		src    string
+		line   int
+		column int
+	}{
+		{"empty source", "  ", 1, 3},
+		{"missing colon", "RULE r " + device + temp + " > 1 TRIGGERS " + sendEmail, 1, 8},
+		{"missing conditions", "RULE r:\nTRIGGERS " + sendEmail, 2, 1},
+		{"missing actions", "RULE r:\n" + device + temp + " > 1\nTRIGGERS\n", 4, 1},
+		{"invalid device", "RULE r:\n  device" + temp + " > 1 TRIGGERS " + sendEmail, 2, 3},
+		{"invalid operator", "RULE r:\n  " + device + temp + " LIKE 1 TRIGGERS " + sendEmail, 2, 55},
+		{"operator not applicable", "RULE r:\n  " + device + temp + " > true TRIGGERS " + sendEmail, 2, 57},
+		{"invalid range", "RULE r:\n  " + device + temp + " BETWEEN [4, 1] TRIGGERS " + sendEmail, 2, 63},
+		{"unterminated string", "RULE r:\n  " + device + `["temperature] > 1`, 2, 40},
+		{"unclosed parenthesis", "RULE r:\n  (" + device + temp + " > 1 TRIGGERS " + sendEmail, 2, 60},
+		{"invalid duration", "RULE r:\n  " + device + temp + " > 1 FOR 5 TRIGGERS " + sendEmail, 2, 63},
+		{"invalid token", "RULE r:\n  " + device + temp + " > 1 TRIGGERS TURN OFF 1-2", 2, 77},
+		{"invalid character", "RULE r: @", 1, 9},
+		{"invalid attempts", "RULE r:\n  " + device + temp + " > 1 TRIGGERS RETRY 0 " + sendEmail, 2, 74},
+		{"too many attempts", "RULE r:\n  " + device + temp + " > 1 TRIGGERS RETRY 101 " + sendEmail, 2, 74},
+		{"invalid day", "RULE r:\n  " + device + temp + " > 1 ACTIVE ON MONDAY TRIGGERS " + sendEmail, 2, 69},
+		{"missing window", "RULE r:\n  " + device + temp + " > 1 ACTIVE TRIGGERS " + sendEmail, 2, 66},
+		{"invalid time of day", "RULE r:\n  " + device + temp + " > 1 ACTIVE FROM 25:00 TO 08:00 TRIGGERS " + sendEmail, 2, 71},
+		{"invalid time window", "RULE r:\n  " + device + temp + " > 1 ACTIVE FROM 08:00 TO 08:00 TRIGGERS " + sendEmail, 2, 80},
+		{"unknown time zone", "RULE r:\n  " + device + temp + ` > 1 ACTIVE ON SAT ZONE "Mars/Olympus" TRIGGERS ` + sendEmail, 2, 78},
+		{"missing samples keyword", "RULE r:\n  AVG(" + device + temp + " OVER 5) > 1 TRIGGERS " + sendEmail, 2, 65},
+		{"invalid number of samples", "RULE r:\n  AVG(" + device + temp + " OVER 0 SAMPLES) > 1 TRIGGERS " + sendEmail, 2, 64},
+		{"missing aggregate window", "RULE r:\n  AVG(" + device + temp + ") > 1 TRIGGERS " + sendEmail, 2, 58},
+		{"aggregate compared with boolean", "RULE r:\n  COUNT(" + device + smoke + " OVER 1h) = true TRIGGERS " + sendEmail, 2, 66},
+		{"filter by range", "RULE r:\n  COUNT(" + device + temp + " BETWEEN [1, 2] OVER 1h) > 1 TRIGGERS " + sendEmail, 2, 69},
+		{"filter by pattern", "RULE r:\n  COUNT(" + device + mode + ` MATCHES "^a" OVER 1h) > 1 TRIGGERS ` + sendEmail, 2, 62},
+		{"incompatible filter", "RULE r:\n  COUNT(" + device + smoke + " > true OVER 1h) > 1 TRIGGERS " + sendEmail, 2, 57},
+		{"filtered delta", "RULE r:\n  DELTA(" + device + temp + " > 1) > 1 TRIGGERS " + sendEmail, 2, 61},
+		{"aggregate with duration", "RULE r:\n  AVG(" + device + temp + " OVER 10m) > 1 FOR 5m TRIGGERS " + sendEmail, 2, 73},
+		{"delta with window", "RULE r:\n  DELTA(" + device + temp + " OVER 10m) > 1 TRIGGERS " + sendEmail, 2, 61},
+		{"rate without unit", "RULE r:\n  RATE(" + device + temp + ") > 1 TRIGGERS " + sendEmail, 2, 59},
+		{"invalid rate unit", "RULE r:\n  RATE(" + device + temp + " PER 0) > 1 TRIGGERS " + sendEmail, 2, 64},
+		{"missing data without duration", "RULE r:\n  " + device + temp + " MISSING 5m TRIGGERS " + sendEmail, 2, 63},
+		{"invalid missing data duration", "RULE r:\n  " + device + temp + " MISSING FOR 0s TRIGGERS " + sendEmail, 2, 67},
+		{"reset threshold not looser than value", "RULE r:\n  " + device + temp + " < 30 RESET 27 TRIGGERS " + sendEmail, 2, 66},
+		{"reset threshold of equality", "RULE r:\n  " + device + smoke + " = true RESET 1 TRIGGERS " + sendEmail, 2, 62},
+		{"hysteresis with duration", "RULE r:\n  " + device + temp + " > 30 RESET 27 FOR 5m TRIGGERS " + sendEmail, 2, 69},
+		{"string operator applied to number", "RULE r:\n  " + device + temp + " CONTAINS 1 TRIGGERS " + sendEmail, 2, 64},
+		{"invalid pattern", "RULE r:\n  " + device + mode + ` MATCHES "(" TRIGGERS ` + sendEmail, 2, 56},
+		{"empty set", "RULE r:\n  " + device + mode + " IN [] TRIGGERS " + sendEmail, 2, 52},
+		{"set of numbers", "RULE r:\n  " + device + mode + ` IN ["a", 1] TRIGGERS ` + sendEmail, 2, 57},
+		{"membership in string", "RULE r:\n  " + device + mode + ` NOT IN "a" TRIGGERS ` + sendEmail, 2, 55},
+		{"incomplete operator", "RULE r:\n  " + device + mode + ` STARTS "a" TRIGGERS ` + sendEmail, 2, 48},
+		{"aggregate of set", "RULE r:\n  COUNT(" + device + mode + ` OVER 1h) IN ["a"] TRIGGERS ` + sendEmail, 2, 66},
+		{"invalid maximum backoff", "RULE r:\n  " + device + temp + " > 1 TRIGGERS RETRY 3 BACKOFF 1m MAX 1s " + sendEmail, 2, 91},
+	}
+
+	for _, tc := range cases {
+		_, err := Parse(tc.src)
+		e, ok := err.(*Error)
+		if !assert.True(t, ok, fmt.Sprintf("%s: expected syntax error, got %v", tc.desc, err)) {
+			continue
+		}
+		assert.Equal(t, tc.line, e.Line, fmt.Sprintf("%s: unexpected line in %s", tc.desc, e))
+		assert.Equal(t, tc.column, e.Column, fmt.Sprintf("%s: unexpected column in %s", tc.desc, e))
+	}
+}
+
+func TestParseResetDirection(t *testing.T) {
+	cases := []struct {
+		desc string
+		src  string
+		msg  string
+	}{
+		{"greater than", "RULE r: " + device + temp + " > 27 RESET 30 TRIGGERS " + sendEmail, "invalid reset threshold 30, expected threshold below 27"},
+		{"less than", "RULE r: " + device + temp + " <= 30 RESET 27 TRIGGERS " + sendEmail, "invalid reset threshold 27, expected threshold above 30"},
+		{"equality", "RULE r: " + device + temp + " = 27 RESET 30 TRIGGERS " + sendEmail, "invalid reset threshold 30"},
+	}
+
+	for _, tc := range cases {
+		_, err := Parse(tc.src)
+		e, ok := err.(*Error)
+		if !assert.True(t, ok, fmt.Sprintf("%s: expected syntax error, got %v", tc.desc, err)) {
+			continue
+		}
+		assert.Equal(t, tc.msg, e.Message, fmt.Sprintf("%s: unexpected message", tc.desc))
+	}
+}
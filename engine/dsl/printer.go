@@ -0,0 +1,295 @@
+package dsl
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+const indent = "    "
+
+// Format prints rules using the DSL. Printed rules are parsed back to the
+// same rules, except for their identifiers and owners.
+func Format(rules ...engine.Rule) string {
+	var buf bytes.Buffer
+
+	for i, rule := range rules {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		formatRule(&buf, rule)
+	}
+
+	return buf.String()
+}
+
+func formatRule(buf *bytes.Buffer, rule engine.Rule) {
+	buf.WriteString("RULE")
+	if rule.Name != "" {
+		buf.WriteString(" " + formatName(rule.Name))
+	}
+	buf.WriteString(":\n")
+
+	for _, cnd := range rule.Conditions {
+		buf.WriteString(indent + formatCondition(cnd) + "\n")
+	}
+
+	if rule.Expression != nil {
+		// Conjunction is split into multiple lines, which are bound by AND.
+		operands := []engine.Expression{*rule.Expression}
+		if rule.Expression.Operator == engine.And && rule.Expression.Condition == nil {
+			operands = rule.Expression.Operands
+		}
+
+		for _, o := range operands {
+			buf.WriteString(indent + formatExpression(o, engine.Or) + "\n")
+		}
+	}
+
+	if rule.Schedule != nil {
+		buf.WriteString(formatSchedule(*rule.Schedule) + "\n")
+	}
+
+	buf.WriteString("TRIGGERS")
+	switch rule.Trigger {
+	case engine.Rising:
+		buf.WriteString(" ON RISE")
+	case engine.Falling:
+		buf.WriteString(" ON FALL")
+	case engine.Level:
+		buf.WriteString(" ALWAYS")
+	}
+
+	if th := rule.Throttle; th != nil {
+		if th.Cooldown > 0 {
+			buf.WriteString(" COOLDOWN " + formatDuration(th.Cooldown))
+		}
+		if th.Limit > 0 {
+			buf.WriteString(fmt.Sprintf(" LIMIT %d PER %s", th.Limit, formatDuration(th.Window)))
+		}
+	}
+
+	if rp := rule.Retry; rp != nil {
+		buf.WriteString(fmt.Sprintf(" RETRY %d", rp.Attempts))
+		if rp.Backoff > 0 {
+			buf.WriteString(" BACKOFF " + formatDuration(rp.Backoff))
+		}
+		if rp.MaxBackoff > 0 {
+			buf.WriteString(" MAX " + formatDuration(rp.MaxBackoff))
+		}
+	}
+	buf.WriteString("\n")
+
+	for _, a := range rule.Actions {
+		buf.WriteString(indent + formatAction(a) + "\n")
+	}
+
+	if len(rule.Resolved) > 0 {
+		buf.WriteString("RESOLVED\n")
+		for _, a := range rule.Resolved {
+			buf.WriteString(indent + formatAction(a) + "\n")
+		}
+	}
+}
+
+// formatExpression prints the expression, enclosing it in parentheses if
+// it binds weaker than the enclosing operator.
+func formatExpression(e engine.Expression, enclosing engine.LogicalOperator) string {
+	if e.Condition != nil {
+		return formatCondition(*e.Condition)
+	}
+
+	var s string
+	switch e.Operator {
+	case engine.Not:
+		if len(e.Operands) == 1 {
+			return "NOT " + formatExpression(e.Operands[0], engine.Not)
+		}
+	case engine.And, engine.Or:
+		for i, o := range e.Operands {
+			if i > 0 {
+				s += " " + string(e.Operator) + " "
+			}
+			s += formatExpression(o, e.Operator)
+		}
+	}
+
+	if precedence(e.Operator) < precedence(enclosing) {
+		return "(" + s + ")"
+	}
+
+	return s
+}
+
+func precedence(op engine.LogicalOperator) int {
+	switch op {
+	case engine.Or:
+		return 1
+	case engine.And:
+		return 2
+	}
+
+	return 3
+}
+
+func formatCondition(cnd engine.Condition) string {
+	ref := fmt.Sprintf("%s[%s]", cnd.DeviceID, strconv.Quote(cnd.Property))
+	if a := cnd.Aggregate; a != nil {
+		if f := a.Filter; f != nil {
+			ref += fmt.Sprintf(" %s %s", mirror(f.Operator), formatValue(f.Value))
+		}
+
+		switch {
+		case a.Function == engine.Delta:
+		case a.Function == engine.Rate:
+			ref += " PER " + formatDuration(a.Per)
+			if a.Window > 0 {
+				ref += " OVER " + formatDuration(a.Window)
+			}
+		case a.Samples > 0:
+			ref += fmt.Sprintf(" OVER %d SAMPLES", a.Samples)
+		default:
+			ref += " OVER " + formatDuration(a.Window)
+		}
+		ref = fmt.Sprintf("%s(%s)", strings.ToUpper(string(a.Function)), ref)
+	}
+
+	if cnd.Missing > 0 {
+		return ref + " MISSING FOR " + formatDuration(cnd.Missing)
+	}
+
+	s := fmt.Sprintf("%s %s %s", ref, mirror(cnd.Operator), formatValue(cnd.Value))
+	if cnd.Reset != nil {
+		s += " RESET " + formatNumber(*cnd.Reset)
+	}
+	if cnd.For > 0 {
+		s += " FOR " + formatDuration(cnd.For)
+	}
+
+	return s
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case engine.Pattern:
+		return strconv.Quote(v.String())
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	case engine.Range:
+		return fmt.Sprintf("[%s, %s]", formatNumber(v.From), formatNumber(v.To))
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatSchedule(s engine.Schedule) string {
+	var windows []string
+	for _, w := range s.Windows {
+		var parts []string
+		if len(w.Days) > 0 {
+			parts = append(parts, "ON "+strings.Join(w.Days, ", "))
+		}
+
+		if w.From != 0 || w.To != 0 {
+			// Interval ending at midnight is printed as ending at 24:00.
+			to := w.To.String()
+			if w.To == 0 {
+				to = "24:00"
+			}
+			parts = append(parts, fmt.Sprintf("FROM %s TO %s", w.From, to))
+		}
+		windows = append(windows, strings.Join(parts, " "))
+	}
+
+	src := "ACTIVE " + strings.Join(windows, " OR ")
+	if s.Zone != "" {
+		src += " ZONE " + strconv.Quote(s.Zone)
+	}
+
+	return src
+}
+
+func formatDuration(d engine.Duration) string {
+	return time.Duration(d).String()
+}
+
+func formatName(name string) string {
+	if wordPattern.MatchString(name) {
+		return name
+	}
+
+	return strconv.Quote(name)
+}
+
+func formatAction(action engine.Action) string {
+	switch a := action.(type) {
+	case engine.SendEmailAction:
+		return fmt.Sprintf("SEND EMAIL %s TO %s", strconv.Quote(a.Content), strconv.Quote(a.Recipient))
+	case engine.TurnOffAction:
+		return "TURN OFF " + a.DeviceId
+	case engine.WebhookAction:
+		return formatWebhook(a)
+	case engine.PublishAction:
+		if a.Template == "" {
+			return "PUBLISH TO " + strconv.Quote(a.Subject)
+		}
+		return fmt.Sprintf("PUBLISH %s TO %s", strconv.Quote(a.Template), strconv.Quote(a.Subject))
+	}
+
+	return ""
+}
+
+func formatWebhook(a engine.WebhookAction) string {
+	s := "WEBHOOK "
+	if a.Method != "" && a.Method != http.MethodPost {
+		s += a.Method + " "
+	}
+	s += strconv.Quote(a.URL)
+
+	if len(a.Headers) > 0 {
+		var names []string
+		for name := range a.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		s += " HEADERS {"
+		for i, name := range names {
+			if i > 0 {
+				s += ", "
+			}
+			s += strconv.Quote(name) + ": " + strconv.Quote(a.Headers[name])
+		}
+		s += "}"
+	}
+
+	if a.Body != "" {
+		s += " BODY " + strconv.Quote(a.Body)
+	}
+
+	if a.Timeout > 0 {
+		s += " TIMEOUT " + formatDuration(a.Timeout)
+	}
+
+	return s
+}
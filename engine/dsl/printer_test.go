@@ -0,0 +1,68 @@
+package dsl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	rule := engine.Rule{
+		Name:       "living room",
+		Conditions: []engine.Condition{hot},
+		Expression: &engine.Expression{Operator: engine.Not, Operands: []engine.Expression{
+			{Operator: engine.Or, Operands: []engine.Expression{engine.Leaf(smoking), engine.Leaf(testMode)}},
+		}},
+		Trigger:  engine.Falling,
+		Throttle: &engine.Throttle{Cooldown: engine.Duration(time.Minute)},
+		Actions: []engine.Action{
+			email,
+			engine.WebhookAction{Name: engine.Webhook, URL: "https://hooks.home.com", Method: "PATCH", Headers: map[string]string{"b": "2", "a": "1"}, Timeout: engine.Duration(5 * time.Second)},
+		},
+		Resolved: []engine.Action{engine.PublishAction{Name: engine.Publish, Subject: "alarms"}},
+	}
+
+	expected := `RULE "living room":
+    ` + device + `["temperature"] > 60
+    NOT (` + device + `["smoke"] = true OR ` + device + `["mode"] = "test")
+TRIGGERS ON FALL COOLDOWN 1m0s
+    SEND EMAIL "Alert!" TO "person01@home.com"
+    WEBHOOK PATCH "https://hooks.home.com" HEADERS {"a": "1", "b": "2"} TIMEOUT 5s
+RESOLVED
+    PUBLISH TO "alarms"
+`
+
+	assert.Equal(t, expected, Format(rule), "unexpected format")
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	srcs := []string{
+		simple,
+		fmt.Sprintf(`RULE: %s%s > 60 OR %s%s = true AND NOT %s%s = "test" TRIGGERS %s`, device, temp, device, smoke, device, mode, sendEmail),
+		fmt.Sprintf(`RULE: (%s%s > 60 OR %s%s = true) AND NOT (%s%s = "te\"st" AND %s%s != false) TRIGGERS %s`, device, temp, device, smoke, device, mode, device, smoke, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s BETWEEN [-1.5, 1e3] FOR 90s TRIGGERS ON RISE LIMIT 3 PER 1h %s RESOLVED %s`, device, temp, sendEmail, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s < 1 TRIGGERS RETRY 3 BACKOFF 1s %s`, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s < 1 TRIGGERS ALWAYS COOLDOWN 5m %s`, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: DELTA(%s%s) >= 5 AND RATE(%s%s PER 1m) > 2 OR RATE(%s%s PER 1h OVER 1h30m) < 0 TRIGGERS %s`, device, temp, device, temp, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s MISSING FOR 5m OR NOT %s%s MISSING FOR 1h30m TRIGGERS ON RISE %s`, device, temp, device, smoke, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s >= 30.5 RESET 27 OR MAX(%s%s OVER 5 SAMPLES) < -1 RESET 0.5 TRIGGERS ON RISE %s`, device, temp, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s STARTS WITH "a\"b" OR %s%s MATCHES "^\\d+$" OR NOT %s%s NOT IN ["a", "b c"] TRIGGERS %s`, device, mode, device, mode, device, mode, sendEmail),
+		fmt.Sprintf(`RULE r: SUM(%s%s OVER 90s) >= 10 OR NOT MIN(%s%s OVER 10 SAMPLES) BETWEEN [0, 1] TRIGGERS %s`, device, temp, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: COUNT(%s%s = true OVER 1h) >= 5 OR AVG(%s%s > -1.5 OVER 10 SAMPLES) < 0 OR COUNT(%s%s != "test" OVER 90s) > 1 TRIGGERS %s`, device, smoke, device, temp, device, mode, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s < 1 ACTIVE FROM 9:30 TO 24:00 OR ON SUN, MON FROM 0:00 TO 6:00 ZONE "America/New_York" TRIGGERS %s`, device, temp, sendEmail),
+		fmt.Sprintf(`RULE r: %s%s < 1 TRIGGERS WEBHOOK DELETE "http://home.com" BODY "{}" TIMEOUT 500ms PUBLISH "{{.Value}}" TO "a.b"`, device, temp),
+	}
+
+	for i, src := range srcs {
+		rules, err := Parse(src)
+		assert.Nil(t, err, fmt.Sprintf("failed to parse %d: %v", i, err))
+
+		formatted := Format(rules...)
+		reparsed, err := Parse(formatted)
+		assert.Nil(t, err, fmt.Sprintf("failed to parse formatted %d: %v\n%s", i, err, formatted))
+		assert.Equal(t, rules, reparsed, fmt.Sprintf("failed at %d\n%s", i, formatted))
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration represents time span which is (un)marshalled as a string in the
+// format accepted by time.ParseDuration, e.g. "1m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return ErrMalformedEntity
+	}
+
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
+
+// ParseDuration parses non-negative duration from its string representation.
+func ParseDuration(s string) (Duration, error) {
+	v, err := time.ParseDuration(s)
+	if err != nil || v < 0 {
+		return 0, ErrMalformedEntity
+	}
+
+	return Duration(v), nil
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// Outcomes of the executed actions.
+const (
+	Succeeded string = "succeeded"
+	Failed    string = "failed"
+)
+
+// ActionResult represents the outcome of the single action of the execution.
+type ActionResult struct {
+	Action Action `json:"action"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Execution records the firing of the rule, along with the event which
+// triggered it and the outcomes of its actions.
+type Execution struct {
+	ID       string         `json:"id"`
+	UserId   string         `json:"userId"`
+	RuleId   string         `json:"ruleId"`
+	Event    writer.Message `json:"event"`
+	Resolved bool           `json:"resolved"`
+	Time     time.Time      `json:"time"`
+	Actions  []ActionResult `json:"actions"`
+}
+
+// ExecutionFilter limits the retrieved executions to the time range and the
+// page. Zero bound of the time range leaves it open.
+type ExecutionFilter struct {
+	From   time.Time
+	To     time.Time
+	Offset uint64
+	Limit  uint64
+}
+
+// ExecutionPage contains the page of executions, newest first, along with
+// the total number of executions within the time range.
+type ExecutionPage struct {
+	Total      uint64
+	Offset     uint64
+	Limit      uint64
+	Executions []Execution
+}
+
+// ExecutionRepository specifies API for keeping the history of the rule
+// executions.
+type ExecutionRepository interface {
+	// Save persists the execution, returning its generated unique identifier.
+	// A non-nil error is returned to indicate operation failure.
+	Save(Execution) (string, error)
+
+	// RetrieveAll retrieves the page of executions of the specific user's rule,
+	// which happened within the time range [From, To) of the filter.
+	// A non-nil error is returned to indicate operation failure.
+	RetrieveAll(string, string, ExecutionFilter) (ExecutionPage, error)
+}
+
+// recorder collects outcomes of the actions of the single rule firing, and
+// saves the execution once all of them are known. Executions which cannot
+// be saved are dropped, since the history is not essential to the service.
+type recorder struct {
+	mu      sync.Mutex
+	repo    ExecutionRepository
+	exec    Execution
+	pending int
+}
+
+func newRecorder(repo ExecutionRepository, rule Rule, trace Trace, now time.Time) *recorder {
+	if repo == nil || len(trace.Actions) == 0 {
+		return nil
+	}
+
+	exec := Execution{
+		UserId:   rule.UserId,
+		RuleId:   rule.ID,
+		Event:    trace.Event,
+		Resolved: trace.Resolved,
+		Time:     now,
+		Actions:  make([]ActionResult, len(trace.Actions)),
+	}
+
+	for i, action := range trace.Actions {
+		exec.Actions[i].Action = action
+	}
+
+	return &recorder{
+		repo:    repo,
+		exec:    exec,
+		pending: len(trace.Actions),
+	}
+}
+
+// settle records the outcome of the i-th action. Calls on nil recorder are
+// ignored, so that actions are performed the same way whether they are
+// recorded or not.
+func (r *recorder) settle(i int, err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	res := &r.exec.Actions[i]
+	res.Status = Succeeded
+	if err != nil {
+		res.Status = Failed
+		res.Error = err.Error()
+	}
+	r.pending--
+	done := r.pending == 0
+	r.mu.Unlock()
+
+	if done {
+		r.repo.Save(r.exec)
+	}
+}
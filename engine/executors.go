@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// ActionExecutor specifies API for performing specific type of actions.
+type ActionExecutor interface {
+	// Execute performs the action of the rule satisfied by the event. A non-nil
+	// error is returned to indicate action failure.
+	Execute(ctx context.Context, action Action, event writer.Message, rule Rule) error
+}
+
+// Executors represents registry of action executors mapped by action type.
+type Executors map[string]ActionExecutor
+
+// Execute performs the action using executor registered for its type.
+func (e Executors) Execute(ctx context.Context, action Action, event writer.Message, rule Rule) error {
+	executor, ok := e[action.Type()]
+	if !ok {
+		return ErrUnsupportedAction
+	}
+
+	return executor.Execute(ctx, action, event, rule)
+}
+
+var _ ActionExecutor = (*sendEmailExecutor)(nil)
+
+type sendEmailExecutor struct {
+	mailer Mailer
+}
+
+// NewSendEmailExecutor instantiates executor which delivers emails using
+// the specified mailer.
+func NewSendEmailExecutor(mailer Mailer) ActionExecutor {
+	return &sendEmailExecutor{mailer}
+}
+
+func (se *sendEmailExecutor) Execute(ctx context.Context, action Action, _ writer.Message, _ Rule) error {
+	a, ok := action.(SendEmailAction)
+	if !ok {
+		return ErrUnsupportedAction
+	}
+
+	return se.mailer.Send(ctx, a.Recipient, a.Content)
+}
+
+var _ ActionExecutor = (*turnOffExecutor)(nil)
+
+type turnOffExecutor struct {
+	controller DeviceController
+}
+
+// NewTurnOffExecutor instantiates executor which turns off devices using
+// the specified controller.
+func NewTurnOffExecutor(controller DeviceController) ActionExecutor {
+	return &turnOffExecutor{controller}
+}
+
+func (to *turnOffExecutor) Execute(ctx context.Context, action Action, _ writer.Message, _ Rule) error {
+	a, ok := action.(TurnOffAction)
+	if !ok {
+		return ErrUnsupportedAction
+	}
+
+	return to.controller.TurnOff(ctx, a.DeviceId)
+}
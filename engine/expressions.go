@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// LogicalOperator represents operator binding the operands of the expression.
+type LogicalOperator string
+
+const (
+	// And is satisfied if all of its operands are satisfied.
+	And LogicalOperator = "AND"
+
+	// Or is satisfied if any of its operands is satisfied.
+	Or LogicalOperator = "OR"
+
+	// Not is satisfied if its single operand is not satisfied.
+	Not LogicalOperator = "NOT"
+)
+
+// Expression represents node of the boolean condition tree. The node is either
+// a leaf holding single condition, or a group binding its operands using the
+// logical operator.
+//
+// In JSON, leaf is represented by the condition itself, while groups are
+// represented as {"and": [...]}, {"or": [...]} and {"not": {...}}.
+type Expression struct {
+	Operator  LogicalOperator
+	Operands  []Expression
+	Condition *Condition
+}
+
+// Leaf creates expression holding single condition.
+func Leaf(cnd Condition) Expression {
+	return Expression{Condition: &cnd}
+}
+
+// Conditions retrieves all conditions contained in the expression tree.
+func (e Expression) Conditions() []Condition {
+	if e.Condition != nil {
+		return []Condition{*e.Condition}
+	}
+
+	var cnds []Condition
+	for _, o := range e.Operands {
+		cnds = append(cnds, o.Conditions()...)
+	}
+
+	return cnds
+}
+
+// evaluate evaluates expression tree using the specified predicate for
+// evaluating conditions in its leaves.
+func (e Expression) evaluate(satisfied func(Condition) bool) bool {
+	if e.Condition != nil {
+		return satisfied(*e.Condition)
+	}
+
+	switch e.Operator {
+	case And:
+		for _, o := range e.Operands {
+			if !o.evaluate(satisfied) {
+				return false
+			}
+		}
+		return len(e.Operands) > 0
+	case Or:
+		for _, o := range e.Operands {
+			if o.evaluate(satisfied) {
+				return true
+			}
+		}
+	case Not:
+		if len(e.Operands) == 1 {
+			return !e.Operands[0].evaluate(satisfied)
+		}
+	}
+
+	return false
+}
+
+func (e Expression) MarshalJSON() ([]byte, error) {
+	if e.Condition != nil {
+		return json.Marshal(e.Condition)
+	}
+
+	key := strings.ToLower(string(e.Operator))
+	if e.Operator == Not && len(e.Operands) == 1 {
+		return json.Marshal(map[string]Expression{key: e.Operands[0]})
+	}
+
+	return json.Marshal(map[string][]Expression{key: e.Operands})
+}
+
+func (e *Expression) UnmarshalJSON(b []byte) error {
+	op, operands, err := UnmarshalGroup(b)
+	if err != nil {
+		return err
+	}
+
+	if op == "" {
+		var cnd Condition
+		if err := json.Unmarshal(b, &cnd); err != nil {
+			return err
+		}
+		e.Condition = &cnd
+		return nil
+	}
+
+	e.Operator = op
+	e.Operands = make([]Expression, len(operands))
+	for i, raw := range operands {
+		if err := json.Unmarshal(raw, &e.Operands[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalGroup decodes JSON representation of the expression group into
+// its logical operator and JSON representations of its operands, so that
+// the operands can be decoded by the caller. Operator is empty if JSON does
+// not represent a group, but a leaf.
+func UnmarshalGroup(b []byte) (LogicalOperator, []json.RawMessage, error) {
+	var group map[string]json.RawMessage
+	if err := json.Unmarshal(b, &group); err != nil {
+		return "", nil, err
+	}
+
+	for _, op := range []LogicalOperator{And, Or, Not} {
+		raw, ok := group[strings.ToLower(string(op))]
+		if !ok {
+			continue
+		}
+
+		if len(group) != 1 {
+			return "", nil, ErrMalformedEntity
+		}
+
+		if op == Not {
+			return op, []json.RawMessage{raw}, nil
+		}
+
+		var operands []json.RawMessage
+		if err := json.Unmarshal(raw, &operands); err != nil {
+			return "", nil, err
+		}
+
+		return op, operands, nil
+	}
+
+	return "", nil, nil
+}
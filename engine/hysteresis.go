@@ -0,0 +1,73 @@
+package engine
+
+import "sync"
+
+// ValidReset checks whether the reset threshold of the hysteresis condition
+// is looser than its value, so that every reading which satisfies the
+// operator against the value satisfies it against the threshold as well.
+// Hysteresis applies only to the ordering operators.
+func ValidReset(op Operator, value, reset float64) bool {
+	switch op {
+	case Lt, Lte, Gt, Gte:
+		return reset != value && op.Compare(reset, value)
+	}
+
+	return false
+}
+
+// activeStore keeps the hysteresis conditions which are currently active,
+// i.e. have been satisfied by the reading and have not been reset since.
+type activeStore struct {
+	mu     sync.Mutex
+	active map[ruleRef]map[string]bool
+}
+
+func newActiveStore() *activeStore {
+	return &activeStore{
+		active: make(map[ruleRef]map[string]bool),
+	}
+}
+
+// update checks the hysteresis condition against the value. Inactive
+// condition is activated once the value satisfies the operator against the
+// condition value, and stays active until the value no longer satisfies
+// the operator against the reset threshold.
+func (as *activeStore) update(rule Rule, cnd Condition, v float64) bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	key := conditionKey(cnd)
+
+	active := as.active[ref][key]
+	if active {
+		active = cnd.Operator.Compare(*cnd.Reset, v)
+	} else {
+		active = cnd.Operator.Compare(cnd.Value, v)
+	}
+
+	if !active {
+		delete(as.active[ref], key)
+		if len(as.active[ref]) == 0 {
+			delete(as.active, ref)
+		}
+		return false
+	}
+
+	conditions, ok := as.active[ref]
+	if !ok {
+		conditions = make(map[string]bool)
+		as.active[ref] = conditions
+	}
+	conditions[key] = true
+
+	return true
+}
+
+// remove drops active state of all conditions of the rule.
+func (as *activeStore) remove(userId, ruleId string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	delete(as.active, ruleRef{userId, ruleId})
+}
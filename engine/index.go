@@ -0,0 +1,191 @@
+package engine
+
+import "sync"
+
+// ruleIndex maps device properties to the enabled rules referring to them, so
+// that only relevant rules are evaluated for an event. Rules of the user are
+// loaded from the repository when the user's events are applied for the
+// first time, and are kept in sync as the rules are saved and removed.
+type ruleIndex struct {
+	mu    sync.RWMutex
+	users map[string]*userIndex
+}
+
+type userIndex struct {
+	rules map[string]Rule
+	refs  map[readingKey][]string
+}
+
+func newRuleIndex() *ruleIndex {
+	return &ruleIndex{
+		users: make(map[string]*userIndex),
+	}
+}
+
+// candidates retrieves the user's rules referring to the device property,
+// in order in which they were indexed. Rules are loaded if the user is not
+// indexed yet.
+func (ri *ruleIndex) candidates(userId, deviceId, property string, load func() []Rule) []Rule {
+	ri.mu.RLock()
+	user, ok := ri.users[userId]
+	if ok {
+		defer ri.mu.RUnlock()
+		return user.candidates(readingKey{deviceId, property})
+	}
+	ri.mu.RUnlock()
+
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	return ri.user(userId, load).candidates(readingKey{deviceId, property})
+}
+
+// load indexes rules of the user, unless the user is already indexed.
+func (ri *ruleIndex) load(userId string, load func() []Rule) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ri.user(userId, load)
+}
+
+// user retrieves index of the user, loading the user's rules if the user
+// is not indexed yet. It is called with the lock held.
+func (ri *ruleIndex) user(userId string, load func() []Rule) *userIndex {
+	user, ok := ri.users[userId]
+	if !ok {
+		user = newUserIndex(load())
+		ri.users[userId] = user
+	}
+
+	return user
+}
+
+// watched retrieves indexed rules which detect missing data.
+func (ri *ruleIndex) watched() []Rule {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	var rules []Rule
+	for _, user := range ri.users {
+		for _, rule := range user.rules {
+			if rule.DetectsMissing() {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules
+}
+
+// rule retrieves the indexed rule of the user.
+func (ri *ruleIndex) rule(userId, ruleId string) (Rule, bool) {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	user, ok := ri.users[userId]
+	if !ok {
+		return Rule{}, false
+	}
+
+	rule, ok := user.rules[ruleId]
+	return rule, ok
+}
+
+// save indexes the rule, replacing its previous version. Rules of users who
+// are not indexed yet are skipped, since they are loaded on first use.
+func (ri *ruleIndex) save(rule Rule) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if user, ok := ri.users[rule.UserId]; ok {
+		user.remove(rule.ID)
+		user.add(rule)
+	}
+}
+
+// remove drops the rule from the index.
+func (ri *ruleIndex) remove(userId, ruleId string) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if user, ok := ri.users[userId]; ok {
+		user.remove(ruleId)
+	}
+}
+
+// invalidate drops rules of the user, so that they are loaded again.
+func (ri *ruleIndex) invalidate(userId string) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	delete(ri.users, userId)
+}
+
+func newUserIndex(rules []Rule) *userIndex {
+	ui := &userIndex{
+		rules: make(map[string]Rule),
+		refs:  make(map[readingKey][]string),
+	}
+
+	for _, rule := range rules {
+		ui.add(rule)
+	}
+
+	return ui
+}
+
+func (ui *userIndex) candidates(key readingKey) []Rule {
+	ids := ui.refs[key]
+	rules := make([]Rule, 0, len(ids))
+	for _, id := range ids {
+		rules = append(rules, ui.rules[id])
+	}
+
+	return rules
+}
+
+// add indexes the rule, unless it is disabled.
+func (ui *userIndex) add(rule Rule) {
+	if rule.Disabled {
+		return
+	}
+
+	ui.rules[rule.ID] = rule
+	for key := range references(rule) {
+		ui.refs[key] = append(ui.refs[key], rule.ID)
+	}
+}
+
+func (ui *userIndex) remove(ruleId string) {
+	rule, ok := ui.rules[ruleId]
+	if !ok {
+		return
+	}
+
+	delete(ui.rules, ruleId)
+	for key := range references(rule) {
+		ids := ui.refs[key]
+		for i, id := range ids {
+			if id == ruleId {
+				ids = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(ui.refs, key)
+			continue
+		}
+		ui.refs[key] = ids
+	}
+}
+
+// references collects device properties the rule conditions refer to.
+func references(rule Rule) map[readingKey]struct{} {
+	keys := make(map[readingKey]struct{})
+	for _, cnd := range rule.AllConditions() {
+		keys[readingKey{cnd.DeviceID, cnd.Property}] = struct{}{}
+	}
+
+	return keys
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleIndex(t *testing.T) {
+	temp := Condition{DeviceID: "thermometer", Property: "temp", Operator: Lt, Type: Numeric, Value: float64(30)}
+	smoke := Condition{DeviceID: "detector", Property: "smoke", Operator: Eq, Type: Bool, Value: true}
+
+	r1 := Rule{ID: "1", UserId: "1", Conditions: []Condition{temp, temp}}
+	r2 := Rule{ID: "2", UserId: "1", Expression: &Expression{Operator: Or, Operands: []Expression{Leaf(temp), Leaf(smoke)}}}
+	r3 := Rule{ID: "3", UserId: "2", Conditions: []Condition{smoke}}
+
+	loads := 0
+	load := func(userId string) []Rule {
+		loads++
+		if userId == "1" {
+			return []Rule{r1, r2}
+		}
+		return []Rule{r3}
+	}
+
+	idx := newRuleIndex()
+	idx.save(r3)
+
+	cases := []struct {
+		desc     string
+		update   func()
+		userId   string
+		deviceId string
+		property string
+		rules    []Rule
+	}{
+		{"load rules", func() {}, "1", "thermometer", "temp", []Rule{r1, r2}},
+		{"rules referring to expression", func() {}, "1", "detector", "smoke", []Rule{r2}},
+		{"unreferred property", func() {}, "1", "thermometer", "humidity", []Rule{}},
+		{"update rule", func() { idx.save(Rule{ID: "1", UserId: "1", Conditions: []Condition{smoke}}) }, "1", "thermometer", "temp", []Rule{r2}},
+		{"remove rule", func() { idx.remove("1", "2") }, "1", "detector", "smoke", []Rule{{ID: "1", UserId: "1", Conditions: []Condition{smoke}}}},
+		{"rule saved before loading", func() {}, "2", "detector", "smoke", []Rule{r3}},
+	}
+
+	for _, tc := range cases {
+		tc.update()
+		rules := idx.candidates(tc.userId, tc.deviceId, tc.property, func() []Rule { return load(tc.userId) })
+		assert.Equal(t, tc.rules, rules, fmt.Sprintf("%s: unexpected rules", tc.desc))
+	}
+
+	assert.Equal(t, 2, loads, "expected rules to be loaded once per user")
+}
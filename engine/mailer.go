@@ -0,0 +1,11 @@
+package engine
+
+import "context"
+
+// Mailer specifies API for delivering emails triggered by rules.
+type Mailer interface {
+	// Send delivers email with the specified content to the recipient, unless
+	// the context is done first. A non-nil error is returned to indicate
+	// delivery failure.
+	Send(ctx context.Context, recipient, content string) error
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// SeenNotifier shares moments at which the device properties were reported
+// with the other instances of the service, since readings are distributed
+// among the instances.
+type SeenNotifier interface {
+	// Seen announces that the device property of the user is reported at
+	// the moment. A non-nil error is returned to indicate delivery failure.
+	Seen(userId, deviceId, property string, at time.Time) error
+}
+
+// Lease grants the right to check rules for missing data to a single
+// instance of the service at a time, so that their actions are not
+// performed by every instance.
+type Lease interface {
+	// Acquire acquires the lease, or renews it if it is already held by
+	// the instance, reporting whether the instance holds the lease.
+	Acquire() (bool, error)
+}
+
+// Watch checks rules for missing data and for conditions held for their
+// duration at the interval, until the context is done. Failures of the
+// checks are passed to the failed function, if it is specified.
+func Watch(ctx context.Context, svc Service, interval time.Duration, failed func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.CheckMissing(ctx); err != nil && failed != nil {
+				failed(err)
+			}
+			if err := svc.CheckPending(ctx); err != nil && failed != nil {
+				failed(err)
+			}
+		}
+	}
+}
+
+// DetectsMissing checks whether any of the rule conditions is satisfied by
+// missing data.
+func (rule Rule) DetectsMissing() bool {
+	for _, cnd := range rule.AllConditions() {
+		if cnd.Missing > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watches checks whether any of the rules detects missing data of the
+// reported property.
+func watches(rules []Rule, event writer.Message) bool {
+	for _, rule := range rules {
+		for _, cnd := range rule.AllConditions() {
+			if cnd.Missing > 0 && cnd.DeviceID == event.Publisher && cnd.Property == event.Name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// missingEvent creates the event on behalf of which the rule is evaluated
+// when checked for missing data. Event refers to the first missing property
+// of the rule, and is timestamped with the moment of the check.
+func missingEvent(rule Rule, now time.Time) writer.Message {
+	event := writer.Message{Time: float64(now.UnixNano()) / float64(time.Second)}
+	for _, cnd := range rule.AllConditions() {
+		if cnd.Missing > 0 {
+			event.Publisher = cnd.DeviceID
+			event.Name = cnd.Property
+			break
+		}
+	}
+
+	return event
+}
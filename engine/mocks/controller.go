@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+var _ engine.DeviceController = (*DeviceControllerMock)(nil)
+
+// DeviceControllerMock represents in-memory device controller which records
+// devices that were turned off.
+type DeviceControllerMock struct {
+	mu    sync.Mutex
+	off   map[string]int
+	Error error
+}
+
+// NewDeviceController instantiates in-memory device controller.
+func NewDeviceController() *DeviceControllerMock {
+	return &DeviceControllerMock{
+		off: make(map[string]int),
+	}
+}
+
+func (dc *DeviceControllerMock) TurnOff(_ context.Context, deviceId string) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.Error != nil {
+		return dc.Error
+	}
+
+	dc.off[deviceId]++
+
+	return nil
+}
+
+// TurnedOff retrieves number of turn off commands sent to the device.
+func (dc *DeviceControllerMock) TurnedOff(deviceId string) int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	return dc.off[deviceId]
+}
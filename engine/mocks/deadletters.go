@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+)
+
+var _ engine.DeadLetterRepository = (*deadLetterRepositoryMock)(nil)
+
+type deadLetterRepositoryMock struct {
+	mu      sync.Mutex
+	letters map[string]engine.DeadLetter
+}
+
+// NewDeadLetterRepository instantiates in-memory dead letter repository.
+func NewDeadLetterRepository() engine.DeadLetterRepository {
+	return &deadLetterRepositoryMock{
+		letters: make(map[string]engine.DeadLetter),
+	}
+}
+
+func (repo *deadLetterRepositoryMock) Save(dl engine.DeadLetter) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	dl.ID = gocql.TimeUUID().String()
+	repo.letters[key(dl.UserId, dl.ID)] = dl
+
+	return dl.ID, nil
+}
+
+func (repo *deadLetterRepositoryMock) One(userId string, id string) (*engine.DeadLetter, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if dl, ok := repo.letters[key(userId, id)]; ok {
+		return &dl, nil
+	}
+
+	return nil, engine.ErrNotFound
+}
+
+func (repo *deadLetterRepositoryMock) All(userId string) []engine.DeadLetter {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	letters := make([]engine.DeadLetter, 0)
+	for _, dl := range repo.letters {
+		if dl.UserId == userId {
+			letters = append(letters, dl)
+		}
+	}
+
+	return letters
+}
+
+func (repo *deadLetterRepositoryMock) Remove(userId string, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.letters, key(userId, id))
+	return nil
+}
@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+)
+
+var _ engine.ExecutionRepository = (*executionRepositoryMock)(nil)
+
+type executionRepositoryMock struct {
+	mu         sync.Mutex
+	executions []engine.Execution
+}
+
+// NewExecutionRepository instantiates in-memory execution repository.
+func NewExecutionRepository() engine.ExecutionRepository {
+	return &executionRepositoryMock{}
+}
+
+func (repo *executionRepositoryMock) Save(exec engine.Execution) (string, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	exec.ID = gocql.UUIDFromTime(exec.Time).String()
+	repo.executions = append(repo.executions, exec)
+
+	return exec.ID, nil
+}
+
+func (repo *executionRepositoryMock) RetrieveAll(userId, ruleId string, filter engine.ExecutionFilter) (engine.ExecutionPage, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	matched := make([]engine.Execution, 0)
+	for _, exec := range repo.executions {
+		if exec.UserId != userId || exec.RuleId != ruleId {
+			continue
+		}
+
+		if !filter.From.IsZero() && exec.Time.Before(filter.From) {
+			continue
+		}
+
+		if !filter.To.IsZero() && !exec.Time.Before(filter.To) {
+			continue
+		}
+
+		matched = append(matched, exec)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Time.After(matched[j].Time)
+	})
+
+	page := engine.ExecutionPage{
+		Total:      uint64(len(matched)),
+		Offset:     filter.Offset,
+		Limit:      filter.Limit,
+		Executions: []engine.Execution{},
+	}
+
+	if filter.Offset >= page.Total {
+		return page, nil
+	}
+
+	end := filter.Offset + filter.Limit
+	if filter.Limit == 0 || end > page.Total {
+		end = page.Total
+	}
+	page.Executions = append(page.Executions, matched[filter.Offset:end]...)
+
+	return page, nil
+}
@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+var _ engine.Mailer = (*MailerMock)(nil)
+
+// MailerMock represents in-memory mailer which records delivered emails.
+type MailerMock struct {
+	mu    sync.Mutex
+	sent  map[string][]string
+	Error error
+}
+
+// NewMailer instantiates in-memory mailer.
+func NewMailer() *MailerMock {
+	return &MailerMock{
+		sent: make(map[string][]string),
+	}
+}
+
+func (m *MailerMock) Send(_ context.Context, recipient, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Error != nil {
+		return m.Error
+	}
+
+	m.sent[recipient] = append(m.sent[recipient], content)
+
+	return nil
+}
+
+// Sent retrieves contents of all emails delivered to the recipient.
+func (m *MailerMock) Sent(recipient string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sent[recipient]
+}
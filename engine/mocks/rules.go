@@ -56,6 +56,25 @@ func (repo *ruleRepositoryMock) All(userId string) []engine.Rule {
 	return rulesList
 }
 
+func (repo *ruleRepositoryMock) WatchedUsers() []string {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	users := make(map[string]bool)
+	for _, r := range repo.rules {
+		if !r.Disabled && r.DetectsMissing() {
+			users[r.UserId] = true
+		}
+	}
+
+	ids := make([]string, 0, len(users))
+	for id := range users {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
 func (repo *ruleRepositoryMock) Remove(userId string, ruleId string) error {
 	delete(repo.rules, key(userId, ruleId))
 	return nil
@@ -0,0 +1,77 @@
+package nats
+
+import (
+	"encoding/json"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+	"go.uber.org/zap"
+)
+
+// changeMsg announces that rules of the user are changed by the instance of
+// the service.
+type changeMsg struct {
+	UserId   string `json:"userId"`
+	Instance string `json:"instance,omitempty"`
+}
+
+var _ engine.RuleNotifier = (*ruleNotifier)(nil)
+
+type ruleNotifier struct {
+	pub      publisher
+	subject  string
+	instance string
+}
+
+// NewRuleNotifier instantiates notifier which announces changes of the rules
+// made by the specified instance of the service, by publishing to the
+// specified subject.
+func NewRuleNotifier(nc *nats.Conn, subject, instance string) engine.RuleNotifier {
+	return &ruleNotifier{nc, subject, instance}
+}
+
+func (rn *ruleNotifier) RulesChanged(userId string) error {
+	data, err := json.Marshal(changeMsg{userId, rn.instance})
+	if err != nil {
+		return err
+	}
+
+	return rn.pub.Publish(rn.subject, data)
+}
+
+var _ Subscriber = (*changesSubscriber)(nil)
+
+type changesSubscriber struct {
+	nc       *nats.Conn
+	service  engine.Service
+	instance string
+	logger   *zap.Logger
+}
+
+// NewChangesSubscriber instantiates subscription handler which invalidates
+// rules kept in memory when they are changed by another instance of the
+// service. Changes announced by the specified instance itself are ignored,
+// since it already invalidated its rules. Every instance needs to receive
+// the changes, so the handler needs to subscribe without a queue group,
+// using an empty queue.
+func NewChangesSubscriber(nc *nats.Conn, service engine.Service, instance string, logger *zap.Logger) *changesSubscriber {
+	return &changesSubscriber{nc, service, instance, logger}
+}
+
+func (cs *changesSubscriber) Subscribe(subject string, queue string) (*nats.Subscription, error) {
+	return cs.nc.QueueSubscribe(subject, queue, cs.handle)
+}
+
+func (cs *changesSubscriber) handle(m *nats.Msg) {
+	var msg changeMsg
+	if err := json.Unmarshal(m.Data, &msg); err != nil || msg.UserId == "" {
+		cs.logger.Error("Failed to decode rules change.", zap.Error(err))
+		return
+	}
+
+	if msg.Instance == cs.instance {
+		return
+	}
+
+	cs.service.InvalidateRules(msg.UserId)
+}
@@ -0,0 +1,69 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRulesChanged(t *testing.T) {
+	publishErr := errors.New("connection closed")
+
+	cases := []struct {
+		desc   string
+		pubErr error
+		data   string
+		err    error
+	}{
+		{"announce change", nil, fmt.Sprintf(`{"userId":"%s","instance":"instance01"}`, uuid), nil},
+		{"publish failure", publishErr, "", publishErr},
+	}
+
+	for _, tc := range cases {
+		pub := &publisherMock{err: tc.pubErr}
+		rn := &ruleNotifier{pub, "rules.changes", "instance01"}
+
+		err := rn.RulesChanged(uuid)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		if tc.err == nil {
+			assert.Equal(t, "rules.changes", pub.subject, fmt.Sprintf("%s: unexpected subject", tc.desc))
+			assert.Equal(t, tc.data, string(pub.data), fmt.Sprintf("%s: unexpected data", tc.desc))
+		}
+	}
+}
+
+// invalidationsMock records users whose rules are invalidated.
+type invalidationsMock struct {
+	engine.Service
+	users []string
+}
+
+func (im *invalidationsMock) InvalidateRules(userId string) {
+	im.users = append(im.users, userId)
+}
+
+func TestHandleChange(t *testing.T) {
+	cases := []struct {
+		desc  string
+		data  string
+		users []string
+	}{
+		{"change by another instance", fmt.Sprintf(`{"userId":"%s","instance":"instance02"}`, uuid), []string{uuid}},
+		{"change by unknown instance", fmt.Sprintf(`{"userId":"%s"}`, uuid), []string{uuid}},
+		{"change by same instance", fmt.Sprintf(`{"userId":"%s","instance":"instance01"}`, uuid), nil},
+		{"malformed change", `{"userId":`, nil},
+	}
+
+	for _, tc := range cases {
+		svc := &invalidationsMock{}
+		cs := NewChangesSubscriber(nil, svc, "instance01", zap.NewNop())
+
+		cs.handle(&nats.Msg{Data: []byte(tc.data)})
+		assert.Equal(t, tc.users, svc.users, fmt.Sprintf("%s: unexpected invalidations", tc.desc))
+	}
+}
@@ -0,0 +1,102 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+)
+
+const (
+	// DefaultControlSubject represents default prefix of the subjects used
+	// for publishing device control commands.
+	DefaultControlSubject string = "control"
+
+	// DefaultControlTemplate represents default SenML payload of the control
+	// command sent to the device.
+	DefaultControlTemplate string = `[{"bn":"{{.DeviceId}}","n":"state","vs":"{{.Command}}","t":{{.Time}}}]`
+
+	commandOff string = "off"
+)
+
+// ErrInvalidDeviceId indicates that the device identifier can not be used as
+// the last token of the control subject.
+var ErrInvalidDeviceId = errors.New("invalid device identifier")
+
+var _ engine.DeviceController = (*deviceController)(nil)
+
+type publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+type deviceController struct {
+	pub     publisher
+	subject string
+	payload *template.Template
+}
+
+// command represents data available to the control payload template. String
+// fields are JSON-escaped, so that they can be embedded into JSON strings.
+type command struct {
+	DeviceId string
+	Command  string
+	Time     float64
+}
+
+// NewDeviceController instantiates controller which publishes control commands
+// on per-device subjects formed as <subject>.<deviceId>. Payload of the command
+// is rendered using specified template. Devices whose identifiers contain
+// NATS subject separators, wildcards or whitespace are not controlled.
+func NewDeviceController(nc *nats.Conn, subject, payload string) (engine.DeviceController, error) {
+	return newDeviceController(nc, subject, payload)
+}
+
+func newDeviceController(pub publisher, subject, payload string) (*deviceController, error) {
+	tmpl, err := template.New("control").Parse(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deviceController{pub, subject, tmpl}, nil
+}
+
+func (dc *deviceController) TurnOff(ctx context.Context, deviceId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return dc.send(deviceId, commandOff)
+}
+
+func (dc *deviceController) send(deviceId, cmd string) error {
+	if deviceId == "" || strings.ContainsAny(deviceId, ".*> \t\r\n") {
+		return engine.Permanent(ErrInvalidDeviceId)
+	}
+
+	c := command{
+		DeviceId: escape(deviceId),
+		Command:  escape(cmd),
+		Time:     float64(time.Now().UnixNano()) / float64(time.Second),
+	}
+
+	var payload bytes.Buffer
+	if err := dc.payload.Execute(&payload, c); err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s.%s", dc.subject, deviceId)
+	return dc.pub.Publish(subject, payload.Bytes())
+}
+
+// escape escapes the string as the content of the JSON string.
+func escape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
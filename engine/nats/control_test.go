@@ -0,0 +1,123 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+type publisherMock struct {
+	subject string
+	data    []byte
+	err     error
+}
+
+func (pub *publisherMock) Publish(subject string, data []byte) error {
+	if pub.err != nil {
+		return pub.err
+	}
+
+	pub.subject = subject
+	pub.data = data
+
+	return nil
+}
+
+func TestTurnOff(t *testing.T) {
+	publishErr := errors.New("connection closed")
+
+	cases := []struct {
+		subject string
+		payload string
+		pubErr  error
+		data    string
+		err     error
+	}{
+		{"control", `{"id":"{{.DeviceId}}","cmd":"{{.Command}}"}`, nil, fmt.Sprintf(`{"id":"%s","cmd":"off"}`, uuid), nil},
+		{"commands", `{{.Command}}`, nil, "off", nil},
+		{"control", `{{.Command}}`, publishErr, "", publishErr},
+	}
+
+	for i, tc := range cases {
+		pub := &publisherMock{err: tc.pubErr}
+		dc, err := newDeviceController(pub, tc.subject, tc.payload)
+		assert.Nil(t, err, fmt.Sprintf("failed at %d\n", i))
+
+		err = dc.TurnOff(context.Background(), uuid)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		if tc.err != nil {
+			continue
+		}
+
+		assert.Equal(t, fmt.Sprintf("%s.%s", tc.subject, uuid), pub.subject, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.data, string(pub.data), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestTurnOffInvalidDevice(t *testing.T) {
+	ids := []string{"", "device.1", "device*", "device>", "device 1", "device\n1"}
+
+	for _, id := range ids {
+		pub := &publisherMock{}
+		dc, err := newDeviceController(pub, DefaultControlSubject, DefaultControlTemplate)
+		assert.Nil(t, err, "failed to parse default template")
+
+		err = dc.TurnOff(context.Background(), id)
+		assert.Equal(t, engine.Permanent(ErrInvalidDeviceId), err, fmt.Sprintf("%q: expected invalid device error", id))
+		assert.Empty(t, pub.subject, fmt.Sprintf("%q: expected command not to be published", id))
+	}
+}
+
+func TestControlTemplateEscaping(t *testing.T) {
+	pub := &publisherMock{}
+	dc, err := newDeviceController(pub, DefaultControlSubject, DefaultControlTemplate)
+	assert.Nil(t, err, "failed to parse default template")
+
+	id := `device"},{"n":"x\`
+	err = dc.TurnOff(context.Background(), id)
+	assert.Nil(t, err, "failed to turn off device")
+
+	var senml []map[string]interface{}
+	err = json.Unmarshal(pub.data, &senml)
+	assert.Nil(t, err, "payload is not valid JSON")
+	assert.Equal(t, 1, len(senml), "unexpected number of records")
+	assert.Equal(t, id, senml[0]["bn"], "unexpected base name")
+}
+
+func TestDefaultControlTemplate(t *testing.T) {
+	pub := &publisherMock{}
+	dc, err := newDeviceController(pub, DefaultControlSubject, DefaultControlTemplate)
+	assert.Nil(t, err, "failed to parse default template")
+
+	err = dc.TurnOff(context.Background(), uuid)
+	assert.Nil(t, err, "failed to turn off device")
+
+	var senml []map[string]interface{}
+	err = json.Unmarshal(pub.data, &senml)
+	assert.Nil(t, err, "default payload is not valid SenML")
+	assert.Equal(t, uuid, senml[0]["bn"], "unexpected base name")
+	assert.Equal(t, "off", senml[0]["vs"], "unexpected command")
+}
+
+func TestInvalidControlTemplate(t *testing.T) {
+	_, err := newDeviceController(&publisherMock{}, DefaultControlSubject, "{{.DeviceId")
+	assert.NotNil(t, err, "expected template parsing error")
+}
+
+func TestTurnOffCanceled(t *testing.T) {
+	pub := &publisherMock{}
+	dc, err := newDeviceController(pub, DefaultControlSubject, DefaultControlTemplate)
+	assert.Nil(t, err, "failed to parse default template")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = dc.TurnOff(ctx, uuid)
+	assert.Equal(t, context.Canceled, err, "expected context error")
+	assert.Empty(t, pub.subject, "expected command not to be published")
+}
@@ -0,0 +1,38 @@
+package nats
+
+import (
+	"encoding/json"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+)
+
+var _ engine.DeadLetterRepository = (*deadLetterQueue)(nil)
+
+type deadLetterQueue struct {
+	engine.DeadLetterRepository
+	pub     publisher
+	subject string
+}
+
+// NewDeadLetterQueue instantiates dead letter repository which publishes the
+// saved dead letters to the specified subject, in addition to persisting them
+// using the underlying repository.
+func NewDeadLetterQueue(repo engine.DeadLetterRepository, nc *nats.Conn, subject string) engine.DeadLetterRepository {
+	return &deadLetterQueue{repo, nc, subject}
+}
+
+func (dlq *deadLetterQueue) Save(dl engine.DeadLetter) (string, error) {
+	id, err := dlq.DeadLetterRepository.Save(dl)
+	if err != nil {
+		return "", err
+	}
+	dl.ID = id
+
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return id, err
+	}
+
+	return id, dlq.pub.Publish(dlq.subject, data)
+}
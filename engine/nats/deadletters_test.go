@@ -0,0 +1,52 @@
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	publishErr := errors.New("connection closed")
+	dl := engine.DeadLetter{
+		UserId:   uuid,
+		RuleId:   uuid,
+		Action:   engine.PublishAction{Name: engine.Publish, Subject: "alarms"},
+		Event:    writer.Message{Publisher: uuid, Name: "temperature", Value: 35},
+		Attempts: 3,
+		Error:    "connection closed",
+	}
+
+	cases := []struct {
+		desc   string
+		pubErr error
+		err    error
+	}{
+		{"publish dead letter", nil, nil},
+		{"publish failure", publishErr, publishErr},
+	}
+
+	for _, tc := range cases {
+		repo := mocks.NewDeadLetterRepository()
+		pub := &publisherMock{err: tc.pubErr}
+		dlq := &deadLetterQueue{repo, pub, "rule.deadletters"}
+
+		id, err := dlq.Save(dl)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, 1, len(repo.All(uuid)), fmt.Sprintf("%s: expected dead letter to be persisted", tc.desc))
+
+		if tc.err == nil {
+			var msg map[string]interface{}
+			json.Unmarshal(pub.data, &msg)
+			assert.Equal(t, "rule.deadletters", pub.subject, fmt.Sprintf("%s: unexpected subject", tc.desc))
+			assert.Equal(t, id, msg["id"], fmt.Sprintf("%s: unexpected dead letter", tc.desc))
+			assert.Equal(t, "alarms", msg["action"].(map[string]interface{})["subject"], fmt.Sprintf("%s: unexpected action", tc.desc))
+		}
+	}
+}
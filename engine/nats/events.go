@@ -1,6 +1,7 @@
 package nats
 
 import (
+	"context"
 	"encoding/json"
 	"go.uber.org/zap"
 
@@ -43,6 +44,8 @@ func (es *eventsSubscriber) Subscribe(subject string, queue string) (*nats.Subsc
 
 		sugar := es.logger.Sugar()
 		sugar.Infof("Applying rules on %d events.", len(events))
-		es.service.ApplyRules(raw.Publisher, events)
+		if err = es.service.ApplyRules(context.Background(), raw.Publisher, events); err != nil {
+			es.logger.Error("Failed to execute rule actions.", zap.Error(err))
+		}
 	})
 }
@@ -8,8 +8,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/MainfluxLabs/rules-engine/engine"
 	"github.com/gocql/gocql"
+	"github.com/nats-io/go-nats"
 )
 
+var uuid = gocql.TimeUUID().String()
+
 const (
 	validRule = `
 		{
@@ -142,6 +145,74 @@ const (
 				}
 			]
 		}`
+	ruleWithExpression = `
+		{
+		  "rules": [
+			{
+			  "expression": {
+				"or": [
+				  {
+					"operator": ">",
+					"property": "temperature",
+					"deviceId": "a32db207-7236-4e75-abad-7c972f4cfd18",
+					"value": 30
+				  },
+				  {
+					"and": [
+					  {
+						"operator": "=",
+						"property": "smoke",
+						"deviceId": "a32db207-7236-4e75-abad-7c972f4cfd18",
+						"value": true
+					  },
+					  {
+						"not": {
+						  "operator": "=",
+						  "property": "mode",
+						  "deviceId": "a32db207-7236-4e75-abad-7c972f4cfd18",
+						  "value": "test"
+						}
+					  }
+					]
+				  }
+				]
+			  },
+			  "userId": "a2dfc0dc-1f14-4935-a78b-92e77c0af7a1",
+			  "name": "rule01",
+			  "actions": [
+				{
+				  "name": "TURN OFF",
+				  "deviceId": "937a7c3e-db39-4d75-b52b-b8442463761a"
+				}
+			  ]
+			}
+		  ]
+		}`
+	multipleNegation = `
+		{
+		  "rules": [
+			{
+			  "expression": {
+				"not": [
+				  {
+					"operator": ">",
+					"property": "temperature",
+					"deviceId": "a32db207-7236-4e75-abad-7c972f4cfd18",
+					"value": 30
+				  }
+				]
+			  },
+			  "userId": "a2dfc0dc-1f14-4935-a78b-92e77c0af7a1",
+			  "name": "rule01",
+			  "actions": [
+				{
+				  "name": "TURN OFF",
+				  "deviceId": "937a7c3e-db39-4d75-b52b-b8442463761a"
+				}
+			  ]
+			}
+		  ]
+		}`
 	missingDeviceId = `
 		{
 		  "rules": [
@@ -166,13 +237,6 @@ const (
 		}`
 )
 
-var (
-	uuid             = gocql.TimeUUID().String()
-	validAction      = action{name: sendEmail, content: "test", recipient: "test"}
-	validCondition   = condition{uuid, "active", engine.Eq, true}
-	invalidAction    = action{name: sendEmail, content: "", recipient: "test"}
-	invalidCondition = condition{uuid, "active", engine.Gt, true}
-)
 
 func TestParsingRules(t *testing.T) {
 	cases := []struct {
@@ -187,6 +251,7 @@ func TestParsingRules(t *testing.T) {
 		{ruleWithoutName, []string{""}, []int{1}, []int{1}, nil},
 		{invalidBtwVal, []string{}, []int{}, []int{}, engine.ErrMalformedEntity},
 		{missingDeviceId, []string{}, []int{}, []int{}, engine.ErrMalformedEntity},
+		{ruleWithExpression, []string{"rule01"}, []int{0}, []int{1}, nil},
 	}
 
 	for i, tc := range cases {
@@ -204,72 +269,58 @@ func TestParsingRules(t *testing.T) {
 	}
 }
 
-func TestValidateRule(t *testing.T) {
-	cases := []struct {
-		r   rule
-		err error
-	}{
-		{rule{uuid, "", []condition{validCondition}, []action{validAction}}, nil},
-		{rule{uuid, "test", []condition{validCondition}, []action{validAction}}, nil},
-		{rule{"test", "", []condition{validCondition}, []action{validAction}}, engine.ErrMalformedEntity},
-		{rule{uuid, "", []condition{}, []action{validAction}}, engine.ErrMalformedEntity},
-		{rule{uuid, "", []condition{validCondition}, []action{}}, engine.ErrMalformedEntity},
-		{rule{uuid, "", []condition{validCondition, invalidCondition}, []action{validAction}}, engine.ErrMalformedEntity},
-		{rule{uuid, "", []condition{validCondition}, []action{validAction, invalidAction}}, engine.ErrMalformedEntity},
-	}
 
-	for i, tc := range cases {
-		err := tc.r.validate()
-		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
-	}
+func TestParsingExpression(t *testing.T) {
+	var raw *rulesMsg
+	err := json.Unmarshal([]byte(ruleWithExpression), &raw)
+	assert.Nil(t, err, "failed to unmarshal expression")
 
-}
+	rls, err := raw.toDomain()
+	assert.Nil(t, err, "failed to convert expression")
 
-func TestValidateCondition(t *testing.T) {
-	cases := []struct {
-		cnd condition
-		err error
-	}{
-		{condition{uuid, "active", engine.Eq, true}, nil},
-		{condition{uuid, "active", engine.Gt, true}, engine.ErrMalformedEntity},
-		{condition{uuid, "name", engine.Eq, "test"}, nil},
-		{condition{uuid, "active", engine.Btw, "test"}, engine.ErrMalformedEntity},
-		{condition{uuid, "temp", engine.Neq, float64(5)}, nil},
-		{condition{uuid, "active", engine.Btw, float64(5)}, engine.ErrMalformedEntity},
-		{condition{uuid, "active", engine.Btw, map[string]interface{}{from: float64(5), to: float64(10)}}, nil},
-		{condition{uuid, "active", engine.Btw, map[string]interface{}{from: "5", to: "10"}}, engine.ErrMalformedEntity},
-		{condition{uuid, "active", engine.Btw, true}, engine.ErrMalformedEntity},
-		{condition{uuid, "active", engine.Btw, map[string]interface{}{from: float64(10), to: float64(5)}}, engine.ErrMalformedEntity},
-		{condition{uuid, "active", engine.Btw, map[string]interface{}{from: float64(10), to: float64(10)}}, engine.ErrMalformedEntity},
-		{condition{"invalid", "active", engine.Eq, true}, engine.ErrMalformedEntity},
-		{condition{uuid, "", engine.Eq, true}, engine.ErrMalformedEntity},
-		{condition{"", "test", engine.Eq, true}, engine.ErrMalformedEntity},
-	}
+	expr := rls[0].Expression
+	assert.Equal(t, engine.Or, expr.Operator, "unexpected root operator")
+	assert.Equal(t, 2, len(expr.Operands), "unexpected number of operands")
+	assert.Equal(t, engine.Numeric, expr.Operands[0].Condition.Type, "unexpected condition type")
+	assert.Equal(t, engine.And, expr.Operands[1].Operator, "unexpected nested operator")
+	assert.Equal(t, engine.Not, expr.Operands[1].Operands[1].Operator, "unexpected negation")
+	assert.Equal(t, 3, len(expr.Conditions()), "unexpected number of conditions")
 
-	for i, tc := range cases {
-		err := tc.cnd.validate()
-		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
-	}
+	err = json.Unmarshal([]byte(multipleNegation), &raw)
+	assert.NotNil(t, err, "expected error for negation of multiple operands")
 }
 
-func TestValidateAction(t *testing.T) {
+func TestDecodeRules(t *testing.T) {
+	user := "a2dfc0dc-1f14-4935-a78b-92e77c0af7a1"
+	rule := `RULE rule01:
+		a32db207-7236-4e75-abad-7c972f4cfd18["temperature"] > 30
+	TRIGGERS
+		TURN OFF 937a7c3e-db39-4d75-b52b-b8442463761a`
+
 	cases := []struct {
-		action action
-		err    error
+		desc    string
+		subject string
+		msg     nats.Msg
+		rules   int
+		err     bool
 	}{
-		{action{name: sendEmail, content: "test", recipient: "test"}, nil},
-		{action{name: sendEmail, content: "", recipient: "test"}, engine.ErrMalformedEntity},
-		{action{name: sendEmail, content: 5, recipient: "test"}, engine.ErrMalformedEntity},
-		{action{name: sendEmail, content: "test", recipient: ""}, engine.ErrMalformedEntity},
-		{action{name: "invalidName", content: "test", recipient: "test"}, engine.ErrMalformedEntity},
-		{action{"invalidProperty": "test", content: "test", recipient: "test"}, engine.ErrMalformedEntity},
-		{action{name: turnOff, deviceId: uuid}, nil},
-		{action{name: turnOff, deviceId: "test"}, engine.ErrMalformedEntity},
-		{action{name: turnOff, content: "test", recipient: "test"}, engine.ErrMalformedEntity},
+		{"JSON rules", "rules", nats.Msg{Subject: "rules", Data: []byte(twoRules)}, 2, false},
+		{"DSL rules", "rules.*", nats.Msg{Subject: "rules." + user, Data: []byte(rule + "\n" + rule)}, 2, false},
+		{"DSL rule with invalid owner", "rules.*", nats.Msg{Subject: "rules.user", Data: []byte(rule)}, 0, true},
+		{"DSL rule with syntax error", "rules.*", nats.Msg{Subject: "rules." + user, Data: []byte("RULE rule01")}, 0, true},
+		{"invalid DSL rule", "rules.*", nats.Msg{Subject: "rules." + user, Data: []byte(`RULE: a32db207-7236-4e75-abad-7c972f4cfd18["t"] > 1 TRIGGERS PUBLISH TO "alarms.*"`)}, 0, true},
+		{"DSL rule on JSON subject", "rules", nats.Msg{Subject: "rules", Data: []byte(rule)}, 0, true},
+		{"DSL rule with string operators", "rules.*", nats.Msg{Subject: "rules." + user, Data: []byte(`RULE: a32db207-7236-4e75-abad-7c972f4cfd18["mode"] IN ["auto", "eco"] a32db207-7236-4e75-abad-7c972f4cfd18["name"] MATCHES "^t\\d+$" TRIGGERS PUBLISH TO "alarms"`)}, 1, false},
 	}
 
-	for i, tc := range cases {
-		err := tc.action.validate()
-		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+	for _, tc := range cases {
+		rls, err := decodeRules(tc.subject, &tc.msg)
+		assert.Equal(t, tc.err, err != nil, fmt.Sprintf("%s: unexpected error %v", tc.desc, err))
+		assert.Equal(t, tc.rules, len(rls), fmt.Sprintf("%s: unexpected number of rules", tc.desc))
+
+		for _, r := range rls {
+			assert.Equal(t, user, r.UserId, fmt.Sprintf("%s: unexpected owner", tc.desc))
+			assert.NotEmpty(t, r.ID, fmt.Sprintf("%s: missing identifier", tc.desc))
+		}
 	}
 }
@@ -0,0 +1,109 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/nats-io/go-nats"
+)
+
+const (
+	// EventsPrefix represents prefix of the subjects carrying Mainflux
+	// messages. Messages published to these subjects are wrapped into the
+	// Mainflux raw message, so they can be consumed as regular events.
+	EventsPrefix string = engine.EventsPrefix
+
+	protocol    string = "rules"
+	senMLFormat string = "application/senml+json"
+)
+
+var _ engine.ActionExecutor = (*publishExecutor)(nil)
+
+type publishExecutor struct {
+	pub publisher
+}
+
+// NewPublishExecutor instantiates executor which publishes messages derived
+// from the triggering events to the NATS subjects specified by the actions.
+func NewPublishExecutor(nc *nats.Conn) engine.ActionExecutor {
+	return &publishExecutor{nc}
+}
+
+func (pe *publishExecutor) Execute(_ context.Context, action engine.Action, event writer.Message, rule engine.Rule) error {
+	a, ok := action.(engine.PublishAction)
+	if !ok {
+		return engine.ErrUnsupportedAction
+	}
+
+	data := engine.NewTemplateData(rule, event)
+
+	var (
+		payload []byte
+		err     error
+	)
+	switch {
+	case a.Template != "":
+		payload, err = engine.RenderTemplate(a.Template, data)
+	case strings.HasPrefix(a.Subject, EventsPrefix):
+		payload, err = senML(data)
+	default:
+		payload, err = json.Marshal(data)
+	}
+	if err != nil {
+		return engine.Permanent(err)
+	}
+
+	if strings.HasPrefix(a.Subject, EventsPrefix) {
+		raw := writer.RawMessage{
+			Channel:     strings.TrimPrefix(a.Subject, EventsPrefix),
+			Publisher:   rule.UserId,
+			Protocol:    protocol,
+			ContentType: senMLFormat,
+			Payload:     payload,
+		}
+
+		if payload, err = json.Marshal(raw); err != nil {
+			return err
+		}
+	}
+
+	return pe.pub.Publish(a.Subject, payload)
+}
+
+// record represents SenML record describing the triggering reading. It is
+// named after the rule, i.e. <ruleId>:<property>, so that the rules can tell
+// the derived events apart from the readings.
+type record struct {
+	BaseName    string   `json:"bn"`
+	Name        string   `json:"n"`
+	Unit        string   `json:"u,omitempty"`
+	Value       *float64 `json:"v,omitempty"`
+	StringValue *string  `json:"vs,omitempty"`
+	BoolValue   *bool    `json:"vb,omitempty"`
+	Time        float64  `json:"t,omitempty"`
+}
+
+// senML encodes the triggering reading as the SenML message, which is the
+// default payload of the messages published to the events subjects.
+func senML(data engine.TemplateData) ([]byte, error) {
+	rec := record{
+		BaseName: data.RuleID + ":",
+		Name:     data.Property,
+		Unit:     data.Unit,
+		Time:     data.Time,
+	}
+
+	switch v := data.Value.(type) {
+	case bool:
+		rec.BoolValue = &v
+	case string:
+		rec.StringValue = &v
+	case float64:
+		rec.Value = &v
+	}
+
+	return json.Marshal([]record{rec})
+}
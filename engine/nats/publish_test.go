@@ -0,0 +1,125 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/mainflux/mainflux/writer/cassandra"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	publishRule = engine.Rule{
+		ID:         uuid,
+		UserId:     uuid,
+		Name:       "rule01",
+		Conditions: []engine.Condition{{DeviceID: uuid, Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+	}
+	publishEvent = writer.Message{Publisher: uuid, Name: "temp", Value: 35}
+)
+
+func TestPublish(t *testing.T) {
+	publishErr := errors.New("connection closed")
+
+	cases := []struct {
+		action  engine.PublishAction
+		pubErr  error
+		subject string
+		data    string
+		err     error
+	}{
+		{
+			engine.PublishAction{Name: engine.Publish, Subject: "alarms", Template: `{{.Publisher}} {{.Property}} {{.Value}}`},
+			nil,
+			"alarms",
+			fmt.Sprintf("%s temp 35", uuid),
+			nil,
+		},
+		{
+			engine.PublishAction{Name: engine.Publish, Subject: "alarms.kitchen", Template: `{"rule":{{json .RuleName}}}`},
+			nil,
+			"alarms.kitchen",
+			`{"rule":"rule01"}`,
+			nil,
+		},
+		{
+			engine.PublishAction{Name: engine.Publish, Subject: "alarms", Template: `{{.Value}}`},
+			publishErr,
+			"",
+			"",
+			publishErr,
+		},
+	}
+
+	for i, tc := range cases {
+		pub := &publisherMock{err: tc.pubErr}
+		pe := &publishExecutor{pub}
+
+		err := pe.Execute(context.Background(), tc.action, publishEvent, publishRule)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.subject, pub.subject, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.data, string(pub.data), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestPublishDefaultPayload(t *testing.T) {
+	pub := &publisherMock{}
+	pe := &publishExecutor{pub}
+
+	action := engine.PublishAction{Name: engine.Publish, Subject: "alarms"}
+	err := pe.Execute(context.Background(), action, publishEvent, publishRule)
+	assert.Nil(t, err, "unexpected error")
+
+	var data engine.TemplateData
+	json.Unmarshal(pub.data, &data)
+	assert.Equal(t, engine.NewTemplateData(publishRule, publishEvent), data, "unexpected default payload")
+}
+
+func TestPublishEvent(t *testing.T) {
+	pub := &publisherMock{}
+	pe := &publishExecutor{pub}
+
+	payload := `[{"n":"alarm","vb":true}]`
+	action := engine.PublishAction{Name: engine.Publish, Subject: "msg.alarms", Template: payload}
+	err := pe.Execute(context.Background(), action, publishEvent, publishRule)
+	assert.Nil(t, err, "unexpected error")
+
+	var raw writer.RawMessage
+	err = json.Unmarshal(pub.data, &raw)
+	assert.Nil(t, err, "published message is not raw message")
+	assert.Equal(t, "msg.alarms", pub.subject, "unexpected subject")
+	assert.Equal(t, "alarms", raw.Channel, "unexpected channel")
+	assert.Equal(t, publishRule.UserId, raw.Publisher, "unexpected publisher")
+	assert.Equal(t, payload, string(raw.Payload), "unexpected payload")
+}
+
+func TestPublishEventDefaultPayload(t *testing.T) {
+	pub := &publisherMock{}
+	pe := &publishExecutor{pub}
+
+	action := engine.PublishAction{Name: engine.Publish, Subject: "msg.alarms"}
+	event := writer.Message{Publisher: uuid, Name: "temp", Unit: "C", Value: 35, Time: 1540000000}
+	err := pe.Execute(context.Background(), action, event, publishRule)
+	assert.Nil(t, err, "unexpected error")
+
+	var raw writer.RawMessage
+	err = json.Unmarshal(pub.data, &raw)
+	assert.Nil(t, err, "published message is not raw message")
+	assert.Equal(t, senMLFormat, raw.ContentType, "unexpected content type")
+
+	events, err := cassandra.Normalize(raw)
+	assert.Nil(t, err, "published payload is not SenML")
+	if !assert.Equal(t, 1, len(events), "unexpected number of events") {
+		return
+	}
+	assert.Equal(t, publishRule.UserId, events[0].Publisher, "unexpected publisher")
+	assert.Equal(t, publishRule.ID+":temp", events[0].Name, "unexpected name")
+	assert.Equal(t, "C", events[0].Unit, "unexpected unit")
+	assert.Equal(t, float64(35), events[0].Value, "unexpected value")
+	assert.Equal(t, float64(1540000000), events[0].Time, "unexpected time")
+}
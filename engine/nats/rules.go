@@ -2,10 +2,12 @@ package nats
 
 import (
 	"encoding/json"
+	"strings"
 	"go.uber.org/zap"
 
 	"github.com/nats-io/go-nats"
 	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/dsl"
 )
 
 var _ Subscriber = (*rulesSubscriber)(nil)
@@ -16,26 +18,19 @@ type rulesSubscriber struct {
 	logger  *zap.Logger
 }
 
-// NewRulesSubscriber instantiates subscription handler for rule creation
+// NewRulesSubscriber instantiates subscription handler for rule creation.
+// Rules are expected to be JSON, unless the handler subscribes to the wildcard
+// subject such as rules.*, in which case they are specified using the DSL and
+// owned by the user identified by the last token of the message subject.
 func NewRulesSubscriber(nc *nats.Conn, service engine.Service, logger *zap.Logger) *rulesSubscriber {
 	return &rulesSubscriber{nc, service, logger}
 }
 
 func (rs *rulesSubscriber) Subscribe(subject string, queue string) (*nats.Subscription, error) {
 	return rs.nc.QueueSubscribe(subject, queue, func(m *nats.Msg) {
-		var (
-			rls []engine.Rule
-			raw *rulesMsg
-			err error
-		)
-
-		if err = json.Unmarshal(m.Data, &raw); err != nil {
-			rs.logger.Error("Failed to unmarshal raw message.", zap.Error(err))
-			return
-		}
-
-		if rls, err = raw.toDomain(); err != nil {
-			rs.logger.Error("Failed to toDomain rules.", zap.Error(err))
+		rls, err := decodeRules(subject, m)
+		if err != nil {
+			rs.logger.Error("Failed to decode rules.", zap.Error(err))
 			return
 		}
 
@@ -49,3 +44,21 @@ func (rs *rulesSubscriber) Subscribe(subject string, queue string) (*nats.Subscr
 		}
 	})
 }
+
+func decodeRules(subject string, m *nats.Msg) ([]engine.Rule, error) {
+	if !strings.HasSuffix(subject, ".*") {
+		var raw *rulesMsg
+		if err := json.Unmarshal(m.Data, &raw); err != nil {
+			return nil, err
+		}
+
+		return raw.toDomain()
+	}
+
+	rls, err := dsl.Parse(string(m.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return fromDSL(m.Subject[strings.LastIndex(m.Subject, ".")+1:], rls)
+}
@@ -0,0 +1,81 @@
+package nats
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+	"go.uber.org/zap"
+)
+
+// seenMsg announces that the device property of the user is reported to the
+// instance of the service at the moment, expressed in Unix nanoseconds.
+type seenMsg struct {
+	UserId   string `json:"userId"`
+	DeviceId string `json:"deviceId"`
+	Property string `json:"property"`
+	Time     int64  `json:"time"`
+	Instance string `json:"instance,omitempty"`
+}
+
+var _ engine.SeenNotifier = (*seenNotifier)(nil)
+
+type seenNotifier struct {
+	pub      publisher
+	subject  string
+	instance string
+}
+
+// NewSeenNotifier instantiates notifier which announces moments at which the
+// device properties are reported to the specified instance of the service,
+// by publishing to the specified subject.
+func NewSeenNotifier(nc *nats.Conn, subject, instance string) engine.SeenNotifier {
+	return &seenNotifier{nc, subject, instance}
+}
+
+func (sn *seenNotifier) Seen(userId, deviceId, property string, at time.Time) error {
+	data, err := json.Marshal(seenMsg{userId, deviceId, property, at.UnixNano(), sn.instance})
+	if err != nil {
+		return err
+	}
+
+	return sn.pub.Publish(sn.subject, data)
+}
+
+var _ Subscriber = (*seenSubscriber)(nil)
+
+type seenSubscriber struct {
+	nc       *nats.Conn
+	service  engine.Service
+	instance string
+	logger   *zap.Logger
+}
+
+// NewSeenSubscriber instantiates subscription handler which records moments
+// at which the device properties are reported to another instance of the
+// service. Moments announced by the specified instance itself are ignored,
+// since it already recorded them. Every instance needs to receive the
+// moments, so the handler needs to subscribe without a queue group, using
+// an empty queue.
+func NewSeenSubscriber(nc *nats.Conn, service engine.Service, instance string, logger *zap.Logger) *seenSubscriber {
+	return &seenSubscriber{nc, service, instance, logger}
+}
+
+func (ss *seenSubscriber) Subscribe(subject string, queue string) (*nats.Subscription, error) {
+	return ss.nc.QueueSubscribe(subject, queue, ss.handle)
+}
+
+func (ss *seenSubscriber) handle(m *nats.Msg) {
+	var msg seenMsg
+	if err := json.Unmarshal(m.Data, &msg); err != nil || msg.UserId == "" || msg.DeviceId == "" || msg.Property == "" {
+		ss.logger.Error("Failed to decode reported property.", zap.Error(err))
+		return
+	}
+
+	if msg.Instance == ss.instance {
+		return
+	}
+
+	ss.service.MarkSeen(msg.UserId, msg.DeviceId, msg.Property, time.Unix(0, msg.Time))
+}
@@ -0,0 +1,73 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/nats-io/go-nats"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSeen(t *testing.T) {
+	publishErr := errors.New("connection closed")
+	at := time.Unix(1500000000, 5)
+
+	cases := []struct {
+		desc   string
+		pubErr error
+		data   string
+		err    error
+	}{
+		{"announce reported property", nil, fmt.Sprintf(`{"userId":"%s","deviceId":"thermometer","property":"temperature","time":1500000000000000005,"instance":"instance01"}`, uuid), nil},
+		{"publish failure", publishErr, "", publishErr},
+	}
+
+	for _, tc := range cases {
+		pub := &publisherMock{err: tc.pubErr}
+		sn := &seenNotifier{pub, "rule.seen", "instance01"}
+
+		err := sn.Seen(uuid, "thermometer", "temperature", at)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		if tc.err == nil {
+			assert.Equal(t, "rule.seen", pub.subject, fmt.Sprintf("%s: unexpected subject", tc.desc))
+			assert.Equal(t, tc.data, string(pub.data), fmt.Sprintf("%s: unexpected data", tc.desc))
+		}
+	}
+}
+
+// seenMock records moments at which the properties are marked as reported.
+type seenMock struct {
+	engine.Service
+	seen []time.Time
+}
+
+func (sm *seenMock) MarkSeen(userId, deviceId, property string, at time.Time) {
+	sm.seen = append(sm.seen, at)
+}
+
+func TestHandleSeen(t *testing.T) {
+	at := time.Unix(1500000000, 5)
+
+	cases := []struct {
+		desc string
+		data string
+		seen []time.Time
+	}{
+		{"reported to another instance", fmt.Sprintf(`{"userId":"%s","deviceId":"thermometer","property":"temperature","time":1500000000000000005,"instance":"instance02"}`, uuid), []time.Time{at}},
+		{"reported to same instance", fmt.Sprintf(`{"userId":"%s","deviceId":"thermometer","property":"temperature","time":1500000000000000005,"instance":"instance01"}`, uuid), nil},
+		{"missing property", fmt.Sprintf(`{"userId":"%s","deviceId":"thermometer","time":1500000000000000005}`, uuid), nil},
+		{"malformed message", `{"userId":`, nil},
+	}
+
+	for _, tc := range cases {
+		svc := &seenMock{}
+		ss := NewSeenSubscriber(nil, svc, "instance01", zap.NewNop())
+
+		ss.handle(&nats.Msg{Data: []byte(tc.data)})
+		assert.Equal(t, tc.seen, svc.seen, fmt.Sprintf("%s: unexpected reported moments", tc.desc))
+	}
+}
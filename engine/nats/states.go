@@ -0,0 +1,68 @@
+package nats
+
+import (
+	"encoding/json"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/asaskevich/govalidator"
+	"github.com/nats-io/go-nats"
+	"go.uber.org/zap"
+)
+
+// stateMsg enables or disables the rule.
+type stateMsg struct {
+	UserId   string `json:"userId"`
+	RuleId   string `json:"ruleId"`
+	Disabled *bool  `json:"disabled"`
+}
+
+func (msg stateMsg) validate() error {
+	if !govalidator.IsUUID(msg.UserId) || !govalidator.IsUUID(msg.RuleId) || msg.Disabled == nil {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// apply enables or disables the rule using the service.
+func (msg stateMsg) apply(svc engine.Service) error {
+	if *msg.Disabled {
+		return svc.DisableRule(msg.UserId, msg.RuleId)
+	}
+
+	return svc.EnableRule(msg.UserId, msg.RuleId)
+}
+
+var _ Subscriber = (*statesSubscriber)(nil)
+
+type statesSubscriber struct {
+	nc      *nats.Conn
+	service engine.Service
+	logger  *zap.Logger
+}
+
+// NewStatesSubscriber instantiates subscription handler which enables and
+// disables rules. Messages specify the user, the rule and whether the rule
+// is disabled.
+func NewStatesSubscriber(nc *nats.Conn, service engine.Service, logger *zap.Logger) *statesSubscriber {
+	return &statesSubscriber{nc, service, logger}
+}
+
+func (ss *statesSubscriber) Subscribe(subject string, queue string) (*nats.Subscription, error) {
+	return ss.nc.QueueSubscribe(subject, queue, func(m *nats.Msg) {
+		var msg stateMsg
+		if err := json.Unmarshal(m.Data, &msg); err != nil {
+			ss.logger.Error("Failed to decode rule state.", zap.Error(err))
+			return
+		}
+
+		if err := msg.validate(); err != nil {
+			ss.logger.Error("Invalid rule state.", zap.Error(err))
+			return
+		}
+
+		if err := msg.apply(ss.service); err != nil {
+			ss.logger.Error("Failed to change rule state.", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateMsg(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	svc := engine.NewService(repo, engine.Executors{})
+
+	ruleId := gocql.TimeUUID().String()
+	repo.Save(engine.Rule{ID: ruleId, UserId: uuid})
+
+	cases := []struct {
+		desc     string
+		data     string
+		err      error
+		disabled bool
+	}{
+		{"disable rule", fmt.Sprintf(`{"userId":"%s","ruleId":"%s","disabled":true}`, uuid, ruleId), nil, true},
+		{"enable rule", fmt.Sprintf(`{"userId":"%s","ruleId":"%s","disabled":false}`, uuid, ruleId), nil, false},
+		{"missing state", fmt.Sprintf(`{"userId":"%s","ruleId":"%s"}`, uuid, ruleId), engine.ErrMalformedEntity, false},
+		{"malformed rule id", fmt.Sprintf(`{"userId":"%s","ruleId":"malformed","disabled":true}`, uuid), engine.ErrMalformedEntity, false},
+		{"non-existent rule", fmt.Sprintf(`{"userId":"%s","ruleId":"%s","disabled":true}`, uuid, gocql.TimeUUID()), engine.ErrNotFound, false},
+	}
+
+	for _, tc := range cases {
+		var msg stateMsg
+		err := json.Unmarshal([]byte(tc.data), &msg)
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+
+		if err = msg.validate(); err == nil {
+			err = msg.apply(svc)
+		}
+		assert.Equal(t, tc.err, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+
+		rule, _ := repo.One(uuid, ruleId)
+		assert.Equal(t, tc.disabled, rule.Disabled, fmt.Sprintf("%s: unexpected state", tc.desc))
+	}
+}
@@ -3,6 +3,8 @@ package engine
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 )
 
 // Operator represents possible operators that should be used to compare
@@ -18,6 +20,12 @@ const (
 	Gt
 	Gte
 	Btw
+	Contains
+	StartsWith
+	EndsWith
+	Matches
+	In
+	NotIn
 )
 
 func (op Operator) String() string {
@@ -32,6 +40,13 @@ var operatorIDs = map[Operator]string{
 	Gt:  ">",
 	Gte: ">=",
 	Btw: "BETWEEN",
+
+	Contains:   "CONTAINS",
+	StartsWith: "STARTS WITH",
+	EndsWith:   "ENDS WITH",
+	Matches:    "MATCHES",
+	In:         "IN",
+	NotIn:      "NOT IN",
 }
 
 var operatorNames = idsToNames(operatorIDs)
@@ -75,7 +90,10 @@ func (op *Operator) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// Compare compares two values using specified operator
+// Compare compares two values using specified operator. String operators
+// check whether the actual value contains, starts or ends with the expected
+// one, or matches its regular expression, while membership operators check
+// whether the actual value is one of the expected set.
 func (op Operator) Compare(expected, actual interface{}) bool {
 	switch op {
 	case Eq:
@@ -94,7 +112,51 @@ func (op Operator) Compare(expected, actual interface{}) bool {
 		value := actual.(float64)
 		ex := expected.(Range)
 		return ex.From <= value && value <= ex.To
+	case Contains:
+		return strings.Contains(actual.(string), expected.(string))
+	case StartsWith:
+		return strings.HasPrefix(actual.(string), expected.(string))
+	case EndsWith:
+		return strings.HasSuffix(actual.(string), expected.(string))
+	case Matches:
+		p, ok := expected.(Pattern)
+		return ok && p.Regexp != nil && p.MatchString(actual.(string))
+	case In, NotIn:
+		member := false
+		for _, v := range expected.([]string) {
+			if v == actual.(string) {
+				member = true
+				break
+			}
+		}
+		return member == (op == In)
 	}
 
 	return false
 }
+
+// Pattern represents the regular expression compared using the MATCHES
+// operator. It is compiled once, when the condition is created, instead of
+// on every comparison.
+type Pattern struct {
+	*regexp.Regexp
+}
+
+// CompilePattern compiles the regular expression of the condition.
+func CompilePattern(expr string) (Pattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, err
+	}
+
+	return Pattern{re}, nil
+}
+
+// MarshalJSON encodes the pattern as its regular expression.
+func (p Pattern) MarshalJSON() ([]byte, error) {
+	if p.Regexp == nil {
+		return json.Marshal("")
+	}
+
+	return json.Marshal(p.String())
+}
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+type ruleRef struct {
+	userId string
+	ruleId string
+}
+
+// pendingStore keeps the moments since which conditions with duration
+// qualifier have been continuously satisfied, and the moments at which they
+// are due to be held for their duration, until the rule is checked then.
+type pendingStore struct {
+	mu    sync.Mutex
+	since map[ruleRef]map[string]time.Time
+	due   map[ruleRef]map[string]time.Time
+}
+
+func newPendingStore() *pendingStore {
+	return &pendingStore{
+		since: make(map[ruleRef]map[string]time.Time),
+		due:   make(map[ruleRef]map[string]time.Time),
+	}
+}
+
+// start marks condition as satisfied, unless it is already pending.
+func (ps *pendingStore) start(rule Rule, cnd Condition, now time.Time) {
+	ps.begin(rule, cnd, now)
+}
+
+// begin marks condition as pending, unless it is already pending, and
+// retrieves the moment since which it is pending.
+func (ps *pendingStore) begin(rule Rule, cnd Condition, now time.Time) time.Time {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	conditions, ok := ps.since[ref]
+	if !ok {
+		conditions = make(map[string]time.Time)
+		ps.since[ref] = conditions
+	}
+
+	key := conditionKey(cnd)
+	since, ok := conditions[key]
+	if !ok {
+		since = now
+		conditions[key] = since
+		if cnd.For > 0 {
+			ps.schedule(ref, key, since.Add(time.Duration(cnd.For)))
+		}
+	}
+
+	return since
+}
+
+// schedule marks the moment at which the condition is due to be held. It is
+// called with the lock held.
+func (ps *pendingStore) schedule(ref ruleRef, key string, at time.Time) {
+	conditions, ok := ps.due[ref]
+	if !ok {
+		conditions = make(map[string]time.Time)
+		ps.due[ref] = conditions
+	}
+
+	conditions[key] = at
+}
+
+// unschedule drops the moment at which the condition is due to be held. It
+// is called with the lock held.
+func (ps *pendingStore) unschedule(ref ruleRef, key string) {
+	delete(ps.due[ref], key)
+	if len(ps.due[ref]) == 0 {
+		delete(ps.due, ref)
+	}
+}
+
+// stop marks condition as unsatisfied.
+func (ps *pendingStore) stop(rule Rule, cnd Condition) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	key := conditionKey(cnd)
+	delete(ps.since[ref], key)
+	if len(ps.since[ref]) == 0 {
+		delete(ps.since, ref)
+	}
+	ps.unschedule(ref, key)
+}
+
+// held checks whether condition has been satisfied for its duration. Once it
+// is, the condition is no longer due.
+func (ps *pendingStore) held(rule Rule, cnd Condition, now time.Time) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	key := conditionKey(cnd)
+	since, ok := ps.since[ref][key]
+	if !ok || now.Sub(since) < time.Duration(cnd.For) {
+		return false
+	}
+
+	ps.unschedule(ref, key)
+	return true
+}
+
+// elapsed retrieves rules whose conditions are due to be held by now, so that
+// they are checked even if no further events refer to them. Retrieved rules
+// are no longer due.
+func (ps *pendingStore) elapsed(now time.Time) []ruleRef {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var refs []ruleRef
+	for ref, conditions := range ps.due {
+		due := false
+		for key, at := range conditions {
+			if !at.After(now) {
+				delete(conditions, key)
+				due = true
+			}
+		}
+
+		if len(conditions) == 0 {
+			delete(ps.due, ref)
+		}
+
+		if due {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// remove drops pending state of all conditions of the rule.
+func (ps *pendingStore) remove(userId, ruleId string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ref := ruleRef{userId, ruleId}
+	delete(ps.since, ref)
+	delete(ps.due, ref)
+}
+
+// heldEvent creates the event on behalf of which the rule is evaluated once
+// its condition is due to be held. Event is the last reading referred by the
+// first condition with duration, and is timestamped with the moment of the
+// check.
+func heldEvent(rule Rule, state State, now time.Time) writer.Message {
+	var event writer.Message
+	for _, cnd := range rule.AllConditions() {
+		if cnd.For == 0 {
+			continue
+		}
+
+		if reading, ok := state.Reading(cnd.DeviceID, cnd.Property); ok {
+			event = reading
+			break
+		}
+	}
+	event.Time = float64(now.UnixNano()) / float64(time.Second)
+
+	return event
+}
+
+func conditionKey(cnd Condition) string {
+	key := fmt.Sprintf("%s|%s|%s|%v|%s", cnd.DeviceID, cnd.Property, cnd.Operator, cnd.Value, time.Duration(cnd.For))
+	if cnd.Aggregate != nil {
+		key += "|" + cnd.Aggregate.String()
+	}
+
+	if cnd.Reset != nil {
+		key += fmt.Sprintf("|reset %v", *cnd.Reset)
+	}
+
+	if cnd.Missing > 0 {
+		key += "|missing " + time.Duration(cnd.Missing).String()
+	}
+
+	return key
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// MaxRetryAttempts limits the number of attempts of the failed action.
+	MaxRetryAttempts = 100
+
+	// MaxRetryBackoff limits the delay between two attempts, whether the
+	// maximum backoff is specified or not.
+	MaxRetryBackoff = 24 * time.Hour
+)
+
+// RetryPolicy determines how many times the failed action is attempted. The
+// delay before the next attempt starts at backoff and doubles with each
+// failed attempt, up to the maximum backoff if specified, or MaxRetryBackoff
+// otherwise. Delays are randomized, so that retries of actions which failed
+// together are spread over time.
+type RetryPolicy struct {
+	Attempts   int      `json:"attempts"`
+	Backoff    Duration `json:"backoff,omitempty"`
+	MaxBackoff Duration `json:"maxBackoff,omitempty"`
+}
+
+// permanentError marks the error of the action which can not succeed if
+// attempted again.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Permanent() bool {
+	return true
+}
+
+// Permanent marks the error as permanent, so that the failed action is kept
+// as a dead letter without further attempts.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return permanentError{err}
+}
+
+// IsPermanent reports whether the action which failed with the error can not
+// succeed if attempted again. Errors marked by Permanent, unsupported actions
+// and errors having Permanent method which returns true are permanent.
+func IsPermanent(err error) bool {
+	if err == ErrUnsupportedAction {
+		return true
+	}
+
+	p, ok := err.(interface{ Permanent() bool })
+	return ok && p.Permanent()
+}
+
+// delay calculates the delay before the next attempt, after the specified
+// number of failed attempts. Delay is chosen randomly from the upper half
+// of the exponential backoff.
+func (p RetryPolicy) delay(failed int) time.Duration {
+	max := MaxRetryBackoff
+	if p.MaxBackoff > 0 && time.Duration(p.MaxBackoff) < max {
+		max = time.Duration(p.MaxBackoff)
+	}
+
+	d := time.Duration(p.Backoff)
+	for i := 1; i < failed && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
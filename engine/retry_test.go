@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		policy RetryPolicy
+		failed int
+		max    time.Duration
+	}{
+		{RetryPolicy{Attempts: 5}, 1, 0},
+		{RetryPolicy{Attempts: 5, Backoff: Duration(time.Second)}, 1, time.Second},
+		{RetryPolicy{Attempts: 5, Backoff: Duration(time.Second)}, 3, 4 * time.Second},
+		{RetryPolicy{Attempts: 5, Backoff: Duration(time.Second), MaxBackoff: Duration(3 * time.Second)}, 3, 3 * time.Second},
+		{RetryPolicy{Attempts: 100, Backoff: Duration(time.Second), MaxBackoff: Duration(time.Minute)}, 99, time.Minute},
+		{RetryPolicy{Attempts: 100, Backoff: Duration(time.Second)}, 99, MaxRetryBackoff},
+		{RetryPolicy{Attempts: 5, Backoff: Duration(48 * time.Hour), MaxBackoff: Duration(72 * time.Hour)}, 1, MaxRetryBackoff},
+	}
+
+	for i, tc := range cases {
+		for n := 0; n < 10; n++ {
+			d := tc.policy.delay(tc.failed)
+			assert.True(t, d >= tc.max/2 && d <= tc.max, fmt.Sprintf("failed at %d: delay %s out of range\n", i, d))
+		}
+	}
+}
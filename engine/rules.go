@@ -1,32 +1,144 @@
 package engine
 
-import "github.com/mainflux/mainflux/writer"
+import (
+	"strings"
 
-// Rule represents base model for Mainflux rule.
+	"github.com/mainflux/mainflux/writer"
+)
+
+// EventsPrefix represents prefix of the subjects carrying Mainflux messages,
+// which are evaluated against the rules of their publishers.
+const EventsPrefix = "msg."
+
+// Rule represents base model for Mainflux rule. Rule is satisfied if all of
+// its conditions and the optional boolean expression are satisfied. Trigger
+// mode determines whether actions are performed on every matching event, or
+// only when the rule becomes matched or unmatched. Resolved actions are
+// performed when the rule goes from matched back to unmatched. Optional
+// throttle limits how often the actions are performed, while optional retry
+// policy overrides the one configured for the failed actions. Optional
+// schedule limits the rule to the time windows in which it is applied.
+// Disabled rules are kept, but are not applied to the events.
 type Rule struct {
-	ID         string      `json:"id"`
-	UserId     string      `json:"-"`
-	Name       string      `json:"name,omitempty"`
-	Conditions []Condition `json:"conditions"`
-	Actions    []Action    `json:"actions"`
+	ID         string       `json:"id"`
+	UserId     string       `json:"-"`
+	Name       string       `json:"name,omitempty"`
+	Disabled   bool         `json:"disabled,omitempty"`
+	Conditions []Condition  `json:"conditions"`
+	Expression *Expression  `json:"expression,omitempty"`
+	Trigger    TriggerMode  `json:"trigger,omitempty"`
+	Schedule   *Schedule    `json:"schedule,omitempty"`
+	Throttle   *Throttle    `json:"throttle,omitempty"`
+	Retry      *RetryPolicy `json:"retry,omitempty"`
+	Actions    []Action     `json:"actions"`
+	Resolved   []Action     `json:"resolved,omitempty"`
 }
 
+const (
+	// SendEmail represents type of the action which sends an email.
+	SendEmail string = "SEND EMAIL"
+
+	// TurnOff represents type of the action which turns off the device.
+	TurnOff string = "TURN OFF"
+
+	// Webhook represents type of the action which sends an HTTP request.
+	Webhook string = "WEBHOOK"
+
+	// Publish represents type of the action which publishes a message.
+	Publish string = "PUBLISH"
+)
+
 // Action represents base action specification.
 type Action interface {
-	Execute()
+	// Type returns type of the action, used to resolve the executor
+	// responsible for performing it.
+	Type() string
 }
 
-// IsMatchedBy checks that all event satisfies all conditions
+// IsMatchedBy checks that event satisfies all conditions and the expression
 // specified by rule.
 func (rule Rule) IsMatchedBy(event writer.Message) bool {
+	matched, _ := rule.Explain(event)
+	return matched
+}
+
+// IsMatchedByState checks that the last known readings of the device
+// properties satisfy all conditions and the expression specified by rule.
+// Durations for which conditions need to stay satisfied are not considered,
+// while aggregate conditions are evaluated against the last reading alone,
+// and missing data conditions are never satisfied.
+func (rule Rule) IsMatchedByState(state State) bool {
+	return rule.evaluate(func(cnd Condition) bool {
+		event, ok := state.Reading(cnd.DeviceID, cnd.Property)
+		return ok && cnd.isSatisfied(event)
+	})
+}
+
+// RefersTo checks whether any of the rule conditions refers to the event's
+// publisher and property.
+func (rule Rule) RefersTo(event writer.Message) bool {
+	for _, cnd := range rule.AllConditions() {
+		if cnd.refersTo(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllConditions retrieves conditions of the rule, including the ones
+// contained in its expression.
+func (rule Rule) AllConditions() []Condition {
+	cnds := append([]Condition{}, rule.Conditions...)
+	if rule.Expression != nil {
+		cnds = append(cnds, rule.Expression.Conditions()...)
+	}
+
+	return cnds
+}
+
+// evaluate evaluates conditions and the expression of the rule using the
+// specified predicate for evaluating single condition.
+func (rule Rule) evaluate(satisfied func(Condition) bool) bool {
 	for _, cnd := range rule.Conditions {
-		if !cnd.isSatisfied(event) {
+		if !satisfied(cnd) {
 			return false
 		}
 	}
+
+	if rule.Expression != nil {
+		return rule.Expression.evaluate(satisfied)
+	}
+
 	return true
 }
 
+// FeedsBack checks whether the rule refers to the events which it publishes.
+// Events published to the EventsPrefix subjects are published on behalf of
+// the rule's owner, so such rule would trigger itself in a loop.
+func (rule Rule) FeedsBack() bool {
+	publishes := false
+	for _, actions := range [][]Action{rule.Actions, rule.Resolved} {
+		for _, action := range actions {
+			if a, ok := action.(PublishAction); ok && strings.HasPrefix(a.Subject, EventsPrefix) {
+				publishes = true
+			}
+		}
+	}
+
+	if !publishes {
+		return false
+	}
+
+	for _, cnd := range rule.AllConditions() {
+		if cnd.DeviceID == rule.UserId {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SendEmailAction represents model for triggering an email sending.
 type SendEmailAction struct {
 	Name      string `json:"name"`
@@ -36,7 +148,9 @@ type SendEmailAction struct {
 
 var _ Action = (*SendEmailAction)(nil)
 
-func (action SendEmailAction) Execute() {}
+func (action SendEmailAction) Type() string {
+	return SendEmail
+}
 
 // TurnOffAction represents model for triggering action to turn off
 // the device.
@@ -47,7 +161,53 @@ type TurnOffAction struct {
 
 var _ Action = (*TurnOffAction)(nil)
 
-func (action TurnOffAction) Execute() {}
+func (action TurnOffAction) Type() string {
+	return TurnOff
+}
+
+// WebhookAction represents model for triggering an HTTP callback. Request
+// body is the template rendered using data about the triggering event.
+type WebhookAction struct {
+	Name    string            `json:"name"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Timeout Duration          `json:"timeout,omitempty"`
+}
+
+var _ Action = (*WebhookAction)(nil)
+
+func (action WebhookAction) Type() string {
+	return Webhook
+}
+
+// PublishAction represents model for publishing a message derived from the
+// triggering event to the specified subject. Message is the template rendered
+// using data about the triggering event.
+type PublishAction struct {
+	Name     string `json:"name"`
+	Subject  string `json:"subject"`
+	Template string `json:"template,omitempty"`
+}
+
+var _ Action = (*PublishAction)(nil)
+
+func (action PublishAction) Type() string {
+	return Publish
+}
+
+// RuleFilter limits the listed rules. Zero filter matches all rules.
+type RuleFilter struct {
+	// Disabled, if specified, matches either only disabled or only enabled
+	// rules.
+	Disabled *bool
+}
+
+// Matches checks whether the rule satisfies the filter.
+func (f RuleFilter) Matches(rule Rule) bool {
+	return f.Disabled == nil || *f.Disabled == rule.Disabled
+}
 
 // RuleRepository specifies API for rules managing.
 type RuleRepository interface {
@@ -62,7 +222,29 @@ type RuleRepository interface {
 	// All retrieves list of rules for specific user.
 	All(string) []Rule
 
+	// WatchedUsers retrieves identifiers of the users owning enabled rules
+	// which detect missing data.
+	WatchedUsers() []string
+
 	// Remove removes specific rule from database. A non-nil error is
 	// returned to indicate operation failure.
 	Remove(string, string) error
 }
+
+// RuleCache represents the rule repository which keeps rules in memory.
+type RuleCache interface {
+	RuleRepository
+
+	// Invalidate drops cached rules of the user, so that they are reloaded
+	// from the underlying repository.
+	Invalidate(string)
+}
+
+// RuleNotifier specifies API for notifying other instances of the service
+// about changes of the rules.
+type RuleNotifier interface {
+	// RulesChanged announces that rules of the user identified by the
+	// specified unique identifier are saved or removed. A non-nil error
+	// is returned to indicate delivery failure.
+	RulesChanged(string) error
+}
@@ -1,52 +1,592 @@
 package engine
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/mainflux/mainflux/writer"
 )
 
 var _ Service = (*ruleService)(nil)
 
 type ruleService struct {
-	rules RuleRepository
+	rules     RuleRepository
+	index     *ruleIndex
+	executors Executors
+	dispatch  *Dispatcher
+	letters   DeadLetterRepository
+	history   ExecutionRepository
+	state     *stateStore
+	pending   *pendingStore
+	active    *activeStore
+	samples   *aggregateStore
+	matches   *matchStore
+	firings   *firingStore
+	staleness time.Duration
+	limit     int
+	clock     func() time.Time
+	notifier  SeenNotifier
+	lease     Lease
+
+	// watching loads rules which detect missing data on the first check.
+	watching sync.Once
+}
+
+// Option represents optional configuration of the domain service.
+type Option func(*ruleService)
+
+// WithStaleness sets the maximum age of the device readings which are taken
+// into account while evaluating rules. Readings are never stale by default.
+func WithStaleness(staleness time.Duration) Option {
+	return func(rs *ruleService) {
+		rs.staleness = staleness
+	}
+}
+
+// WithSampleLimit sets the maximum number of samples of the single device
+// property kept for evaluating aggregate conditions, which is DefaultSampleLimit
+// by default. Windows of the aggregate conditions are truncated to the limit.
+func WithSampleLimit(limit int) Option {
+	return func(rs *ruleService) {
+		rs.limit = limit
+	}
+}
+
+// WithClock sets the source of the current time used by the service.
+func WithClock(clock func() time.Time) Option {
+	return func(rs *ruleService) {
+		rs.clock = clock
+	}
+}
+
+// WithDispatcher makes the service hand actions of the satisfied rules over
+// to the dispatcher, instead of executing them while applying the rules.
+func WithDispatcher(dispatcher *Dispatcher) Option {
+	return func(rs *ruleService) {
+		rs.dispatch = dispatcher
+	}
+}
+
+// WithDeadLetters sets the repository of the actions which failed after all
+// attempts, so that they can be listed and replayed.
+func WithDeadLetters(letters DeadLetterRepository) Option {
+	return func(rs *ruleService) {
+		rs.letters = letters
+	}
+}
+
+// WithExecutions sets the repository of the rule executions, so that every
+// firing of the rules is recorded along with the outcomes of its actions.
+func WithExecutions(history ExecutionRepository) Option {
+	return func(rs *ruleService) {
+		rs.history = history
+	}
+}
+
+// WithSeenNotifier makes the service announce moments at which the device
+// properties watched for missing data are reported, so that the instance
+// checking for missing data takes the readings received by the other
+// instances into account.
+func WithSeenNotifier(notifier SeenNotifier) Option {
+	return func(rs *ruleService) {
+		rs.notifier = notifier
+	}
+}
+
+// WithLease makes the service check rules for missing data only while it
+// holds the lease, so that a single instance of the service checks them.
+// Without the lease, every instance checks the rules.
+func WithLease(lease Lease) Option {
+	return func(rs *ruleService) {
+		rs.lease = lease
+	}
 }
 
-// NewService instantiates the domain service implementation.
-func NewService(rules RuleRepository) Service {
-	return &ruleService{
-		rules: rules,
+// NewService instantiates the domain service implementation. Actions of the
+// satisfied rules are performed by executors registered for their types.
+func NewService(rules RuleRepository, executors Executors, opts ...Option) Service {
+	rs := &ruleService{
+		rules:     rules,
+		index:     newRuleIndex(),
+		executors: executors,
+		state:     newStateStore(),
+		pending:   newPendingStore(),
+		active:    newActiveStore(),
+		matches:   newMatchStore(),
+		firings:   newFiringStore(),
+		limit:     DefaultSampleLimit,
+		clock:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(rs)
 	}
+	rs.samples = newAggregateStore(rs.limit)
+
+	return rs
 }
 
 func (rs *ruleService) SaveRule(rule Rule) error {
-	return rs.rules.Save(rule)
+	if err := rs.rules.Save(rule); err != nil {
+		return err
+	}
+
+	rs.watch(rule)
+	rs.index.save(rule)
+	return nil
+}
+
+func (rs *ruleService) UpdateRule(rule Rule) error {
+	existing, err := rs.rules.One(rule.UserId, rule.ID)
+	if err != nil {
+		return err
+	}
+	rule.Disabled = existing.Disabled
+
+	if err := rs.rules.Save(rule); err != nil {
+		return err
+	}
+
+	rs.watch(rule)
+	rs.index.save(rule)
+	rs.reset(rule.UserId, rule.ID)
+	return nil
 }
 
 func (rs *ruleService) ViewRule(userId string, ruleId string) (*Rule, error) {
 	return rs.rules.One(userId, ruleId)
 }
 
-func (rs *ruleService) ListRules(userId string) ([]Rule, error) {
-	return rs.rules.All(userId), nil
+func (rs *ruleService) ListRules(userId string, filter RuleFilter) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	for _, rule := range rs.rules.All(userId) {
+		if filter.Matches(rule) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
 }
 
-func (rs *ruleService) RemoveRule(userId string, ruleId string) error {
-	return rs.rules.Remove(userId, ruleId)
+func (rs *ruleService) EnableRule(userId string, ruleId string) error {
+	return rs.disable(userId, ruleId, false)
+}
+
+func (rs *ruleService) DisableRule(userId string, ruleId string) error {
+	return rs.disable(userId, ruleId, true)
 }
 
-func (rs *ruleService) ApplyRules(userId string, events []writer.Message) error {
-	rls, err := rs.ListRules(userId)
+// disable changes whether the rule is disabled, resetting its evaluation
+// state, so that enabled rule does not rely on the stale state.
+func (rs *ruleService) disable(userId, ruleId string, disabled bool) error {
+	rule, err := rs.rules.One(userId, ruleId)
 	if err != nil {
 		return err
 	}
 
+	if rule.Disabled == disabled {
+		return nil
+	}
+	rule.Disabled = disabled
+
+	if err := rs.rules.Save(*rule); err != nil {
+		return err
+	}
+
+	rs.watch(*rule)
+	rs.index.save(*rule)
+	rs.reset(userId, ruleId)
+	return nil
+}
+
+func (rs *ruleService) RemoveRule(userId string, ruleId string) error {
+	if err := rs.rules.Remove(userId, ruleId); err != nil {
+		return err
+	}
+
+	rs.index.remove(userId, ruleId)
+	rs.reset(userId, ruleId)
+	return nil
+}
+
+func (rs *ruleService) InvalidateRules(userId string) {
+	if cache, ok := rs.rules.(RuleCache); ok {
+		cache.Invalidate(userId)
+	}
+
+	rs.index.invalidate(userId)
+	rs.watchUser(userId)
+}
+
+// watch indexes rules of the user if the rule detects missing data, so that
+// it is checked even if the user's readings were not received yet.
+func (rs *ruleService) watch(rule Rule) {
+	if rule.Disabled || !rule.DetectsMissing() {
+		return
+	}
+
+	rs.index.load(rule.UserId, func() []Rule {
+		return rs.rules.All(rule.UserId)
+	})
+}
+
+// watchUser indexes rules of the user if any of them detects missing data.
+func (rs *ruleService) watchUser(userId string) {
+	rules := rs.rules.All(userId)
+	for _, rule := range rules {
+		if !rule.Disabled && rule.DetectsMissing() {
+			rs.index.load(userId, func() []Rule {
+				return rules
+			})
+			return
+		}
+	}
+}
+
+// watchAll indexes rules of all users owning rules which detect missing
+// data, so that they are checked after the service is restarted as well.
+func (rs *ruleService) watchAll() {
+	for _, userId := range rs.rules.WatchedUsers() {
+		userId := userId
+		rs.index.load(userId, func() []Rule {
+			return rs.rules.All(userId)
+		})
+	}
+}
+
+// reset drops evaluation state of the rule.
+func (rs *ruleService) reset(userId, ruleId string) {
+	rs.pending.remove(userId, ruleId)
+	rs.active.remove(userId, ruleId)
+	rs.matches.remove(userId, ruleId)
+	rs.firings.remove(userId, ruleId)
+}
+
+func (rs *ruleService) ApplyRules(ctx context.Context, userId string, events []writer.Message) error {
+	load := func() []Rule {
+		return rs.rules.All(userId)
+	}
+
+	var failure error
+	for _, event := range events {
+		now := rs.clock()
+		rs.state.update(userId, event, now)
+		state := rs.state.snapshot(userId, now, rs.staleness)
+
+		rules := rs.index.candidates(userId, event.Publisher, event.Name, load)
+		rs.samples.record(userId, event, rules, now)
+
+		if rs.notifier != nil && watches(rules, event) {
+			if err := rs.notifier.Seen(userId, event.Publisher, event.Name, now); err != nil && failure == nil {
+				failure = err
+			}
+		}
+
+		for _, rule := range rules {
+			trace := rs.evaluate(rule, event, state, now)
+			rec := newRecorder(rs.history, rule, trace, now)
+			if err := rs.perform(ctx, trace.Actions, event, rule, rec, &failure); err != nil {
+				return err
+			}
+		}
+	}
+
+	return failure
+}
+
+func (rs *ruleService) CheckMissing(ctx context.Context) error {
+	if rs.lease != nil {
+		held, err := rs.lease.Acquire()
+		if err != nil || !held {
+			return err
+		}
+	}
+
+	rs.watching.Do(rs.watchAll)
+	now := rs.clock()
+
+	var failure error
+	for _, rule := range rs.index.watched() {
+		event := missingEvent(rule, now)
+		if !rule.isActive(event, now) {
+			continue
+		}
+
+		state := rs.state.snapshot(rule.UserId, now, rs.staleness)
+		trace := rs.conclude(rule, event, state, now)
+		rec := newRecorder(rs.history, rule, trace, now)
+		if err := rs.perform(ctx, trace.Actions, event, rule, rec, &failure); err != nil {
+			return err
+		}
+	}
+
+	return failure
+}
+
+func (rs *ruleService) MarkSeen(userId, deviceId, property string, at time.Time) {
+	rs.state.mark(userId, deviceId, property, at)
+}
+
+func (rs *ruleService) CheckPending(ctx context.Context) error {
+	now := rs.clock()
+
+	var failure error
+	for _, ref := range rs.pending.elapsed(now) {
+		rule, ok := rs.index.rule(ref.userId, ref.ruleId)
+		if !ok {
+			continue
+		}
+
+		state := rs.state.snapshot(rule.UserId, now, rs.staleness)
+		event := heldEvent(rule, state, now)
+		if !rule.isActive(event, now) {
+			continue
+		}
+
+		trace := rs.conclude(rule, event, state, now)
+		rec := newRecorder(rs.history, rule, trace, now)
+		if err := rs.perform(ctx, trace.Actions, event, rule, rec, &failure); err != nil {
+			return err
+		}
+	}
+
+	return failure
+}
+
+func (rs *ruleService) SimulateRule(rule Rule, events []writer.Message) ([]Trace, error) {
+	sim := &ruleService{
+		state:     newStateStore(),
+		pending:   newPendingStore(),
+		active:    newActiveStore(),
+		samples:   newAggregateStore(rs.limit),
+		matches:   newMatchStore(),
+		firings:   newFiringStore(),
+		staleness: rs.staleness,
+	}
+
+	var traces []Trace
 	for _, event := range events {
-		for _, rule := range rls {
-			if rule.IsMatchedBy(event) {
-				for _, action := range rule.Actions {
-					action.Execute()
-				}
+		now := rs.clock()
+		if event.Time > 0 {
+			now = timeOf(event)
+		}
+
+		sim.state.update(rule.UserId, event, now)
+		sim.samples.record(rule.UserId, event, []Rule{rule}, now)
+		if !rule.RefersTo(event) {
+			traces = append(traces, Trace{Event: event})
+			continue
+		}
+
+		state := sim.state.snapshot(rule.UserId, now, sim.staleness)
+		traces = append(traces, sim.evaluate(rule, event, state, now))
+	}
+
+	return traces, nil
+}
+
+func (rs *ruleService) ListDeadLetters(userId string) ([]DeadLetter, error) {
+	if rs.letters == nil {
+		return []DeadLetter{}, nil
+	}
+
+	return rs.letters.All(userId), nil
+}
+
+func (rs *ruleService) ListExecutions(userId, ruleId string, filter ExecutionFilter) (ExecutionPage, error) {
+	if rs.history == nil {
+		return ExecutionPage{Offset: filter.Offset, Limit: filter.Limit, Executions: []Execution{}}, nil
+	}
+
+	return rs.history.RetrieveAll(userId, ruleId, filter)
+}
+
+func (rs *ruleService) ReplayDeadLetter(ctx context.Context, userId, letterId string) error {
+	if rs.letters == nil {
+		return ErrNotFound
+	}
+
+	dl, err := rs.letters.One(userId, letterId)
+	if err != nil {
+		return err
+	}
+
+	// Actions are replayed on behalf of the current version of the rule.
+	rule, err := rs.rules.One(userId, dl.RuleId)
+	if err != nil {
+		rule = &Rule{ID: dl.RuleId, UserId: userId}
+	}
+
+	var failure error
+	if err := rs.perform(ctx, []Action{dl.Action}, dl.Event, *rule, nil, &failure); err != nil {
+		return err
+	}
+
+	if failure != nil {
+		return failure
+	}
+
+	return rs.letters.Remove(userId, letterId)
+}
+
+// evaluate evaluates rule referring to the event against the state, updating
+// its evaluation state. Returned trace lists the actions to perform. Rules
+// are not evaluated outside of their schedule.
+func (rs *ruleService) evaluate(rule Rule, event writer.Message, state State, now time.Time) Trace {
+	if !rule.isActive(event, now) {
+		return Trace{Event: event, Inactive: true}
+	}
+
+	rs.track(rule, event, now)
+	return rs.conclude(rule, event, state, now)
+}
+
+// conclude evaluates rule against the state, updating whether it is matched
+// and fired, without tracking the event itself.
+func (rs *ruleService) conclude(rule Rule, event writer.Message, state State, now time.Time) Trace {
+	matched, conditions := rule.explain(rs.check(rule, state, now))
+
+	trace := Trace{
+		Event:      event,
+		Evaluated:  true,
+		Conditions: conditions,
+		Matched:    matched,
+	}
+
+	t := rs.matches.update(rule, matched)
+	if t.fires(rule.Trigger) {
+		if rs.firings.allow(rule, now) {
+			trace.Fired = true
+			trace.Actions = append(trace.Actions, rule.Actions...)
+		} else {
+			trace.Throttled = true
+		}
+	}
+
+	if t.resolves() {
+		trace.Resolved = true
+		trace.Actions = append(trace.Actions, rule.Resolved...)
+	}
+
+	return trace
+}
+
+// perform executes or dispatches actions of the rule, recording the first
+// failure. Outcomes of the actions are settled on the recorder, including
+// the actions which are not performed. Context error is returned if the
+// context is done before all actions are executed or dispatched.
+func (rs *ruleService) perform(ctx context.Context, actions []Action, event writer.Message, rule Rule, rec *recorder, failure *error) error {
+	for i, action := range actions {
+		if err := ctx.Err(); err != nil {
+			for ; i < len(actions); i++ {
+				rec.settle(i, err)
 			}
+			return err
+		}
+
+		if err := rs.execute(ctx, action, event, rule, rec, i); err != nil && *failure == nil {
+			*failure = err
 		}
 	}
+
 	return nil
 }
+
+// execute executes or dispatches the i-th action, settling its outcome on
+// the recorder once it is known.
+func (rs *ruleService) execute(ctx context.Context, action Action, event writer.Message, rule Rule, rec *recorder, i int) error {
+	if rs.dispatch == nil {
+		err := rs.executors.Execute(ctx, action, event, rule)
+		rec.settle(i, err)
+		return err
+	}
+
+	j := job{
+		action: action,
+		event:  event,
+		rule:   rule,
+		done:   func(err error) { rec.settle(i, err) },
+	}
+
+	if err := rs.dispatch.dispatch(ctx, j); err != nil {
+		rec.settle(i, err)
+		return err
+	}
+
+	return nil
+}
+
+// track updates pending state of the rule conditions which need to stay
+// satisfied for some duration and refer to the event.
+func (rs *ruleService) track(rule Rule, event writer.Message, now time.Time) {
+	for _, cnd := range rule.AllConditions() {
+		if cnd.For == 0 || cnd.Aggregate != nil || !cnd.refersTo(event) {
+			continue
+		}
+
+		if cnd.isSatisfied(event) {
+			rs.pending.start(rule, cnd, now)
+			continue
+		}
+		rs.pending.stop(rule, cnd)
+	}
+}
+
+// check creates function which checks the condition against the state,
+// taking into account the duration for which it needs to stay satisfied.
+// Aggregate conditions are checked against the samples within their window,
+// while missing data conditions are checked against the moment at which the
+// property was last reported, or since which the condition is checked, if
+// the property was not reported yet. Hysteresis conditions stay satisfied
+// until their reset threshold is crossed.
+func (rs *ruleService) check(rule Rule, state State, now time.Time) func(Condition) ConditionTrace {
+	return func(cnd Condition) ConditionTrace {
+		if cnd.Missing > 0 {
+			since, ok := rs.state.seen(rule.UserId, cnd.DeviceID, cnd.Property)
+			if !ok {
+				since = rs.pending.begin(rule, cnd, now)
+			}
+
+			return ConditionTrace{Condition: cnd, Known: ok, Satisfied: now.Sub(since) >= time.Duration(cnd.Missing)}
+		}
+
+		if cnd.Aggregate != nil {
+			v, ok := rs.samples.aggregate(rule.UserId, cnd, now)
+			if !ok {
+				return ConditionTrace{Condition: cnd}
+			}
+
+			return ConditionTrace{Condition: cnd, Known: true, Value: v, Satisfied: rs.compare(rule, cnd, v)}
+		}
+
+		event, ok := state.Reading(cnd.DeviceID, cnd.Property)
+		if !ok {
+			return ConditionTrace{Condition: cnd}
+		}
+
+		t := ConditionTrace{Condition: cnd, Known: true, Value: cnd.valueOf(event)}
+		if cnd.Reset != nil {
+			t.Satisfied = rs.active.update(rule, cnd, event.Value)
+			return t
+		}
+
+		if !cnd.isSatisfied(event) {
+			return t
+		}
+
+		t.Satisfied = cnd.For == 0 || rs.pending.held(rule, cnd, now)
+		t.Pending = !t.Satisfied
+		return t
+	}
+}
+
+// compare compares the numeric value against the condition, taking into
+// account whether the hysteresis condition is active.
+func (rs *ruleService) compare(rule Rule, cnd Condition, v float64) bool {
+	if cnd.Reset != nil {
+		return rs.active.update(rule, cnd, v)
+	}
+
+	return cnd.Operator.Compare(cnd.Value, v)
+}
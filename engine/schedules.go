@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// Weekdays lists abbreviations of the days of the week used by schedules,
+// indexed by time.Weekday.
+var Weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+const minutesPerDay = 24 * 60
+
+// TimeOfDay represents minutes since midnight, which is (un)marshalled as a
+// string in the HH:MM format, e.g. "18:30".
+type TimeOfDay int
+
+// ParseTimeOfDay parses time of day from the HH:MM format. Midnight at the
+// end of the day, 24:00, is parsed as zero.
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[0]) > 2 || len(parts[1]) != 2 {
+		return 0, ErrMalformedEntity
+	}
+
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, ErrMalformedEntity
+	}
+
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, ErrMalformedEntity
+	}
+
+	return NewTimeOfDay(h, m)
+}
+
+// NewTimeOfDay creates time of day from hours and minutes. Midnight at the
+// end of the day, 24:00, is represented as zero.
+func NewTimeOfDay(h, m int) (TimeOfDay, error) {
+	if h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, ErrMalformedEntity
+	}
+
+	return TimeOfDay((h*60 + m) % minutesPerDay), nil
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return ErrMalformedEntity
+	}
+
+	v, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+
+	*t = v
+	return nil
+}
+
+// Window represents daily interval of time on the specific days of the week.
+// Window without days applies to every day. Interval ends at midnight if its
+// end is zero, and spans midnight if it ends before it starts, in which case
+// the days refer to its start.
+type Window struct {
+	Days []string  `json:"days,omitempty"`
+	From TimeOfDay `json:"from"`
+	To   TimeOfDay `json:"to"`
+}
+
+// Schedule limits the rule to the time windows. Windows are evaluated in the
+// IANA time zone, e.g. "Europe/Belgrade", or in UTC if zone is not specified.
+type Schedule struct {
+	Zone    string   `json:"zone,omitempty"`
+	Windows []Window `json:"windows"`
+}
+
+// Active checks whether the time falls within any of the schedule windows.
+// Schedules with unknown time zone are never active.
+func (s Schedule) Active(t time.Time) bool {
+	loc, err := location(s.Zone)
+	if err != nil {
+		return false
+	}
+
+	t = t.In(loc)
+	m := TimeOfDay(t.Hour()*60 + t.Minute())
+	for _, w := range s.Windows {
+		if w.contains(t.Weekday(), m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w Window) contains(day time.Weekday, m TimeOfDay) bool {
+	if w.To != 0 && w.To < w.From {
+		yesterday := (day + 6) % 7
+		return (m >= w.From && w.on(day)) || (m < w.To && w.on(yesterday))
+	}
+
+	return m >= w.From && (w.To == 0 || m < w.To) && w.on(day)
+}
+
+func (w Window) on(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if d == Weekdays[day] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isActive checks whether the rule is scheduled at the time of the event, or
+// at the current time if the event does not specify its time.
+func (rule Rule) isActive(event writer.Message, now time.Time) bool {
+	if rule.Schedule == nil {
+		return true
+	}
+
+	if event.Time > 0 {
+		now = timeOf(event)
+	}
+
+	return rule.Schedule.Active(now)
+}
+
+// timeOf converts time of the event, specified in seconds since the epoch.
+func timeOf(event writer.Message) time.Time {
+	return time.Unix(0, int64(event.Time*float64(time.Second)))
+}
+
+var locations = struct {
+	sync.RWMutex
+	cache map[string]*time.Location
+}{cache: make(map[string]*time.Location)}
+
+// location loads the time zone, caching it, since loading reads the zone
+// database.
+func location(name string) (*time.Location, error) {
+	locations.RLock()
+	loc, ok := locations.cache[name]
+	locations.RUnlock()
+	if ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Lock()
+	locations.cache[name] = loc
+	locations.Unlock()
+
+	return loc, nil
+}
+
+// ValidZone checks whether the IANA time zone is known.
+func ValidZone(name string) bool {
+	_, err := location(name)
+	return err == nil
+}
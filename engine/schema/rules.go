@@ -0,0 +1,922 @@
+// Package schema contains the wire format of rules shared by the transports,
+// together with its validation and conversion to the domain model.
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/asaskevich/govalidator"
+)
+
+const (
+	sendEmail string = engine.SendEmail
+	turnOff   string = engine.TurnOff
+	webhook   string = engine.Webhook
+	publish   string = engine.Publish
+
+	name      string = "name"
+	content   string = "content"
+	recipient string = "recipient"
+	from      string = "from"
+	to        string = "to"
+	deviceId  string = "deviceId"
+	uri       string = "url"
+	method    string = "method"
+	headers   string = "headers"
+	body      string = "body"
+	timeout   string = "timeout"
+	subject   string = "subject"
+	tmpl      string = "template"
+)
+
+var triggerModes = map[engine.TriggerMode]bool{
+	engine.Level:   true,
+	engine.Rising:  true,
+	engine.Falling: true,
+}
+
+var aggregateFunctions = map[engine.AggregateFunction]bool{
+	engine.Avg:   true,
+	engine.Min:   true,
+	engine.Max:   true,
+	engine.Count: true,
+	engine.Sum:   true,
+	engine.Delta: true,
+	engine.Rate:  true,
+}
+
+var numericOperators = map[engine.Operator]bool{
+	engine.Eq:  true,
+	engine.Neq: true,
+	engine.Lt:  true,
+	engine.Lte: true,
+	engine.Gt:  true,
+	engine.Gte: true,
+}
+
+var stringOperators = map[engine.Operator]bool{
+	engine.Eq:         true,
+	engine.Neq:        true,
+	engine.Contains:   true,
+	engine.StartsWith: true,
+	engine.EndsWith:   true,
+	engine.Matches:    true,
+}
+
+var weekdays = map[string]bool{
+	"MON": true,
+	"TUE": true,
+	"WED": true,
+	"THU": true,
+	"FRI": true,
+	"SAT": true,
+	"SUN": true,
+}
+
+var webhookMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// Rule represents rule specification received from the clients.
+type Rule struct {
+	UserId     string      `json:"userId"`
+	Name       string      `json:"name"`
+	Conditions []condition `json:"conditions"`
+	Expression *expression `json:"expression"`
+	Trigger    string      `json:"trigger"`
+	Schedule   *schedule   `json:"schedule"`
+	Throttle   *throttle   `json:"throttle"`
+	Retry      *retry      `json:"retry"`
+	Actions    []action    `json:"actions"`
+	Resolved   []action    `json:"resolved"`
+}
+
+type condition struct {
+	DeviceID  string          `json:"deviceId"`
+	Property  string          `json:"property"`
+	Aggregate *aggregate      `json:"aggregate"`
+	Operator  engine.Operator `json:"operator"`
+	Value     interface{}     `json:"value"`
+	Reset     *float64        `json:"reset"`
+	For       string          `json:"for"`
+	Missing   string          `json:"missing"`
+}
+
+type aggregate struct {
+	Function string  `json:"function"`
+	Window   string  `json:"window"`
+	Samples  int     `json:"samples"`
+	Per      string  `json:"per"`
+	Filter   *filter `json:"filter"`
+}
+
+type filter struct {
+	Operator engine.Operator `json:"operator"`
+	Value    interface{}     `json:"value"`
+}
+
+type throttle struct {
+	Cooldown string `json:"cooldown"`
+	Limit    int    `json:"limit"`
+	Window   string `json:"window"`
+}
+
+type schedule struct {
+	Zone    string   `json:"zone"`
+	Windows []window `json:"windows"`
+}
+
+type window struct {
+	Days []string `json:"days"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+}
+
+type retry struct {
+	Attempts   int    `json:"attempts"`
+	Backoff    string `json:"backoff"`
+	MaxBackoff string `json:"maxBackoff"`
+}
+
+// expression represents node of the boolean condition tree, specified either
+// as single condition, or as {"and": [...]}, {"or": [...]} or {"not": {...}}.
+type expression struct {
+	operator  engine.LogicalOperator
+	operands  []expression
+	condition *condition
+}
+
+type bounds struct {
+	from float64
+	to   float64
+}
+
+type action map[string]interface{}
+
+// FromDomain converts domain rule, e.g. the one produced by the DSL parser,
+// to the rule specification so it can be validated.
+func FromDomain(rule engine.Rule) (Rule, error) {
+	var r Rule
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, engine.ErrMalformedEntity
+	}
+	r.UserId = rule.UserId
+
+	return r, nil
+}
+
+// Validate checks that the rule specification is well-formed.
+func (r Rule) Validate() error {
+	if !govalidator.IsUUID(r.UserId) || len(r.Actions) == 0 || (len(r.Conditions) == 0 && r.Expression == nil) {
+		return engine.ErrMalformedEntity
+	}
+
+	for _, c := range r.Conditions {
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Expression != nil {
+		if err := r.Expression.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Trigger != "" && !triggerModes[engine.TriggerMode(r.Trigger)] {
+		return engine.ErrMalformedEntity
+	}
+
+	if r.Schedule != nil {
+		if err := r.Schedule.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Throttle != nil {
+		if err := r.Throttle.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Retry != nil {
+		if err := r.Retry.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range r.Actions {
+		if err := a.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range r.Resolved {
+		if err := a.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.ToDomain("").FeedsBack() {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// ToDomain converts validated rule specification to the domain rule with the
+// specified identifier.
+func (r Rule) ToDomain(id string) engine.Rule {
+	var (
+		actions    []engine.Action
+		conditions []engine.Condition
+	)
+
+	rule := engine.Rule{
+		ID:      id,
+		Name:    r.Name,
+		UserId:  r.UserId,
+		Trigger: engine.TriggerMode(r.Trigger),
+	}
+
+	for _, a := range r.Actions {
+		actions = append(actions, a.toDomain())
+	}
+
+	for _, a := range r.Resolved {
+		rule.Resolved = append(rule.Resolved, a.toDomain())
+	}
+
+	if r.Schedule != nil {
+		s := r.Schedule.toDomain()
+		rule.Schedule = &s
+	}
+
+	if r.Throttle != nil {
+		th := r.Throttle.toDomain()
+		rule.Throttle = &th
+	}
+
+	if r.Retry != nil {
+		rp := r.Retry.toDomain()
+		rule.Retry = &rp
+	}
+
+	for _, c := range r.Conditions {
+		conditions = append(conditions, c.toDomain())
+	}
+
+	rule.Actions = actions
+	rule.Conditions = conditions
+
+	if r.Expression != nil {
+		expr := r.Expression.toDomain()
+		rule.Expression = &expr
+	}
+
+	return rule
+}
+
+func (s schedule) validate() error {
+	if len(s.Windows) == 0 || !engine.ValidZone(s.Zone) {
+		return engine.ErrMalformedEntity
+	}
+
+	for _, w := range s.Windows {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s schedule) toDomain() engine.Schedule {
+	sch := engine.Schedule{Zone: s.Zone}
+	for _, w := range s.Windows {
+		sch.Windows = append(sch.Windows, w.toDomain())
+	}
+
+	return sch
+}
+
+// validate checks that the window specifies valid days, or valid non-empty
+// interval of time, or both.
+func (w window) validate() error {
+	for _, d := range w.Days {
+		if !weekdays[d] {
+			return engine.ErrMalformedEntity
+		}
+	}
+
+	var from, to engine.TimeOfDay
+	if w.From != "" {
+		f, err := engine.ParseTimeOfDay(w.From)
+		if err != nil {
+			return err
+		}
+		from = f
+	}
+
+	if w.To != "" {
+		t, err := engine.ParseTimeOfDay(w.To)
+		if err != nil {
+			return err
+		}
+		to = t
+	}
+
+	if from == to && (from != 0 || len(w.Days) == 0) {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+func (w window) toDomain() engine.Window {
+	win := engine.Window{Days: w.Days}
+	win.From, _ = engine.ParseTimeOfDay(w.From)
+	win.To, _ = engine.ParseTimeOfDay(w.To)
+
+	return win
+}
+
+func (t throttle) validate() error {
+	if t.Cooldown != "" {
+		if _, err := engine.ParseDuration(t.Cooldown); err != nil {
+			return err
+		}
+	}
+
+	window := engine.Duration(0)
+	if t.Window != "" {
+		w, err := engine.ParseDuration(t.Window)
+		if err != nil {
+			return err
+		}
+		window = w
+	}
+
+	if t.Limit < 0 || (t.Limit > 0) != (window > 0) {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+func (t throttle) toDomain() engine.Throttle {
+	th := engine.Throttle{Limit: t.Limit}
+	th.Cooldown, _ = engine.ParseDuration(t.Cooldown)
+	th.Window, _ = engine.ParseDuration(t.Window)
+
+	return th
+}
+
+func (r retry) validate() error {
+	if r.Attempts < 1 || r.Attempts > engine.MaxRetryAttempts {
+		return engine.ErrMalformedEntity
+	}
+
+	var backoff, max engine.Duration
+	if r.Backoff != "" {
+		b, err := engine.ParseDuration(r.Backoff)
+		if err != nil {
+			return err
+		}
+		backoff = b
+	}
+
+	if r.MaxBackoff != "" {
+		m, err := engine.ParseDuration(r.MaxBackoff)
+		if err != nil {
+			return err
+		}
+		max = m
+	}
+
+	if max > 0 && max < backoff {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+func (r retry) toDomain() engine.RetryPolicy {
+	rp := engine.RetryPolicy{Attempts: r.Attempts}
+	rp.Backoff, _ = engine.ParseDuration(r.Backoff)
+	rp.MaxBackoff, _ = engine.ParseDuration(r.MaxBackoff)
+
+	return rp
+}
+
+func (e *expression) UnmarshalJSON(b []byte) error {
+	op, operands, err := engine.UnmarshalGroup(b)
+	if err != nil {
+		return err
+	}
+
+	if op == "" {
+		var c condition
+		if err := json.Unmarshal(b, &c); err != nil {
+			return err
+		}
+		e.condition = &c
+		return nil
+	}
+
+	e.operator = op
+	e.operands = make([]expression, len(operands))
+	for i, raw := range operands {
+		if err := json.Unmarshal(raw, &e.operands[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e expression) validate() error {
+	if e.condition != nil {
+		return e.condition.validate()
+	}
+
+	switch e.operator {
+	case engine.And, engine.Or:
+		if len(e.operands) == 0 {
+			return engine.ErrMalformedEntity
+		}
+	case engine.Not:
+		if len(e.operands) != 1 {
+			return engine.ErrMalformedEntity
+		}
+	default:
+		return engine.ErrMalformedEntity
+	}
+
+	for _, o := range e.operands {
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e expression) toDomain() engine.Expression {
+	if e.condition != nil {
+		return engine.Leaf(e.condition.toDomain())
+	}
+
+	expr := engine.Expression{Operator: e.operator}
+	for _, o := range e.operands {
+		expr.Operands = append(expr.Operands, o.toDomain())
+	}
+
+	return expr
+}
+
+func (c condition) validate() error {
+	if !govalidator.IsUUID(c.DeviceID) || c.Property == "" {
+		return engine.ErrMalformedEntity
+	}
+
+	if c.Missing != "" {
+		return c.validateMissing()
+	}
+
+	if c.Reset != nil {
+		if v, ok := c.Value.(float64); !ok || c.For != "" || !engine.ValidReset(c.Operator, v, *c.Reset) {
+			return engine.ErrMalformedEntity
+		}
+	}
+
+	if c.Operator == engine.Undefined || validateValue(c.Value, c.Operator) != nil {
+		return engine.ErrMalformedEntity
+	}
+
+	if c.For != "" {
+		if _, err := engine.ParseDuration(c.For); err != nil {
+			return err
+		}
+	}
+
+	if c.Aggregate != nil {
+		return c.validateAggregate()
+	}
+
+	return nil
+}
+
+// validateMissing checks the condition which is satisfied once the property
+// is not reported for the specified duration, and therefore compares nothing.
+func (c condition) validateMissing() error {
+	if c.Operator != engine.Undefined || c.Value != nil || c.Reset != nil || c.For != "" || c.Aggregate != nil {
+		return engine.ErrMalformedEntity
+	}
+
+	d, err := engine.ParseDuration(c.Missing)
+	if err != nil {
+		return err
+	}
+
+	if d == 0 {
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+// validateAggregate checks that the aggregate has exactly one window, unless
+// it is delta or rate, that only the windowed aggregates filter samples,
+// and that the aggregate condition compares numbers without duration.
+func (c condition) validateAggregate() error {
+	a := c.Aggregate
+	if !aggregateFunctions[engine.AggregateFunction(a.Function)] || a.Samples < 0 || c.For != "" {
+		return engine.ErrMalformedEntity
+	}
+
+	if _, ok := c.Value.(bool); ok {
+		return engine.ErrMalformedEntity
+	}
+
+	if _, ok := c.Value.(string); ok {
+		return engine.ErrMalformedEntity
+	}
+
+	if _, ok := c.Value.([]interface{}); ok {
+		return engine.ErrMalformedEntity
+	}
+
+	var window, per engine.Duration
+	if a.Window != "" {
+		w, err := engine.ParseDuration(a.Window)
+		if err != nil {
+			return err
+		}
+		window = w
+	}
+
+	if a.Per != "" {
+		p, err := engine.ParseDuration(a.Per)
+		if err != nil {
+			return err
+		}
+		per = p
+	}
+
+	switch engine.AggregateFunction(a.Function) {
+	case engine.Delta:
+		if window > 0 || a.Samples > 0 || per > 0 {
+			return engine.ErrMalformedEntity
+		}
+	case engine.Rate:
+		if per == 0 || a.Samples > 0 {
+			return engine.ErrMalformedEntity
+		}
+	default:
+		if per > 0 || (window > 0) == (a.Samples > 0) {
+			return engine.ErrMalformedEntity
+		}
+	}
+
+	if a.Filter != nil {
+		return a.Filter.validate(engine.AggregateFunction(a.Function))
+	}
+
+	return nil
+}
+
+// validate checks that the filter compares boolean, number or string, which
+// can not be matched against the pattern.
+func (f filter) validate(fn engine.AggregateFunction) error {
+	if fn == engine.Delta || fn == engine.Rate || f.Operator == engine.Matches {
+		return engine.ErrMalformedEntity
+	}
+
+	switch f.Value.(type) {
+	case bool, float64, string:
+		return validateValue(f.Value, f.Operator)
+	}
+
+	return engine.ErrMalformedEntity
+}
+
+func validateValue(v interface{}, op engine.Operator) error {
+	switch v.(type) {
+	case bool:
+		if op != engine.Eq && op != engine.Neq {
+			return engine.ErrMalformedEntity
+		}
+	case string:
+		if !stringOperators[op] {
+			return engine.ErrMalformedEntity
+		}
+		if op == engine.Matches {
+			if _, err := engine.CompilePattern(v.(string)); err != nil {
+				return engine.ErrMalformedEntity
+			}
+		}
+	case float64:
+		if !numericOperators[op] {
+			return engine.ErrMalformedEntity
+		}
+	case []interface{}:
+		if _, err := convertSet(v.([]interface{})); err != nil || (op != engine.In && op != engine.NotIn) {
+			return engine.ErrMalformedEntity
+		}
+	case map[string]interface{}:
+		if op != engine.Btw {
+			return engine.ErrMalformedEntity
+		}
+		bounds, err := convertBounds(v.(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		if bounds.from >= bounds.to {
+			return engine.ErrMalformedEntity
+		}
+	default:
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+func (c condition) toDomain() engine.Condition {
+	cnd := engine.Condition{
+		DeviceID: c.DeviceID,
+		Property: c.Property,
+		Operator: c.Operator,
+		Value:    c.Value,
+		Reset:    c.Reset,
+	}
+
+	if c.For != "" {
+		cnd.For, _ = engine.ParseDuration(c.For)
+	}
+
+	if c.Missing != "" {
+		cnd.Missing, _ = engine.ParseDuration(c.Missing)
+	}
+
+	if c.Aggregate != nil {
+		a := engine.Aggregate{Function: engine.AggregateFunction(c.Aggregate.Function), Samples: c.Aggregate.Samples}
+		a.Window, _ = engine.ParseDuration(c.Aggregate.Window)
+		a.Per, _ = engine.ParseDuration(c.Aggregate.Per)
+		if f := c.Aggregate.Filter; f != nil {
+			a.Filter = &engine.Filter{Operator: f.Operator, Value: f.Value}
+		}
+		cnd.Aggregate = &a
+	}
+
+	switch c.Value.(type) {
+	case bool:
+		cnd.Type = engine.Bool
+	case float64:
+		cnd.Type = engine.Numeric
+	case string:
+		cnd.Type = engine.String
+		if c.Operator == engine.Matches {
+			cnd.Value, _ = engine.CompilePattern(c.Value.(string))
+		}
+	case []interface{}:
+		cnd.Type = engine.Set
+		cnd.Value, _ = convertSet(c.Value.([]interface{}))
+	case map[string]interface{}:
+		cnd.Type = engine.Between
+		bounds, _ := convertBounds(c.Value.(map[string]interface{}))
+		cnd.Value = engine.Range{
+			From: bounds.from,
+			To:   bounds.to,
+		}
+	}
+
+	return cnd
+}
+
+func (a action) validate() error {
+	name, ok := a[name]
+
+	if !ok {
+		return engine.ErrMalformedEntity
+	}
+
+	switch name {
+	case sendEmail:
+		if _, err := requireStrProp(a, content); err != nil {
+			return err
+		}
+		if _, err := requireStrProp(a, recipient); err != nil {
+			return err
+		}
+	case turnOff:
+		if id, err := requireStrProp(a, deviceId); err != nil || !govalidator.IsUUID(*id) {
+			return engine.ErrMalformedEntity
+		}
+	case webhook:
+		return a.validateWebhook()
+	case publish:
+		if s, err := requireStrProp(a, subject); err != nil || !validSubject(*s) {
+			return engine.ErrMalformedEntity
+		}
+		if t, ok := a[tmpl]; ok {
+			s, ok := t.(string)
+			if !ok {
+				return engine.ErrMalformedEntity
+			}
+			if _, err := engine.ParseTemplate(s); err != nil {
+				return err
+			}
+		}
+	default:
+		return engine.ErrMalformedEntity
+	}
+
+	return nil
+}
+
+func (a action) validateWebhook() error {
+	u, err := requireStrProp(a, uri)
+	if err != nil {
+		return err
+	}
+
+	if parsed, err := url.Parse(*u); err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return engine.ErrMalformedEntity
+	}
+
+	if m, ok := a[method]; ok {
+		if s, ok := m.(string); !ok || !webhookMethods[s] {
+			return engine.ErrMalformedEntity
+		}
+	}
+
+	if h, ok := a[headers]; ok {
+		if _, err := convertHeaders(h); err != nil {
+			return err
+		}
+	}
+
+	if b, ok := a[body]; ok {
+		s, ok := b.(string)
+		if !ok {
+			return engine.ErrMalformedEntity
+		}
+		if _, err := engine.ParseTemplate(s); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := a[timeout]; ok {
+		s, ok := t.(string)
+		if !ok {
+			return engine.ErrMalformedEntity
+		}
+		if _, err := engine.ParseDuration(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a action) toDomain() engine.Action {
+	switch a[name] {
+	case sendEmail:
+		return engine.SendEmailAction{
+			Name:      sendEmail,
+			Recipient: a[recipient].(string),
+			Content:   a[content].(string),
+		}
+	case turnOff:
+		return engine.TurnOffAction{
+			Name:     turnOff,
+			DeviceId: a[deviceId].(string),
+		}
+	case webhook:
+		return a.toWebhook()
+	case publish:
+		p := engine.PublishAction{
+			Name:    publish,
+			Subject: a[subject].(string),
+		}
+		if t, ok := a[tmpl].(string); ok {
+			p.Template = t
+		}
+		return p
+	}
+
+	return nil
+}
+
+func (a action) toWebhook() engine.WebhookAction {
+	wh := engine.WebhookAction{
+		Name:   webhook,
+		URL:    a[uri].(string),
+		Method: http.MethodPost,
+	}
+
+	if m, ok := a[method].(string); ok {
+		wh.Method = m
+	}
+
+	if h, ok := a[headers]; ok {
+		wh.Headers, _ = convertHeaders(h)
+	}
+
+	if b, ok := a[body].(string); ok {
+		wh.Body = b
+	}
+
+	if t, ok := a[timeout].(string); ok {
+		wh.Timeout, _ = engine.ParseDuration(t)
+	}
+
+	return wh
+}
+
+func convertBounds(object map[string]interface{}) (*bounds, error) {
+	if fp, ok := object[from]; ok {
+		if from, ok := fp.(float64); ok {
+			if tp, ok := object[to]; ok {
+				if to, ok := tp.(float64); ok {
+					return &bounds{from, to}, nil
+				}
+			}
+		}
+	}
+
+	return nil, engine.ErrMalformedEntity
+}
+
+// convertSet converts the non-empty set of strings.
+func convertSet(items []interface{}) ([]string, error) {
+	if len(items) == 0 {
+		return nil, engine.ErrMalformedEntity
+	}
+
+	set := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, engine.ErrMalformedEntity
+		}
+		set[i] = s
+	}
+
+	return set, nil
+}
+
+func convertHeaders(value interface{}) (map[string]string, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, engine.ErrMalformedEntity
+	}
+
+	hdrs := make(map[string]string)
+	for k, v := range object {
+		s, ok := v.(string)
+		if !ok || k == "" {
+			return nil, engine.ErrMalformedEntity
+		}
+		hdrs[k] = s
+	}
+
+	return hdrs, nil
+}
+
+// validSubject checks that subject consists of non-empty tokens without
+// wildcards and whitespace.
+func validSubject(s string) bool {
+	for _, token := range strings.Split(s, ".") {
+		if token == "" || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requireStrProp(object map[string]interface{}, prop string) (*string, error) {
+	if p, ok := object[prop]; ok {
+		if sp, ok := p.(string); ok && sp != "" {
+			return &sp, nil
+		}
+	}
+
+	return nil, engine.ErrMalformedEntity
+}
@@ -0,0 +1,286 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+	"fmt"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/gocql/gocql"
+)
+
+var (
+	uuid              = gocql.TimeUUID().String()
+	validAction       = action{name: sendEmail, content: "test", recipient: "test"}
+	validCondition    = condition{DeviceID: uuid, Property: "active", Operator: engine.Eq, Value: true}
+	otherCondition    = condition{DeviceID: gocql.TimeUUID().String(), Property: "active", Operator: engine.Eq, Value: true}
+	invalidAction     = action{name: sendEmail, content: "", recipient: "test"}
+	invalidCondition  = condition{DeviceID: uuid, Property: "active", Operator: engine.Gt, Value: true}
+	validExpression   = expression{operator: engine.Or, operands: []expression{{condition: &validCondition}, {condition: &validCondition}}}
+	invalidExpression = expression{operator: engine.Not, operands: []expression{{condition: &validCondition}, {condition: &validCondition}}}
+)
+
+func TestValidateRule(t *testing.T) {
+	cases := []struct {
+		r   Rule
+		err error
+	}{
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "test", Conditions: []condition{validCondition}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: "test", Name: "", Conditions: []condition{validCondition}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition, invalidCondition}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{validAction, invalidAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Expression: &validExpression, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Expression: &validExpression, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Expression: &invalidExpression, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Trigger: "rising", Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Trigger: "edge", Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{validAction}, Resolved: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{validAction}, Resolved: []action{invalidAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Cooldown: "5m"}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Limit: 10, Window: "1h"}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Cooldown: "5 min"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Limit: 10}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Window: "1h"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Throttle: &throttle{Limit: -1, Window: "1h"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: 3, Backoff: "1s", MaxBackoff: "1m"}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: 1}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: 0, Backoff: "1s"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: engine.MaxRetryAttempts}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: engine.MaxRetryAttempts + 1}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: 3, Backoff: "1 sec"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Retry: &retry{Attempts: 3, Backoff: "1m", MaxBackoff: "1s"}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Zone: "Europe/Belgrade", Windows: []window{{Days: []string{"MON", "FRI"}, From: "18:00", To: "08:00"}, {Days: []string{"SAT"}}}}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{{From: "22:00", To: "24:00"}}}, Actions: []action{validAction}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Zone: "Mars/Olympus", Windows: []window{{Days: []string{"SAT"}}}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{{Days: []string{"Saturday"}}}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{{From: "8am", To: "16:00"}}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{{From: "08:00", To: "08:00"}}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Schedule: &schedule{Windows: []window{{}}}, Actions: []action{validAction}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{{name: publish, subject: "alarms"}}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{otherCondition}, Actions: []action{{name: publish, subject: "msg.alarms"}}}, nil},
+		{Rule{UserId: uuid, Name: "", Conditions: []condition{validCondition}, Actions: []action{{name: publish, subject: "msg.alarms"}}}, engine.ErrMalformedEntity},
+		{Rule{UserId: uuid, Name: "", Expression: &validExpression, Actions: []action{validAction}, Resolved: []action{{name: publish, subject: "msg.alarms"}}}, engine.ErrMalformedEntity},
+	}
+
+	for i, tc := range cases {
+		err := tc.r.Validate()
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+	}
+
+}
+
+func TestValidateCondition(t *testing.T) {
+	reset := float64(27)
+	cases := []struct {
+		cnd condition
+		err error
+	}{
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Eq, Value: true}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Gt, Value: float64(30), For: "5m"}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Gt, Value: float64(30), For: "5 minutes"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Gt, Value: float64(30), For: "-5m"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Gt, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.Eq, Value: "test"}, nil},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: "test"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Neq, Value: float64(5)}, nil},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: map[string]interface{}{from: float64(5), to: float64(10)}}, nil},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: map[string]interface{}{from: "5", to: "10"}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: map[string]interface{}{from: float64(10), to: float64(5)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Btw, Value: map[string]interface{}{from: float64(10), to: float64(10)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Gt, Value: map[string]interface{}{from: float64(5), to: float64(10)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.Eq, Value: map[string]interface{}{from: float64(5), to: float64(10)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.In, Value: map[string]interface{}{from: float64(5), to: float64(10)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: "invalid", Property: "active", Operator: engine.Eq, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "", Operator: engine.Eq, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: "", Property: "test", Operator: engine.Eq, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Window: "10m"}, Operator: engine.Gt, Value: float64(28)}, nil},
+		{condition{DeviceID: uuid, Property: "open", Aggregate: &aggregate{Function: "count", Window: "1h"}, Operator: engine.Gte, Value: float64(5)}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "max", Samples: 5}, Operator: engine.Btw, Value: map[string]interface{}{from: float64(5), to: float64(10)}}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "median", Window: "10m"}, Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg"}, Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Window: "10m", Samples: 5}, Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Samples: -5}, Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Window: "10 min"}, Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Window: "10m"}, Operator: engine.Gt, Value: float64(28), For: "5m"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Aggregate: &aggregate{Function: "count", Window: "10m"}, Operator: engine.Eq, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "open", Aggregate: &aggregate{Function: "count", Window: "1h", Filter: &filter{engine.Eq, true}}, Operator: engine.Gte, Value: float64(5)}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Samples: 5, Filter: &filter{engine.Lt, float64(0)}}, Operator: engine.Gt, Value: float64(28)}, nil},
+		{condition{DeviceID: uuid, Property: "mode", Aggregate: &aggregate{Function: "count", Window: "1h", Filter: &filter{engine.StartsWith, "auto"}}, Operator: engine.Gte, Value: float64(5)}, nil},
+		{condition{DeviceID: uuid, Property: "open", Aggregate: &aggregate{Function: "count", Window: "1h", Filter: &filter{engine.Gt, true}}, Operator: engine.Gte, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Aggregate: &aggregate{Function: "count", Window: "1h", Filter: &filter{engine.Matches, "^auto"}}, Operator: engine.Gte, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Aggregate: &aggregate{Function: "count", Window: "1h", Filter: &filter{engine.In, []interface{}{"auto"}}}, Operator: engine.Gte, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "delta", Filter: &filter{engine.Lt, float64(0)}}, Operator: engine.Gt, Value: float64(2)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "name", Aggregate: &aggregate{Function: "count", Window: "10m"}, Operator: engine.Eq, Value: "test"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "delta"}, Operator: engine.Gt, Value: float64(5)}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "delta", Samples: 2}, Operator: engine.Gt, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "delta", Per: "1m"}, Operator: engine.Gt, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "rate", Per: "1m"}, Operator: engine.Gt, Value: float64(2)}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "rate", Per: "1m", Window: "10m"}, Operator: engine.Gt, Value: float64(2)}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "rate", Window: "10m"}, Operator: engine.Gt, Value: float64(2)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "rate", Per: "1 min"}, Operator: engine.Gt, Value: float64(2)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "rate", Per: "1m", Samples: 5}, Operator: engine.Gt, Value: float64(2)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Per: "1m", Window: "10m"}, Operator: engine.Gt, Value: float64(2)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5m"}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "0s"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5 min"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5m", Operator: engine.Gt, Value: float64(28)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5m", For: "1m"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5m", Aggregate: &aggregate{Function: "delta"}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: "test", Property: "temp", Missing: "5m"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Lt, Value: float64(30), Reset: &reset}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Gte, Value: float64(25), Reset: &reset}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Gt, Value: float64(30), Reset: &reset}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Lte, Value: float64(27), Reset: &reset}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Eq, Value: float64(30), Reset: &reset}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Btw, Value: map[string]interface{}{from: float64(28), to: float64(30)}, Reset: &reset}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Lt, Value: float64(30), Reset: &reset, For: "5m"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Aggregate: &aggregate{Function: "avg", Window: "10m"}, Operator: engine.Lt, Value: float64(30), Reset: &reset}, nil},
+		{condition{DeviceID: uuid, Property: "temp", Missing: "5m", Reset: &reset}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.Contains, Value: "err"}, nil},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.StartsWith, Value: "sensor-"}, nil},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.EndsWith, Value: ".log"}, nil},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.Matches, Value: `^sensor-\d+$`}, nil},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.Matches, Value: "sensor-("}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.Contains, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "active", Operator: engine.StartsWith, Value: true}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "name", Operator: engine.Lt, Value: "a"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Value: []interface{}{"auto", "eco"}}, nil},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.NotIn, Value: []interface{}{"test"}}, nil},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Value: []interface{}{}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Value: []interface{}{"auto", float64(1)}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.Eq, Value: []interface{}{"auto"}}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Value: "auto"}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "temp", Operator: engine.In, Value: float64(5)}, engine.ErrMalformedEntity},
+		{condition{DeviceID: uuid, Property: "mode", Aggregate: &aggregate{Function: "count", Window: "10m"}, Operator: engine.In, Value: []interface{}{"auto"}}, engine.ErrMalformedEntity},
+	}
+
+	for i, tc := range cases {
+		err := tc.cnd.validate()
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestValidateAction(t *testing.T) {
+	cases := []struct {
+		action action
+		err    error
+	}{
+		{action{name: sendEmail, content: "test", recipient: "test"}, nil},
+		{action{name: sendEmail, content: "", recipient: "test"}, engine.ErrMalformedEntity},
+		{action{name: sendEmail, content: 5, recipient: "test"}, engine.ErrMalformedEntity},
+		{action{name: sendEmail, content: "test", recipient: ""}, engine.ErrMalformedEntity},
+		{action{name: "invalidName", content: "test", recipient: "test"}, engine.ErrMalformedEntity},
+		{action{"invalidProperty": "test", content: "test", recipient: "test"}, engine.ErrMalformedEntity},
+		{action{name: turnOff, deviceId: uuid}, nil},
+		{action{name: turnOff, deviceId: "test"}, engine.ErrMalformedEntity},
+		{action{name: turnOff, content: "test", recipient: "test"}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts"}, nil},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts", method: "PUT", headers: map[string]interface{}{"X-Token": "secret"}, body: `{"value": {{.Value}}}`, timeout: "5s"}, nil},
+		{action{name: webhook}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "hooks.home.com/alerts"}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "ftp://hooks.home.com/alerts"}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts", method: "CONNECT"}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts", headers: map[string]interface{}{"X-Token": 5}}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts", body: "{{.Value"}, engine.ErrMalformedEntity},
+		{action{name: webhook, uri: "https://hooks.home.com/alerts", timeout: "5 seconds"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms.kitchen"}, nil},
+		{action{name: publish, subject: "msg.alarms", tmpl: `[{"n":"alarm","vb":true}]`}, nil},
+		{action{name: publish}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms.*"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms.>"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms..kitchen"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "kitchen alarms"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms", tmpl: "{{.Value"}, engine.ErrMalformedEntity},
+		{action{name: publish, subject: "alarms", tmpl: 5}, engine.ErrMalformedEntity},
+	}
+
+	for i, tc := range cases {
+		err := tc.action.validate()
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestWebhookToDomain(t *testing.T) {
+	cases := []struct {
+		action  action
+		webhook engine.WebhookAction
+	}{
+		{
+			action{name: webhook, uri: "https://hooks.home.com/alerts"},
+			engine.WebhookAction{Name: webhook, URL: "https://hooks.home.com/alerts", Method: "POST"},
+		},
+		{
+			action{name: webhook, uri: "https://hooks.home.com/alerts", method: "PUT", headers: map[string]interface{}{"X-Token": "secret"}, body: "{}", timeout: "5s"},
+			engine.WebhookAction{
+				Name:    webhook,
+				URL:     "https://hooks.home.com/alerts",
+				Method:  "PUT",
+				Headers: map[string]string{"X-Token": "secret"},
+				Body:    "{}",
+				Timeout: engine.Duration(5 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		assert.Equal(t, tc.webhook, tc.action.toDomain(), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestConditionToDomain(t *testing.T) {
+	pattern, _ := engine.CompilePattern(`^sensor-\d+$`)
+	cases := []struct {
+		cnd    condition
+		domain engine.Condition
+	}{
+		{
+			condition{DeviceID: uuid, Property: "name", Operator: engine.StartsWith, Value: "sensor-"},
+			engine.Condition{DeviceID: uuid, Property: "name", Operator: engine.StartsWith, Type: engine.String, Value: "sensor-"},
+		},
+		{
+			condition{DeviceID: uuid, Property: "name", Operator: engine.Matches, Value: `^sensor-\d+$`},
+			engine.Condition{DeviceID: uuid, Property: "name", Operator: engine.Matches, Type: engine.String, Value: pattern},
+		},
+		{
+			condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Value: []interface{}{"auto", "eco"}},
+			engine.Condition{DeviceID: uuid, Property: "mode", Operator: engine.In, Type: engine.Set, Value: []string{"auto", "eco"}},
+		},
+	}
+
+	for i, tc := range cases {
+		assert.Equal(t, tc.domain, tc.cnd.toDomain(), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestExpressionUnmarshal(t *testing.T) {
+	leaf := fmt.Sprintf(`{"deviceId":"%s","property":"active","operator":"=","value":true}`, uuid)
+	cases := []struct {
+		json string
+		expr expression
+		err  bool
+	}{
+		{leaf, expression{condition: &validCondition}, false},
+		{fmt.Sprintf(`{"or":[%s,%s]}`, leaf, leaf), validExpression, false},
+		{fmt.Sprintf(`{"not":%s}`, leaf), expression{operator: engine.Not, operands: []expression{{condition: &validCondition}}}, false},
+		{fmt.Sprintf(`{"and":[%s],"or":[%s]}`, leaf, leaf), expression{}, true},
+		{`{"not":[]}`, expression{}, true},
+	}
+
+	for i, tc := range cases {
+		var expr expression
+		err := json.Unmarshal([]byte(tc.json), &expr)
+		assert.Equal(t, tc.err, err != nil, fmt.Sprintf("failed at %d\n", i))
+		if !tc.err {
+			assert.Equal(t, tc.expr, expr, fmt.Sprintf("failed at %d\n", i))
+		}
+	}
+}
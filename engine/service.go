@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"context"
 	"errors"
+	"time"
+
 	"github.com/mainflux/mainflux/writer"
 )
 
@@ -12,8 +15,15 @@ var (
 	// ErrMalformedEntity indicates malformed entity specification.
 	ErrMalformedEntity error = errors.New("malformed entity specification")
 
+	// ErrUnsupportedContentType indicates unacceptable or lack of Content-Type.
+	ErrUnsupportedContentType error = errors.New("unsupported content type")
+
 	// ErrNotFound indicates a non-existent entity request.
 	ErrNotFound error = errors.New("non-existent entity")
+
+	// ErrUnsupportedAction indicates that there is no executor registered
+	// for the action type.
+	ErrUnsupportedAction error = errors.New("unsupported action")
 )
 
 // Service specifies an API that must be fulfilled by domain service implementation.
@@ -21,18 +31,82 @@ type Service interface {
 	// Save specific rule.
 	SaveRule(Rule) error
 
+	// UpdateRule replaces existing rule identified by the user's unique identifier
+	// and rule's unique identifier. Evaluation state of the rule is reset, while
+	// the rule stays enabled or disabled.
+	UpdateRule(Rule) error
+
 	// ViewRule retrieves specific rule using unique identifiers of user and rule.
 	ViewRule(string, string) (*Rule, error)
 
 	// ListRules retrieves data about all rules that belongs to specific user
-	// identified by user unique identifier.
-	ListRules(string) ([]Rule, error)
+	// identified by user unique identifier, and satisfy the filter.
+	ListRules(string, RuleFilter) ([]Rule, error)
+
+	// EnableRule enables specific rule identified by the user's unique identifier
+	// and rule's unique identifier, so that it is applied to the events again.
+	EnableRule(string, string) error
+
+	// DisableRule disables specific rule identified by the user's unique identifier
+	// and rule's unique identifier, so that it is kept, but not applied to the
+	// events. Evaluation state of the rule is reset.
+	DisableRule(string, string) error
 
 	// RemoveRule removes specific rule identified by the user's unique identifier
 	// and rule's unique identifier.
 	RemoveRule(string, string) error
 
-	// ApplyRules checks which events satisfy which rules and execute related actions
-	// for satisfied rules.
-	ApplyRules(userId string, events []writer.Message) error
+	// InvalidateRules drops rules of the specific user kept in memory, after
+	// they were changed by another instance of the service.
+	InvalidateRules(string)
+
+	// ApplyRules records events as the last known readings of the device properties
+	// and evaluates rules referring to them against these readings, executing related
+	// actions for satisfied rules. All actions of satisfied rules are executed, while the
+	// first action failure is reported as a non-nil error. Execution stops once
+	// the context is cancelled. If the service uses a dispatcher, actions are only
+	// queued for execution and their failures are reported by the dispatcher.
+	ApplyRules(ctx context.Context, userId string, events []writer.Message) error
+
+	// CheckMissing evaluates rules with missing data conditions at the current time,
+	// executing related actions for satisfied rules the same way as ApplyRules. It
+	// is meant to be called periodically, since devices which stop reporting do not
+	// trigger evaluation of the rules. Rules of all users which detect missing data
+	// are loaded on the first check, so that they are watched after a restart too.
+	// If the service uses a lease, rules are checked only while it holds the lease.
+	CheckMissing(ctx context.Context) error
+
+	// MarkSeen records that the user's device property identified by the device's
+	// unique identifier and the property name was reported at the specified moment,
+	// so that the readings received by another instance of the service are taken
+	// into account while checking rules for missing data.
+	MarkSeen(userId, deviceId, property string, at time.Time)
+
+	// CheckPending evaluates rules whose conditions with duration have been
+	// satisfied for their duration by now, executing related actions for
+	// satisfied rules the same way as ApplyRules. It is meant to be called
+	// periodically, since devices which report only on change do not trigger
+	// evaluation of the rules once the duration elapses. Each rule is checked
+	// once per elapsed duration.
+	CheckPending(ctx context.Context) error
+
+	// SimulateRule evaluates rule against the events in isolation, explaining
+	// the evaluation triggered by each event without performing any actions.
+	// Events are considered received at their time, if specified.
+	SimulateRule(rule Rule, events []writer.Message) ([]Trace, error)
+
+	// ListDeadLetters retrieves actions of the specific user's rules which failed
+	// after all attempts.
+	ListDeadLetters(string) ([]DeadLetter, error)
+
+	// ListExecutions retrieves the page of executions of the specific rule,
+	// identified by the user's unique identifier and rule's unique identifier,
+	// which happened within the time range of the filter. Executions are kept
+	// even after the rule is removed, until they expire.
+	ListExecutions(string, string, ExecutionFilter) (ExecutionPage, error)
+
+	// ReplayDeadLetter executes the failed action again, identified by the user's
+	// unique identifier and dead letter's unique identifier. Dead letter is removed
+	// once the action is executed, or queued for execution by the dispatcher.
+	ReplayDeadLetter(context.Context, string, string) error
 }
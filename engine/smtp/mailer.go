@@ -0,0 +1,189 @@
+// Package smtp contains SMTP based implementation of the rules engine mailer.
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+)
+
+const (
+	// PlainBody marks the email content as plain text.
+	PlainBody string = "plain"
+
+	// HTMLBody marks the email content as HTML.
+	HTMLBody string = "html"
+
+	plainText string = "text/plain; charset=UTF-8"
+	html      string = "text/html; charset=UTF-8"
+)
+
+// ErrAuthUnsupported indicates that credentials are configured, but the
+// SMTP server does not support authentication.
+var ErrAuthUnsupported error = errors.New("smtp server doesn't support authentication")
+
+var _ engine.Mailer = (*mailer)(nil)
+
+// Config contains settings used for connecting to the SMTP server.
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Subject  string
+
+	// Body is the type of the email content, either PlainBody or HTMLBody.
+	// Content is sent as plain text unless it is specified as HTML.
+	Body string
+}
+
+type mailer struct {
+	cfg  Config
+	addr string
+	auth smtp.Auth
+}
+
+// NewMailer instantiates mailer which delivers emails through the configured
+// SMTP server. The connection is upgraded using STARTTLS whenever the server
+// supports it, and authentication is performed if username is provided.
+func NewMailer(cfg Config) engine.Mailer {
+	m := &mailer{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, cfg.Port),
+	}
+
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return m
+}
+
+func (m *mailer) Send(ctx context.Context, recipient, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// Interrupt the conversation with the server once the context is done,
+	// since the SMTP client does not respect the context itself.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return ErrAuthUnsupported
+		}
+
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(m.cfg.From); err != nil {
+		return err
+	}
+
+	if err := c.Rcpt(recipient); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(m.message(recipient, content)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// client represents SMTP client along with its underlying connection.
+type client struct {
+	*smtp.Client
+	conn net.Conn
+}
+
+// dial connects to the SMTP server, limiting the duration of the connection
+// by the context deadline, if any.
+func (m *mailer) dial(ctx context.Context) (*client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &client{c, conn}, nil
+}
+
+// message composes the email, encoding its content as quoted-printable, so
+// that non-ASCII characters and long lines are delivered intact.
+func (m *mailer) message(recipient, content string) []byte {
+	contentType := plainText
+	if m.cfg.Body == HTMLBody {
+		contentType = html
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.cfg.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n")
+	fmt.Fprintf(&msg, "\r\n")
+
+	w := quotedprintable.NewWriter(&msg)
+	w.Write([]byte(content))
+	w.Close()
+	fmt.Fprintf(&msg, "\r\n")
+
+	return msg.Bytes()
+}
@@ -0,0 +1,212 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	from      = "rules@mainflux.com"
+	recipient = "person01@home.com"
+	rejected  = "rejected@home.com"
+)
+
+type delivery struct {
+	from      string
+	recipient string
+	data      string
+}
+
+// serve runs minimal SMTP server which accepts single connection and
+// reports the received email.
+func serve(t *testing.T, deliveries chan<- delivery) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SMTP server: %s", err)
+	}
+
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var d delivery
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("220 localhost ESMTP")
+
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO":
+				tc.PrintfLine("250-localhost")
+				tc.PrintfLine("250 8BITMIME")
+			case "MAIL":
+				d.from = address(line)
+				tc.PrintfLine("250 OK")
+			case "RCPT":
+				d.recipient = address(line)
+				if d.recipient == rejected {
+					tc.PrintfLine("550 mailbox unavailable")
+					continue
+				}
+				tc.PrintfLine("250 OK")
+			case "DATA":
+				tc.PrintfLine("354 send data")
+				lines, err := tc.ReadDotLines()
+				if err != nil {
+					return
+				}
+				d.data = strings.Join(lines, "\n")
+				tc.PrintfLine("250 OK")
+				deliveries <- d
+			case "QUIT":
+				tc.PrintfLine("221 bye")
+				return
+			default:
+				tc.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func address(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return line[start+1 : end]
+}
+
+// body decodes the quoted-printable content of the received email.
+func body(data string) string {
+	parts := strings.SplitN(data, "\n\n", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	content, _ := ioutil.ReadAll(quotedprintable.NewReader(strings.NewReader(parts[1])))
+	return strings.TrimRight(string(content), "\r\n")
+}
+
+func TestSend(t *testing.T) {
+	long := strings.Repeat("Temperature in the living room is 35 °C. ", 5)
+
+	cases := []struct {
+		recipient   string
+		body        string
+		content     string
+		contentType string
+		delivered   bool
+	}{
+		{recipient, "", "High temperature in living room!", plainText, true},
+		{recipient, PlainBody, "Temperature is <b>35</b> and rising, a < b", plainText, true},
+		{recipient, HTMLBody, "<b>Alert!</b><br><p>High temperature in living room!</p>", html, true},
+		{recipient, PlainBody, long, plainText, true},
+		{rejected, "", "High temperature in living room!", plainText, false},
+	}
+
+	for i, tc := range cases {
+		deliveries := make(chan delivery, 1)
+		host, port, _ := net.SplitHostPort(serve(t, deliveries))
+
+		m := NewMailer(Config{Host: host, Port: port, From: from, Subject: "Alert", Body: tc.body})
+		err := m.Send(context.Background(), tc.recipient, tc.content)
+
+		if !tc.delivered {
+			assert.NotNil(t, err, fmt.Sprintf("failed at %d\n", i))
+			continue
+		}
+
+		assert.Nil(t, err, fmt.Sprintf("failed at %d\n", i))
+
+		d := <-deliveries
+		assert.Equal(t, from, d.from, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.recipient, d.recipient, fmt.Sprintf("failed at %d\n", i))
+		assert.Contains(t, d.data, fmt.Sprintf("Content-Type: %s", tc.contentType), fmt.Sprintf("failed at %d\n", i))
+		assert.Contains(t, d.data, "Content-Transfer-Encoding: quoted-printable", fmt.Sprintf("failed at %d\n", i))
+		assert.Contains(t, d.data, "Subject: Alert", fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.content, body(d.data), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestSendWithoutAuthSupport(t *testing.T) {
+	host, port, _ := net.SplitHostPort(serve(t, make(chan delivery, 1)))
+
+	m := NewMailer(Config{Host: host, Port: port, Username: "user", Password: "pass", From: from})
+	err := m.Send(context.Background(), recipient, "content")
+
+	assert.Equal(t, ErrAuthUnsupported, err, "expected authentication error")
+}
+
+func TestSendUnreachableServer(t *testing.T) {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	m := NewMailer(Config{Host: host, Port: port, From: from})
+	err := m.Send(context.Background(), recipient, "content")
+
+	assert.NotNil(t, err, "expected connection error")
+}
+
+func TestSendCanceled(t *testing.T) {
+	deliveries := make(chan delivery, 1)
+	host, port, _ := net.SplitHostPort(serve(t, deliveries))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewMailer(Config{Host: host, Port: port, From: from})
+	err := m.Send(ctx, recipient, "content")
+
+	assert.Equal(t, context.Canceled, err, "expected context error")
+	assert.Empty(t, deliveries, "expected email not to be delivered")
+}
+
+func TestSendTimeout(t *testing.T) {
+	// Server accepts the connection, but never greets the client.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "unexpected error")
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(time.Second)
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	m := NewMailer(Config{Host: host, Port: port, From: from})
+	start := time.Now()
+	err = m.Send(ctx, recipient, "content")
+
+	assert.NotNil(t, err, "expected timeout error")
+	assert.True(t, time.Since(start) < time.Second, "expected send to stop once the context is done")
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+// State provides the last known readings of device properties.
+type State interface {
+	// Reading retrieves the last known reading of the device property.
+	Reading(deviceId, property string) (writer.Message, bool)
+}
+
+type readingKey struct {
+	deviceId string
+	property string
+}
+
+type reading struct {
+	event    writer.Message
+	received time.Time
+}
+
+// stateStore keeps the last reading of each device property per user, along
+// with the moment at which the property was last reported, which may be
+// reported by another instance of the service as well. Readings themselves
+// are kept only by the instance which received them.
+type stateStore struct {
+	mu       sync.RWMutex
+	readings map[string]map[readingKey]reading
+	reported map[string]map[readingKey]time.Time
+}
+
+func newStateStore() *stateStore {
+	return &stateStore{
+		readings: make(map[string]map[readingKey]reading),
+		reported: make(map[string]map[readingKey]time.Time),
+	}
+}
+
+func (ss *stateStore) update(userId string, event writer.Message, received time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	user, ok := ss.readings[userId]
+	if !ok {
+		user = make(map[readingKey]reading)
+		ss.readings[userId] = user
+	}
+
+	user[readingKey{event.Publisher, event.Name}] = reading{event, received}
+	ss.report(userId, readingKey{event.Publisher, event.Name}, received)
+}
+
+// mark records the moment at which the device property was reported,
+// without its reading.
+func (ss *stateStore) mark(userId, deviceId, property string, at time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.report(userId, readingKey{deviceId, property}, at)
+}
+
+// report keeps the latest of the moments at which the property was reported.
+// It needs to be called with the lock held.
+func (ss *stateStore) report(userId string, key readingKey, at time.Time) {
+	user, ok := ss.reported[userId]
+	if !ok {
+		user = make(map[readingKey]time.Time)
+		ss.reported[userId] = user
+	}
+
+	if last, ok := user[key]; !ok || at.After(last) {
+		user[key] = at
+	}
+}
+
+// seen retrieves the moment at which the device property was last reported.
+func (ss *stateStore) seen(userId, deviceId, property string) (time.Time, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	at, ok := ss.reported[userId][readingKey{deviceId, property}]
+	return at, ok
+}
+
+// snapshot creates view of the user's readings which ignores readings
+// older than max age, unless max age is zero.
+func (ss *stateStore) snapshot(userId string, now time.Time, maxAge time.Duration) State {
+	return &userState{ss, userId, now, maxAge}
+}
+
+var _ State = (*userState)(nil)
+
+type userState struct {
+	store  *stateStore
+	userId string
+	now    time.Time
+	maxAge time.Duration
+}
+
+func (us *userState) Reading(deviceId, property string) (writer.Message, bool) {
+	us.store.mu.RLock()
+	defer us.store.mu.RUnlock()
+
+	r, ok := us.store.readings[us.userId][readingKey{deviceId, property}]
+	if !ok || (us.maxAge > 0 && us.now.Sub(r.received) > us.maxAge) {
+		return writer.Message{}, false
+	}
+
+	return r.event, true
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+
+	"github.com/mainflux/mainflux/writer"
+)
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// TemplateData represents data about the rule firing which is available
+// to the action templates.
+type TemplateData struct {
+	RuleID    string      `json:"ruleId"`
+	RuleName  string      `json:"ruleName,omitempty"`
+	UserID    string      `json:"userId"`
+	Publisher string      `json:"publisher"`
+	Property  string      `json:"property"`
+	Value     interface{} `json:"value"`
+	Unit      string      `json:"unit,omitempty"`
+	Time      float64     `json:"time,omitempty"`
+}
+
+// NewTemplateData creates template data describing the event which
+// satisfied the rule.
+func NewTemplateData(rule Rule, event writer.Message) TemplateData {
+	return TemplateData{
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		UserID:    rule.UserId,
+		Publisher: event.Publisher,
+		Property:  event.Name,
+		Value:     eventValue(rule, event),
+		Unit:      event.Unit,
+		Time:      event.Time,
+	}
+}
+
+// ParseTemplate parses action template. Besides the standard functions,
+// templates can use "json" function for embedding values as JSON.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("action").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, ErrMalformedEntity
+	}
+
+	return tmpl, nil
+}
+
+// RenderTemplate renders action template using the specified data.
+func RenderTemplate(text string, data TemplateData) ([]byte, error) {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// eventValue resolves value of the event based on the type of the rule
+// condition referring to the event's publisher and property.
+func eventValue(rule Rule, event writer.Message) interface{} {
+	for _, cnd := range rule.AllConditions() {
+		if cnd.refersTo(event) {
+			return cnd.valueOf(event)
+		}
+	}
+
+	return event.Value
+}
@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/dsl"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRulesAggregates(t *testing.T) {
+	start := time.Now()
+	now := start
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	avg := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "avg@home.com"}
+	max := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature peaked!", Recipient: "max@home.com"}
+	count := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Door keeps opening!", Recipient: "count@home.com"}
+
+	repo.Save(engine.Rule{
+		ID:     "1",
+		UserId: "1",
+		Conditions: []engine.Condition{{
+			DeviceID:  "thermometer",
+			Property:  "temperature",
+			Aggregate: &engine.Aggregate{Function: engine.Avg, Window: engine.Duration(10 * time.Minute)},
+			Operator:  engine.Lt,
+			Type:      engine.Numeric,
+			Value:     float64(28),
+		}},
+		Actions: []engine.Action{avg},
+	})
+	repo.Save(engine.Rule{
+		ID:     "2",
+		UserId: "1",
+		Conditions: []engine.Condition{{
+			DeviceID:  "thermometer",
+			Property:  "temperature",
+			Aggregate: &engine.Aggregate{Function: engine.Max, Samples: 2},
+			Operator:  engine.Lt,
+			Type:      engine.Numeric,
+			Value:     float64(35),
+		}},
+		Actions: []engine.Action{max},
+	})
+	repo.Save(engine.Rule{
+		ID:     "3",
+		UserId: "1",
+		Conditions: []engine.Condition{{
+			DeviceID:  "door",
+			Property:  "open",
+			Aggregate: &engine.Aggregate{Function: engine.Count, Window: engine.Duration(time.Hour), Filter: &engine.Filter{Operator: engine.Eq, Value: true}},
+			Operator:  engine.Lte,
+			Type:      engine.Numeric,
+			Value:     float64(3),
+		}},
+		Actions: []engine.Action{count},
+	})
+
+	temperature := func(v float64) writer.Message {
+		return writer.Message{Publisher: "thermometer", Name: "temperature", Value: v}
+	}
+	door := writer.Message{Publisher: "door", Name: "open", BoolValue: true}
+	closed := writer.Message{Publisher: "door", Name: "open", BoolValue: false}
+
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		event   writer.Message
+		avg     int
+		max     int
+		count   int
+	}{
+		{"single sample below average", 0, temperature(27), 0, 0, 0},
+		{"average above threshold", time.Minute, temperature(30), 1, 0, 0},
+		{"peak within samples", 2 * time.Minute, temperature(40), 2, 1, 0},
+		{"peak still within samples", 3 * time.Minute, temperature(20), 3, 2, 0},
+		{"peak out of samples", 4 * time.Minute, temperature(20), 3, 2, 0},
+		{"high samples out of window", 12*time.Minute + 30*time.Second, temperature(35), 3, 2, 0},
+		{"first door opening", 13 * time.Minute, door, 3, 2, 0},
+		{"door closing", 13*time.Minute + 30*time.Second, closed, 3, 2, 0},
+		{"second door opening", 14 * time.Minute, door, 3, 2, 0},
+		{"door closing again", 14*time.Minute + 30*time.Second, closed, 3, 2, 0},
+		{"third door opening", 15 * time.Minute, door, 3, 2, 1},
+		{"fourth door opening", 16 * time.Minute, door, 3, 2, 2},
+		{"door openings out of window", 75 * time.Minute, door, 3, 2, 2},
+	}
+
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{tc.event})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.avg, len(mailer.Sent(avg.Recipient)), fmt.Sprintf("%s: unexpected number of average alerts", tc.desc))
+		assert.Equal(t, tc.max, len(mailer.Sent(max.Recipient)), fmt.Sprintf("%s: unexpected number of peak alerts", tc.desc))
+		assert.Equal(t, tc.count, len(mailer.Sent(count.Recipient)), fmt.Sprintf("%s: unexpected number of door alerts", tc.desc))
+	}
+}
+
+func TestSimulateRuleAggregates(t *testing.T) {
+	svc := engine.NewService(mocks.NewRuleRepository(), nil)
+	rule := engine.Rule{
+		Conditions: []engine.Condition{{
+			DeviceID:  "thermometer",
+			Property:  "temperature",
+			Aggregate: &engine.Aggregate{Function: engine.Sum, Window: engine.Duration(time.Minute)},
+			Operator:  engine.Lt,
+			Type:      engine.Numeric,
+			Value:     float64(50),
+		}},
+		Actions: []engine.Action{logAction{"High temperature!"}},
+	}
+
+	start := time.Now().Unix()
+	var events []writer.Message
+	for i, v := range []float64{20, 20, 20, 20} {
+		events = append(events, writer.Message{Publisher: "thermometer", Name: "temperature", Value: v, Time: float64(start + int64(i*30))})
+	}
+
+	traces, err := svc.SimulateRule(rule, events)
+	assert.Nil(t, err, "unexpected error")
+
+	// Samples older than a minute fall out of the window.
+	sums := []float64{20, 40, 40, 40}
+	for i, tr := range traces {
+		assert.Equal(t, sums[i], tr.Conditions[0].Value, fmt.Sprintf("failed at %d\n", i))
+		assert.False(t, tr.Matched, fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestApplyRulesChange(t *testing.T) {
+	start := time.Now()
+	now := start
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	jump := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature jumped!", Recipient: "delta@home.com"}
+	rise := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature rises fast!", Recipient: "rate@home.com"}
+	trend := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature keeps rising!", Recipient: "trend@home.com"}
+
+	rule := func(id string, a engine.Aggregate, value float64, action engine.Action) engine.Rule {
+		return engine.Rule{
+			ID:         id,
+			UserId:     "1",
+			Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Aggregate: &a, Operator: engine.Lt, Type: engine.Numeric, Value: value}},
+			Actions:    []engine.Action{action},
+		}
+	}
+
+	repo.Save(rule("1", engine.Aggregate{Function: engine.Delta}, 5, jump))
+	repo.Save(rule("2", engine.Aggregate{Function: engine.Rate, Per: engine.Duration(time.Minute)}, 2, rise))
+	repo.Save(rule("3", engine.Aggregate{Function: engine.Rate, Per: engine.Duration(time.Minute), Window: engine.Duration(10 * time.Minute)}, 1, trend))
+
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		value   float64
+		jump    int
+		rise    int
+		trend   int
+	}{
+		{"first reading", 0, 20, 0, 0, 0},
+		{"slow rise", 5 * time.Minute, 24, 0, 0, 0},
+		{"fast rise", 6 * time.Minute, 27, 0, 1, 1},
+		{"jump", 16 * time.Minute, 33, 1, 1, 1},
+		{"drop", 16*time.Minute + 30*time.Second, 20, 1, 1, 1},
+		{"fast rise of small delta", 17 * time.Minute, 22.5, 1, 2, 1},
+	}
+
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: tc.value}})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.jump, len(mailer.Sent(jump.Recipient)), fmt.Sprintf("%s: unexpected number of delta alerts", tc.desc))
+		assert.Equal(t, tc.rise, len(mailer.Sent(rise.Recipient)), fmt.Sprintf("%s: unexpected number of rate alerts", tc.desc))
+		assert.Equal(t, tc.trend, len(mailer.Sent(trend.Recipient)), fmt.Sprintf("%s: unexpected number of windowed rate alerts", tc.desc))
+	}
+}
+
+func TestApplyRulesDocumentedChange(t *testing.T) {
+	start := time.Now()
+	now := start
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	// Conditions from the DSL documentation, satisfied once the average
+	// temperature is above 28 and once it rises faster than 2 per minute.
+	device := "8837ffdf-2bec-42f7-9c2d-b8cfa67661a9"
+	rules, err := dsl.Parse(fmt.Sprintf(`
+		RULE avg: AVG(%s["temperature"] OVER 10m) > 28 TRIGGERS SEND EMAIL "Temperature is high!" TO "avg@home.com"
+		RULE rise: RATE(%s["temperature"] PER 1m OVER 10m) > 2 TRIGGERS SEND EMAIL "Temperature rises fast!" TO "rate@home.com"`, device, device))
+	assert.Nil(t, err, "unexpected error")
+	for i, r := range rules {
+		r.ID = fmt.Sprintf("%d", i+1)
+		r.UserId = "1"
+		repo.Save(r)
+	}
+
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		value   float64
+		avg     int
+		rise    int
+	}{
+		{"first reading", 0, 20, 0, 0},
+		{"slow rise", 5 * time.Minute, 22, 0, 0},
+		{"fast rise", 6 * time.Minute, 34, 0, 1},
+		{"high average", 7 * time.Minute, 40, 1, 2},
+	}
+
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: device, Name: "temperature", Value: tc.value}})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.avg, len(mailer.Sent("avg@home.com")), fmt.Sprintf("%s: unexpected number of average alerts", tc.desc))
+		assert.Equal(t, tc.rise, len(mailer.Sent("rate@home.com")), fmt.Sprintf("%s: unexpected number of rate alerts", tc.desc))
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+)
+
+var sizes = []int{100, 1000, 5000}
+
+// benchmarkRules creates the user's rules, each referring to a different
+// device, and the event which satisfies one of them.
+func benchmarkRules(n int) (engine.RuleRepository, writer.Message) {
+	repo := mocks.NewRuleRepository()
+	for i := 0; i < n; i++ {
+		device := "device" + strconv.Itoa(i)
+		repo.Save(engine.Rule{
+			ID:         strconv.Itoa(i),
+			UserId:     "1",
+			Conditions: []engine.Condition{{DeviceID: device, Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+			Actions:    []engine.Action{logAction{"High temperature!"}},
+		})
+	}
+
+	return repo, writer.Message{Publisher: "device" + strconv.Itoa(n/2), Name: "temp", Value: 35}
+}
+
+func BenchmarkApplyRules(b *testing.B) {
+	for _, n := range sizes {
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			repo, event := benchmarkRules(n)
+			svc := engine.NewService(repo, engine.Executors{})
+			events := []writer.Message{event}
+
+			// Rules are indexed once they are applied for the first time,
+			// which is excluded from the measurement.
+			svc.ApplyRules(context.Background(), "1", events)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				svc.ApplyRules(context.Background(), "1", events)
+			}
+		})
+	}
+}
+
+// BenchmarkScanRules measures the matching which loads all of the user's
+// rules and scans them for every event, for comparison with the index.
+func BenchmarkScanRules(b *testing.B) {
+	for _, n := range sizes {
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			repo, event := benchmarkRules(n)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, rule := range repo.All("1") {
+					if rule.RefersTo(event) {
+						rule.IsMatchedBy(event)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingExecutor executes actions once they are released, unless the
+// context is done first.
+type blockingExecutor struct {
+	mu       sync.Mutex
+	release  chan struct{}
+	executed int
+	err      error
+}
+
+func (be *blockingExecutor) Execute(ctx context.Context, _ engine.Action, _ writer.Message, _ engine.Rule) error {
+	select {
+	case <-be.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	be.mu.Lock()
+	defer be.mu.Unlock()
+	be.executed++
+
+	return be.err
+}
+
+func (be *blockingExecutor) count() int {
+	be.mu.Lock()
+	defer be.mu.Unlock()
+
+	return be.executed
+}
+
+func TestDispatcherBackpressure(t *testing.T) {
+	executor := &blockingExecutor{release: make(chan struct{})}
+	d := engine.NewDispatcher(executor, engine.DispatcherConfig{Workers: 2, QueueSize: 2})
+	action := logAction{"High temperature!"}
+
+	// Two actions are taken by the workers and two wait in the queue.
+	for i := 0; i < 4; i++ {
+		err := d.Execute(context.Background(), action, writer.Message{}, engine.Rule{})
+		assert.Nil(t, err, "unexpected error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := d.Execute(ctx, action, writer.Message{}, engine.Rule{})
+	assert.Equal(t, context.DeadlineExceeded, err, "expected full queue to block")
+
+	close(executor.release)
+	err = d.Close(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 4, executor.count(), "expected queued actions to be executed")
+
+	err = d.Execute(context.Background(), action, writer.Message{}, engine.Rule{})
+	assert.Equal(t, engine.ErrDispatcherClosed, err, "expected closed dispatcher")
+}
+
+func TestDispatcherCloseTimeout(t *testing.T) {
+	executor := &blockingExecutor{release: make(chan struct{})}
+	d := engine.NewDispatcher(executor, engine.DispatcherConfig{Workers: 1, QueueSize: 1})
+	d.Execute(context.Background(), logAction{}, writer.Message{}, engine.Rule{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := d.Close(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err, "expected draining to time out")
+
+	close(executor.release)
+	err = d.Close(context.Background())
+	assert.Nil(t, err, "unexpected error")
+}
+
+func TestDispatcherFailures(t *testing.T) {
+	deliveryErr := errors.New("delivery failed")
+
+	cases := []struct {
+		desc     string
+		executor *blockingExecutor
+		timeout  time.Duration
+		err      error
+	}{
+		{"successful action", &blockingExecutor{release: make(chan struct{})}, 0, nil},
+		{"failed action", &blockingExecutor{release: make(chan struct{}), err: deliveryErr}, 0, deliveryErr},
+		{"timed out action", &blockingExecutor{}, 10 * time.Millisecond, context.DeadlineExceeded},
+	}
+
+	for _, tc := range cases {
+		if tc.executor.release != nil {
+			close(tc.executor.release)
+		}
+
+		var (
+			mu     sync.Mutex
+			failed []error
+		)
+		d := engine.NewDispatcher(tc.executor, engine.DispatcherConfig{
+			Workers: 1,
+			Timeout: tc.timeout,
+			Failed: func(_ engine.Action, _ writer.Message, _ engine.Rule, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				failed = append(failed, err)
+			},
+		})
+
+		d.Execute(context.Background(), logAction{}, writer.Message{}, engine.Rule{})
+		d.Close(context.Background())
+
+		var errs []error
+		if tc.err != nil {
+			errs = []error{tc.err}
+		}
+		assert.Equal(t, errs, failed, tc.desc+": unexpected failures")
+	}
+}
+
+func TestApplyRulesDispatched(t *testing.T) {
+	executor := &blockingExecutor{release: make(chan struct{})}
+	d := engine.NewDispatcher(executor, engine.DispatcherConfig{Workers: 1, QueueSize: 10})
+
+	repo := mocks.NewRuleRepository()
+	svc := engine.NewService(repo, engine.Executors{}, engine.WithDispatcher(d))
+	repo.Save(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{logAction{"High temperature!"}, logAction{"Still high!"}},
+	})
+
+	event := writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}
+	err := svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	assert.Nil(t, err, "expected rules to be applied without waiting for actions")
+	assert.Equal(t, 0, executor.count(), "expected actions to be pending")
+
+	close(executor.release)
+	d.Close(context.Background())
+	assert.Equal(t, 2, executor.count(), "expected dispatched actions to be executed")
+}
+
+// flakyExecutor fails the specified number of times before succeeding. It
+// fails with the specified error, if any.
+type flakyExecutor struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	err      error
+}
+
+func (fe *flakyExecutor) Execute(_ context.Context, _ engine.Action, _ writer.Message, _ engine.Rule) error {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	fe.attempts++
+	if fe.attempts <= fe.failures {
+		if fe.err != nil {
+			return fe.err
+		}
+		return errors.New("delivery failed")
+	}
+
+	return nil
+}
+
+func (fe *flakyExecutor) count() int {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	return fe.attempts
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	fast := engine.RetryPolicy{Attempts: 3, Backoff: engine.Duration(time.Millisecond)}
+	single := engine.RetryPolicy{Attempts: 1}
+
+	cases := []struct {
+		desc     string
+		failures int
+		retry    engine.RetryPolicy
+		retries  map[string]engine.RetryPolicy
+		rule     *engine.RetryPolicy
+		attempts int
+		letters  int
+	}{
+		{"succeeded after retries", 2, fast, nil, nil, 3, 0},
+		{"failed after all attempts", 5, fast, nil, nil, 3, 1},
+		{"policy of action type", 5, fast, map[string]engine.RetryPolicy{"LOG": single}, nil, 1, 1},
+		{"policy of rule", 5, single, map[string]engine.RetryPolicy{"LOG": single}, &fast, 3, 1},
+		{"no retries", 5, engine.RetryPolicy{}, nil, nil, 1, 1},
+	}
+
+	for _, tc := range cases {
+		executor := &flakyExecutor{failures: tc.failures}
+		letters := mocks.NewDeadLetterRepository()
+		d := engine.NewDispatcher(executor, engine.DispatcherConfig{
+			Workers:     1,
+			Retry:       tc.retry,
+			Retries:     tc.retries,
+			DeadLetters: letters,
+		})
+
+		rule := engine.Rule{ID: "1", UserId: "1", Retry: tc.rule}
+		event := writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}
+		d.Execute(context.Background(), logAction{"High temperature!"}, event, rule)
+
+		deadline := time.Now().Add(time.Second)
+		for executor.count() < tc.attempts && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		d.Close(context.Background())
+
+		assert.Equal(t, tc.attempts, executor.count(), tc.desc+": unexpected number of attempts")
+
+		dls := letters.All("1")
+		assert.Equal(t, tc.letters, len(dls), tc.desc+": unexpected number of dead letters")
+		for _, dl := range dls {
+			assert.Equal(t, "1", dl.RuleId, tc.desc+": unexpected rule")
+			assert.Equal(t, logAction{"High temperature!"}, dl.Action, tc.desc+": unexpected action")
+			assert.Equal(t, event, dl.Event, tc.desc+": unexpected event")
+			assert.Equal(t, tc.attempts, dl.Attempts, tc.desc+": unexpected number of attempts")
+			assert.Equal(t, "delivery failed", dl.Error, tc.desc+": unexpected error")
+		}
+	}
+}
+
+func TestDispatcherPermanentFailures(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+	}{
+		{"permanent error", engine.Permanent(errors.New("invalid template"))},
+		{"unsupported action", engine.ErrUnsupportedAction},
+	}
+
+	for _, tc := range cases {
+		executor := &flakyExecutor{failures: 5, err: tc.err}
+		letters := mocks.NewDeadLetterRepository()
+		d := engine.NewDispatcher(executor, engine.DispatcherConfig{
+			Workers:     1,
+			Retry:       engine.RetryPolicy{Attempts: 3, Backoff: engine.Duration(time.Millisecond)},
+			DeadLetters: letters,
+		})
+
+		rule := engine.Rule{ID: "1", UserId: "1"}
+		event := writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}
+		d.Execute(context.Background(), logAction{"High temperature!"}, event, rule)
+
+		deadline := time.Now().Add(time.Second)
+		for len(letters.All("1")) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		d.Close(context.Background())
+
+		assert.Equal(t, 1, executor.count(), tc.desc+": unexpected number of attempts")
+
+		dls := letters.All("1")
+		assert.Equal(t, 1, len(dls), tc.desc+": unexpected number of dead letters")
+		for _, dl := range dls {
+			assert.Equal(t, 1, dl.Attempts, tc.desc+": unexpected number of attempts")
+			assert.Equal(t, tc.err.Error(), dl.Error, tc.desc+": unexpected error")
+		}
+	}
+}
+
+func TestDispatcherCloseRetries(t *testing.T) {
+	executor := &flakyExecutor{failures: 1}
+	letters := mocks.NewDeadLetterRepository()
+	d := engine.NewDispatcher(executor, engine.DispatcherConfig{
+		Retry:       engine.RetryPolicy{Attempts: 3, Backoff: engine.Duration(time.Hour)},
+		DeadLetters: letters,
+	})
+
+	d.Execute(context.Background(), logAction{}, writer.Message{}, engine.Rule{UserId: "1"})
+	for executor.count() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := d.Close(context.Background())
+	assert.Nil(t, err, "expected pending retry not to delay closing")
+	assert.Equal(t, 1, executor.count(), "unexpected number of attempts")
+	assert.Equal(t, 1, len(letters.All("1")), "expected pending retry to be kept as dead letter")
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	letters := mocks.NewDeadLetterRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)}, engine.WithDeadLetters(letters))
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "High temperature!", Recipient: "person01@home.com"}
+	repo.Save(engine.Rule{ID: "1", UserId: "1", Actions: []engine.Action{email}})
+	id, _ := letters.Save(engine.DeadLetter{UserId: "1", RuleId: "1", Action: email, Attempts: 3, Error: "delivery failed"})
+
+	dls, err := svc.ListDeadLetters("1")
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(dls), "unexpected number of dead letters")
+
+	deliveryErr := errors.New("delivery failed")
+	cases := []struct {
+		desc    string
+		userId  string
+		id      string
+		mailErr error
+		sent    int
+		letters int
+		err     error
+	}{
+		{"replay failure", "1", id, deliveryErr, 0, 1, deliveryErr},
+		{"replay dead letter", "1", id, nil, 1, 0, nil},
+		{"replay removed dead letter", "1", id, nil, 1, 0, engine.ErrNotFound},
+		{"replay dead letter of another user", "2", id, nil, 1, 0, engine.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		mailer.Error = tc.mailErr
+		err := svc.ReplayDeadLetter(context.Background(), tc.userId, tc.id)
+		assert.Equal(t, tc.err, err, tc.desc+": unexpected error")
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), tc.desc+": unexpected number of emails")
+		assert.Equal(t, tc.letters, len(letters.All("1")), tc.desc+": unexpected number of dead letters")
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListExecutions(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	now := start
+	repo := mocks.NewRuleRepository()
+	history := mocks.NewExecutionRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+		engine.WithExecutions(history),
+	)
+
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "alerts@home.com"}
+	resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is back to normal.", Recipient: "resolved@home.com"}
+	repo.Save(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{alert, logAction{"High temperature!"}},
+		Resolved:   []engine.Action{resolved},
+	})
+
+	deliveryErr := errors.New("delivery failed")
+	events := []struct {
+		value   float64
+		mailErr error
+	}{
+		{35, nil},
+		{36, deliveryErr},
+		{25, nil},
+		{20, nil},
+	}
+
+	for i, e := range events {
+		now = start.Add(time.Duration(i) * time.Minute)
+		mailer.Error = e.mailErr
+		svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: e.value}})
+	}
+
+	page, err := svc.ListExecutions("1", "1", engine.ExecutionFilter{Limit: 10})
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, uint64(3), page.Total, "unexpected number of executions")
+
+	resolution := page.Executions[0]
+	assert.True(t, resolution.Resolved, "expected resolution")
+	assert.Equal(t, start.Add(2*time.Minute), resolution.Time, "unexpected time of resolution")
+	assert.Equal(t, float64(25), resolution.Event.Value, "unexpected event")
+	assert.Equal(t, []engine.ActionResult{{Action: resolved, Status: engine.Succeeded}}, resolution.Actions, "unexpected outcomes")
+
+	failure := page.Executions[1]
+	assert.False(t, failure.Resolved, "unexpected resolution")
+	assert.Equal(t, []engine.ActionResult{
+		{Action: alert, Status: engine.Failed, Error: deliveryErr.Error()},
+		{Action: logAction{"High temperature!"}, Status: engine.Failed, Error: engine.ErrUnsupportedAction.Error()},
+	}, failure.Actions, "unexpected outcomes")
+	assert.Equal(t, engine.Succeeded, page.Executions[2].Actions[0].Status, "unexpected outcome")
+
+	cases := []struct {
+		desc   string
+		ruleId string
+		filter engine.ExecutionFilter
+		total  uint64
+		times  []time.Time
+	}{
+		{"all executions", "1", engine.ExecutionFilter{}, 3, []time.Time{start.Add(2 * time.Minute), start.Add(time.Minute), start}},
+		{"executions since", "1", engine.ExecutionFilter{From: start.Add(time.Minute)}, 2, []time.Time{start.Add(2 * time.Minute), start.Add(time.Minute)}},
+		{"executions until", "1", engine.ExecutionFilter{To: start.Add(time.Minute)}, 1, []time.Time{start}},
+		{"executions within range", "1", engine.ExecutionFilter{From: start.Add(time.Second), To: start.Add(2 * time.Minute)}, 1, []time.Time{start.Add(time.Minute)}},
+		{"page of executions", "1", engine.ExecutionFilter{Offset: 1, Limit: 1}, 3, []time.Time{start.Add(time.Minute)}},
+		{"page beyond executions", "1", engine.ExecutionFilter{Offset: 3, Limit: 1}, 3, []time.Time{}},
+		{"executions of another rule", "2", engine.ExecutionFilter{}, 0, []time.Time{}},
+	}
+
+	for _, tc := range cases {
+		page, err := svc.ListExecutions("1", tc.ruleId, tc.filter)
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.total, page.Total, fmt.Sprintf("%s: unexpected total", tc.desc))
+
+		times := []time.Time{}
+		for _, exec := range page.Executions {
+			times = append(times, exec.Time)
+		}
+		assert.Equal(t, tc.times, times, fmt.Sprintf("%s: unexpected executions", tc.desc))
+	}
+}
+
+func TestListExecutionsDispatched(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	history := mocks.NewExecutionRepository()
+	executor := &blockingExecutor{release: make(chan struct{}), err: errors.New("delivery failed")}
+	d := engine.NewDispatcher(executor, engine.DispatcherConfig{})
+	svc := engine.NewService(repo, nil, engine.WithDispatcher(d), engine.WithExecutions(history))
+
+	repo.Save(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{logAction{"High temperature!"}},
+	})
+
+	err := svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: 35}})
+	assert.Nil(t, err, "unexpected error")
+
+	page, _ := svc.ListExecutions("1", "1", engine.ExecutionFilter{})
+	assert.Equal(t, uint64(0), page.Total, "expected execution to be recorded once outcomes are known")
+
+	close(executor.release)
+	d.Close(context.Background())
+
+	page, _ = svc.ListExecutions("1", "1", engine.ExecutionFilter{})
+	assert.Equal(t, uint64(1), page.Total, "unexpected number of executions")
+	assert.Equal(t, []engine.ActionResult{{Action: logAction{"High temperature!"}, Status: engine.Failed, Error: "delivery failed"}}, page.Executions[0].Actions, "unexpected outcomes")
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	hot   = engine.Condition{DeviceID: "sensor", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}
+	smoke = engine.Condition{DeviceID: "sensor", Property: "smoke", Operator: engine.Eq, Type: engine.Bool, Value: true}
+	test  = engine.Condition{DeviceID: "sensor", Property: "mode", Operator: engine.Eq, Type: engine.String, Value: "test"}
+)
+
+func TestIsMatchedByExpression(t *testing.T) {
+	hotOrSmoke := engine.Expression{Operator: engine.Or, Operands: []engine.Expression{engine.Leaf(hot), engine.Leaf(smoke)}}
+	notTest := engine.Expression{Operator: engine.Not, Operands: []engine.Expression{engine.Leaf(test)}}
+
+	cases := []struct {
+		desc    string
+		rule    engine.Rule
+		event   writer.Message
+		matched bool
+	}{
+		{"first OR operand", engine.Rule{Expression: &hotOrSmoke}, writer.Message{Publisher: "sensor", Name: "temp", Value: 35}, true},
+		{"second OR operand", engine.Rule{Expression: &hotOrSmoke}, writer.Message{Publisher: "sensor", Name: "smoke", BoolValue: true}, true},
+		{"no OR operand", engine.Rule{Expression: &hotOrSmoke}, writer.Message{Publisher: "sensor", Name: "temp", Value: 20}, false},
+		{"negated condition", engine.Rule{Expression: &notTest}, writer.Message{Publisher: "sensor", Name: "mode", StringValue: "test"}, false},
+		{"negated mismatch", engine.Rule{Expression: &notTest}, writer.Message{Publisher: "sensor", Name: "mode", StringValue: "live"}, true},
+		{"flat AND expression", engine.Rule{Conditions: []engine.Condition{hot}, Expression: &notTest}, writer.Message{Publisher: "sensor", Name: "temp", Value: 35}, true},
+		{"flat conditions", engine.Rule{Conditions: []engine.Condition{hot, smoke}}, writer.Message{Publisher: "sensor", Name: "temp", Value: 35}, false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.matched, tc.rule.IsMatchedBy(tc.event), fmt.Sprintf("%s: unexpected result", tc.desc))
+	}
+}
+
+func TestExpressionMarshaling(t *testing.T) {
+	pattern, _ := engine.CompilePattern(`^t\d+$`)
+	cases := []struct {
+		json string
+		expr engine.Expression
+		err  error
+	}{
+		{
+			`{"deviceId":"sensor","property":"temp","operator":"<","value":30}`,
+			engine.Leaf(hot),
+			nil,
+		},
+		{
+			`{"or":[{"deviceId":"sensor","property":"temp","operator":"<","value":30},{"not":{"deviceId":"sensor","property":"mode","operator":"=","value":"test"}}]}`,
+			engine.Expression{Operator: engine.Or, Operands: []engine.Expression{
+				engine.Leaf(hot),
+				{Operator: engine.Not, Operands: []engine.Expression{engine.Leaf(test)}},
+			}},
+			nil,
+		},
+		{
+			`{"deviceId":"sensor","property":"temp","operator":"<","value":30,"for":"5m0s"}`,
+			engine.Leaf(engine.Condition{DeviceID: "sensor", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), For: engine.Duration(5 * time.Minute)}),
+			nil,
+		},
+		{
+			`{"deviceId":"sensor","property":"mode","operator":"NOT IN","value":["test","off"]}`,
+			engine.Leaf(engine.Condition{DeviceID: "sensor", Property: "mode", Operator: engine.NotIn, Type: engine.Set, Value: []string{"test", "off"}}),
+			nil,
+		},
+		{
+			`{"deviceId":"sensor","property":"mode","operator":"MATCHES","value":"^t\\d+$"}`,
+			engine.Leaf(engine.Condition{DeviceID: "sensor", Property: "mode", Operator: engine.Matches, Type: engine.String, Value: pattern}),
+			nil,
+		},
+		{
+			`{"and":[{"deviceId":"sensor","property":"smoke","operator":"=","value":true}]}`,
+			engine.Expression{Operator: engine.And, Operands: []engine.Expression{engine.Leaf(smoke)}},
+			nil,
+		},
+	}
+
+	for i, tc := range cases {
+		data, err := json.Marshal(tc.expr)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		assert.JSONEq(t, tc.json, string(data), fmt.Sprintf("failed at %d\n", i))
+
+		var expr engine.Expression
+		err = json.Unmarshal([]byte(tc.json), &expr)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.expr, expr, fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestExpressionUnmarshalingErrors(t *testing.T) {
+	cases := []string{
+		`{"and":[],"or":[]}`,
+		`{"deviceId":"sensor","property":"temp","operator":"invalid","value":30}`,
+		`{"deviceId":"sensor","property":"temp","operator":"BETWEEN","value":{"from":"a"}}`,
+		`{"not":[]}`,
+		`{"deviceId":"sensor","property":"mode","operator":"IN","value":["test",1]}`,
+		`{"deviceId":"sensor","property":"mode","operator":"MATCHES","value":"("}`,
+	}
+
+	for i, tc := range cases {
+		var expr engine.Expression
+		err := json.Unmarshal([]byte(tc), &expr)
+		assert.NotNil(t, err, fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestExplain(t *testing.T) {
+	rule := engine.Rule{
+		Conditions: []engine.Condition{hot},
+		Expression: &engine.Expression{Operator: engine.Or, Operands: []engine.Expression{engine.Leaf(hot), engine.Leaf(smoke)}},
+	}
+
+	matched, traces := rule.Explain(writer.Message{Publisher: "sensor", Name: "temp", Value: 35})
+	assert.True(t, matched, "expected rule to be matched")
+	assert.Equal(t, []engine.ConditionTrace{
+		{Condition: hot, Known: true, Value: float64(35), Satisfied: true},
+		{Condition: smoke},
+	}, traces, "unexpected condition traces")
+}
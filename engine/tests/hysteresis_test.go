@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/dsl"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRulesHysteresis(t *testing.T) {
+	start := time.Now()
+	now := start
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "alerts@home.com"}
+	resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is back to normal.", Recipient: "resolved@home.com"}
+	avg := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Average temperature is high!", Recipient: "avg@home.com"}
+
+	reset, avgReset := float64(27), float64(28)
+	repo.Save(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), Reset: &reset}},
+		Actions:    []engine.Action{alert},
+		Resolved:   []engine.Action{resolved},
+	})
+	repo.Save(engine.Rule{
+		ID:     "2",
+		UserId: "1",
+		Conditions: []engine.Condition{{
+			DeviceID:  "thermometer",
+			Property:  "temperature",
+			Aggregate: &engine.Aggregate{Function: engine.Avg, Samples: 2},
+			Operator:  engine.Lt,
+			Type:      engine.Numeric,
+			Value:     float64(29.5),
+			Reset:     &avgReset,
+		}},
+		Trigger: engine.Rising,
+		Actions: []engine.Action{avg},
+	})
+
+	cases := []struct {
+		desc     string
+		value    float64
+		alerts   int
+		resolved int
+		avg      int
+	}{
+		{"below value", 25, 0, 0, 0},
+		{"above value", 31, 1, 0, 0},
+		{"below value above reset", 29, 2, 0, 1},
+		{"hovering around value", 30.5, 3, 0, 1},
+		{"still above reset", 28, 4, 0, 1},
+		{"at reset", 27, 4, 1, 1},
+		{"below value after reset", 29, 4, 1, 1},
+		{"above value again", 32, 5, 1, 2},
+	}
+
+	for i, tc := range cases {
+		now = start.Add(time.Duration(i) * time.Minute)
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: tc.value}})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.alerts, len(mailer.Sent(alert.Recipient)), fmt.Sprintf("%s: unexpected number of alerts", tc.desc))
+		assert.Equal(t, tc.resolved, len(mailer.Sent(resolved.Recipient)), fmt.Sprintf("%s: unexpected number of resolutions", tc.desc))
+		assert.Equal(t, tc.avg, len(mailer.Sent(avg.Recipient)), fmt.Sprintf("%s: unexpected number of average alerts", tc.desc))
+	}
+}
+
+func TestApplyRulesHysteresisDSL(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+	// Rule is turned on once temperature rises above 30, and turned off
+	// once it falls to 27.
+	device := "8837ffdf-2bec-42f7-9c2d-b8cfa67661a9"
+	rules, err := dsl.Parse(fmt.Sprintf(`RULE fan: %s["temperature"] > 30 RESET 27 TRIGGERS ON RISE
+		SEND EMAIL "Temperature is high!" TO "alerts@home.com"
+	RESOLVED
+		SEND EMAIL "Temperature is back to normal." TO "resolved@home.com"`, device))
+	assert.Nil(t, err, "unexpected error")
+	for _, r := range rules {
+		r.ID = "1"
+		r.UserId = "1"
+		repo.Save(r)
+	}
+
+	cases := []struct {
+		desc     string
+		value    float64
+		alerts   int
+		resolved int
+	}{
+		{"below value", 29, 0, 0},
+		{"above value", 31, 1, 0},
+		{"below value above threshold", 28, 1, 0},
+		{"above value again", 30.5, 1, 0},
+		{"below threshold", 26.5, 1, 1},
+		{"below value after reset", 29.5, 1, 1},
+		{"above value after reset", 32, 2, 1},
+	}
+
+	for _, tc := range cases {
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: device, Name: "temperature", Value: tc.value}})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.alerts, len(mailer.Sent("alerts@home.com")), fmt.Sprintf("%s: unexpected number of alerts", tc.desc))
+		assert.Equal(t, tc.resolved, len(mailer.Sent("resolved@home.com")), fmt.Sprintf("%s: unexpected number of resolutions", tc.desc))
+	}
+}
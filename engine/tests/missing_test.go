@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/cache"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMissing(t *testing.T) {
+	start := time.Now()
+	now := start
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is offline!", Recipient: "alerts@home.com"}
+	resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is back online.", Recipient: "resolved@home.com"}
+	reminder := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is still offline!", Recipient: "reminders@home.com"}
+
+	// Rules of the user are watched once any of them detects missing data.
+	repo.Save(engine.Rule{
+		ID:         "2",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Missing: engine.Duration(15 * time.Minute)}},
+		Actions:    []engine.Action{reminder},
+	})
+	err := svc.SaveRule(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Missing: engine.Duration(10 * time.Minute)}},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{alert},
+		Resolved:   []engine.Action{resolved},
+	})
+	assert.Nil(t, err, "unexpected error")
+
+	temperature := writer.Message{Publisher: "thermometer", Name: "temperature", Value: 25}
+
+	cases := []struct {
+		desc      string
+		elapsed   time.Duration
+		event     *writer.Message
+		alerts    int
+		resolved  int
+		reminders int
+	}{
+		{"never reported", 0, nil, 0, 0, 0},
+		{"not reported within interval", 5 * time.Minute, nil, 0, 0, 0},
+		{"not reported for interval", 10 * time.Minute, nil, 1, 0, 0},
+		{"still not reported", 11 * time.Minute, nil, 1, 0, 0},
+		{"reported again", 12 * time.Minute, &temperature, 1, 1, 0},
+		{"reported within interval", 15 * time.Minute, nil, 1, 1, 0},
+		{"not reported for interval again", 22 * time.Minute, nil, 2, 1, 0},
+		{"not reported for longer interval", 27 * time.Minute, nil, 2, 1, 1},
+		{"still not reported for longer interval", 28 * time.Minute, nil, 2, 1, 2},
+	}
+
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		if tc.event != nil {
+			err = svc.ApplyRules(context.Background(), "1", []writer.Message{*tc.event})
+		} else {
+			err = svc.CheckMissing(context.Background())
+		}
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.alerts, len(mailer.Sent(alert.Recipient)), fmt.Sprintf("%s: unexpected number of alerts", tc.desc))
+		assert.Equal(t, tc.resolved, len(mailer.Sent(resolved.Recipient)), fmt.Sprintf("%s: unexpected number of resolutions", tc.desc))
+		assert.Equal(t, tc.reminders, len(mailer.Sent(reminder.Recipient)), fmt.Sprintf("%s: unexpected number of reminders", tc.desc))
+	}
+}
+
+// broadcastNotifier announces changes of the rules to all services, including
+// the one which changed them.
+type broadcastNotifier struct {
+	services []engine.Service
+}
+
+func (bn *broadcastNotifier) RulesChanged(userId string) error {
+	for _, svc := range bn.services {
+		svc.InvalidateRules(userId)
+	}
+
+	return nil
+}
+
+func TestCheckMissingCached(t *testing.T) {
+	start := time.Now()
+	now := start
+	clock := engine.WithClock(func() time.Time { return now })
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	executors := engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)}
+
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is offline!", Recipient: "alerts@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Missing: engine.Duration(10 * time.Minute)}},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{alert},
+	}
+
+	// Rule is saved through the cache, whose change is announced to the
+	// service which saved it as well.
+	notifier := &broadcastNotifier{}
+	svc := engine.NewService(cache.NewRuleRepository(repo, notifier, nil), executors, clock)
+	notifier.services = append(notifier.services, svc)
+
+	err := svc.SaveRule(rule)
+	assert.Nil(t, err, "unexpected error")
+
+	err = svc.CheckMissing(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	now = start.Add(10 * time.Minute)
+	err = svc.CheckMissing(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, len(mailer.Sent(alert.Recipient)), "expected rule to be watched after its change is announced")
+
+	// Restarted service did not receive any events or rules yet.
+	restarted := engine.NewService(cache.NewRuleRepository(repo, &broadcastNotifier{}, nil), executors, clock)
+
+	err = restarted.CheckMissing(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	now = start.Add(20 * time.Minute)
+	err = restarted.CheckMissing(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 2, len(mailer.Sent(alert.Recipient)), "expected rule to be watched after restart")
+
+	// Rules changed by another instance are reloaded.
+	now = start.Add(25 * time.Minute)
+	err = restarted.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: 25}})
+	assert.Nil(t, err, "unexpected error")
+	restarted.InvalidateRules("1")
+	now = start.Add(35 * time.Minute)
+	err = restarted.CheckMissing(context.Background())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 3, len(mailer.Sent(alert.Recipient)), "expected rule to be watched after invalidation")
+}
+
+// broadcastSeen announces reported properties to all services, including the
+// one which received them.
+type broadcastSeen struct {
+	services []engine.Service
+}
+
+func (bs *broadcastSeen) Seen(userId, deviceId, property string, at time.Time) error {
+	for _, svc := range bs.services {
+		svc.MarkSeen(userId, deviceId, property, at)
+	}
+
+	return nil
+}
+
+// leaseMock is held depending on whether it is granted.
+type leaseMock struct {
+	granted bool
+}
+
+func (lm *leaseMock) Acquire() (bool, error) {
+	return lm.granted, nil
+}
+
+func TestCheckMissingInstances(t *testing.T) {
+	start := time.Now()
+	now := start
+	clock := engine.WithClock(func() time.Time { return now })
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	executors := engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)}
+
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is offline!", Recipient: "alerts@home.com"}
+	resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Thermometer is back online.", Recipient: "resolved@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Missing: engine.Duration(10 * time.Minute)}},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{alert},
+		Resolved:   []engine.Action{resolved},
+	}
+
+	// Readings are received by either instance, while only the instance
+	// holding the lease checks for missing data.
+	seen := &broadcastSeen{}
+	leader := engine.NewService(repo, executors, clock, engine.WithSeenNotifier(seen), engine.WithLease(&leaseMock{true}))
+	follower := engine.NewService(repo, executors, clock, engine.WithSeenNotifier(seen), engine.WithLease(&leaseMock{false}))
+	seen.services = []engine.Service{leader, follower}
+
+	err := leader.SaveRule(rule)
+	assert.Nil(t, err, "unexpected error")
+
+	temperature := writer.Message{Publisher: "thermometer", Name: "temperature", Value: 25}
+
+	cases := []struct {
+		desc     string
+		elapsed  time.Duration
+		event    bool
+		alerts   int
+		resolved int
+	}{
+		{"never reported", 0, false, 0, 0},
+		{"reported to follower", 5 * time.Minute, true, 0, 0},
+		{"reported within interval", 10 * time.Minute, false, 0, 0},
+		{"not reported for interval", 15 * time.Minute, false, 1, 0},
+		{"still not reported", 16 * time.Minute, false, 1, 0},
+		{"reported to follower again", 17 * time.Minute, true, 1, 1},
+		{"reported within interval again", 20 * time.Minute, false, 1, 1},
+	}
+
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		if tc.event {
+			err = follower.ApplyRules(context.Background(), "1", []writer.Message{temperature})
+			assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		}
+		for _, svc := range seen.services {
+			err = svc.CheckMissing(context.Background())
+			assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		}
+		assert.Equal(t, tc.alerts, len(mailer.Sent(alert.Recipient)), fmt.Sprintf("%s: unexpected number of alerts", tc.desc))
+		assert.Equal(t, tc.resolved, len(mailer.Sent(resolved.Recipient)), fmt.Sprintf("%s: unexpected number of resolutions", tc.desc))
+	}
+}
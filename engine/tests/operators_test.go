@@ -28,6 +28,12 @@ func TestOperatorMarshaling(t *testing.T) {
 		{engine.Gt, `">"`, nil},
 		{engine.Gte, `">="`, nil},
 		{engine.Btw, `"BETWEEN"`, nil},
+		{engine.Contains, `"CONTAINS"`, nil},
+		{engine.StartsWith, `"STARTS WITH"`, nil},
+		{engine.EndsWith, `"ENDS WITH"`, nil},
+		{engine.Matches, `"MATCHES"`, nil},
+		{engine.In, `"IN"`, nil},
+		{engine.NotIn, `"NOT IN"`, nil},
 	}
 	for i, tc := range cases {
 		buffer := &bytes.Buffer{}
@@ -54,6 +60,13 @@ func TestOperatorUnmarshaling(t *testing.T) {
 		{`{"op" : ">"}`, engine.Gt, nil},
 		{`{"op" : ">="}`, engine.Gte, nil},
 		{`{"op" : "BETWEEN"}`, engine.Btw, nil},
+		{`{"op" : "CONTAINS"}`, engine.Contains, nil},
+		{`{"op" : "STARTS WITH"}`, engine.StartsWith, nil},
+		{`{"op" : "ENDS WITH"}`, engine.EndsWith, nil},
+		{`{"op" : "MATCHES"}`, engine.Matches, nil},
+		{`{"op" : "IN"}`, engine.In, nil},
+		{`{"op" : "NOT IN"}`, engine.NotIn, nil},
+		{`{"op" : "STARTS"}`, engine.Undefined, engine.ErrMalformedEntity},
 		{`{"op" : "invalid"}`, engine.Undefined, engine.ErrMalformedEntity},
 		{`{"op" : null}`, engine.Undefined, engine.ErrMalformedEntity},
 	}
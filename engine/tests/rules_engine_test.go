@@ -3,19 +3,42 @@ package tests
 import (
 	"testing"
 	"fmt"
+	"errors"
+	"context"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/MainfluxLabs/rules-engine/engine/mocks"
 	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/dsl"
+	"github.com/mainflux/mainflux/writer"
 )
 
-var (
-	rulesRepo engine.RuleRepository = mocks.NewRuleRepository()
-	svc       engine.Service        = engine.NewService(rulesRepo)
-)
+// newService instantiates service along with its repository, mailer and
+// device controller, so that tests do not share any state.
+func newService() (engine.Service, engine.RuleRepository, *mocks.MailerMock, *mocks.DeviceControllerMock) {
+	rulesRepo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	controller := mocks.NewDeviceController()
+	svc := engine.NewService(rulesRepo, engine.Executors{
+		engine.SendEmail: engine.NewSendEmailExecutor(mailer),
+		engine.TurnOff:   engine.NewTurnOffExecutor(controller),
+	})
+
+	return svc, rulesRepo, mailer, controller
+}
+
+type logAction struct {
+	Message string
+}
+
+func (a logAction) Type() string {
+	return "LOG"
+}
 
 func TestViewRule(t *testing.T) {
-	existingRule := engine.Rule{"1", "1", "test-rule-1", make([]engine.Condition, 0), make([]engine.Action, 0)}
+	svc, rulesRepo, _, _ := newService()
+	existingRule := engine.Rule{ID: "1", UserId: "1", Name: "test-rule-1", Conditions: make([]engine.Condition, 0), Actions: make([]engine.Action, 0)}
 	rulesRepo.Save(existingRule)
 
 	cases := []struct {
@@ -36,8 +59,9 @@ func TestViewRule(t *testing.T) {
 }
 
 func TestListRules(t *testing.T) {
-	r1 := engine.Rule{"1", "2", "test-rule-1", make([]engine.Condition, 0), make([]engine.Action, 0)}
-	r2 := engine.Rule{"2", "2", "test-rule-2", make([]engine.Condition, 0), make([]engine.Action, 0)}
+	svc, rulesRepo, _, _ := newService()
+	r1 := engine.Rule{ID: "1", UserId: "2", Name: "test-rule-1", Conditions: make([]engine.Condition, 0), Actions: make([]engine.Action, 0)}
+	r2 := engine.Rule{ID: "2", UserId: "2", Name: "test-rule-2", Conditions: make([]engine.Condition, 0), Actions: make([]engine.Action, 0)}
 	rulesRepo.Save(r1)
 	rulesRepo.Save(r2)
 
@@ -51,13 +75,16 @@ func TestListRules(t *testing.T) {
 	}
 
 	for i, tc := range cases {
-		r, err := svc.ListRules(tc.userId)
+		r, err := svc.ListRules(tc.userId, engine.RuleFilter{})
 		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
 		assert.ElementsMatch(t, tc.rules, r, fmt.Sprintf("failed at %d\n", i))
 	}
 }
 
 func TestRemoveRule(t *testing.T) {
+	svc, rulesRepo, _, _ := newService()
+	rulesRepo.Save(engine.Rule{ID: "1", UserId: "1", Name: "test-rule-1"})
+
 	cases := []struct {
 		userId string
 		ruleId string
@@ -71,4 +98,695 @@ func TestRemoveRule(t *testing.T) {
 		err := svc.RemoveRule(tc.userId, tc.ruleId)
 		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
 	}
+
+	_, err := svc.ViewRule("1", "1")
+	assert.Equal(t, engine.ErrNotFound, err, "expected rule to be removed")
+}
+
+func TestApplyRules(t *testing.T) {
+	svc, rulesRepo, mailer, controller := newService()
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "High temperature!", Recipient: "person01@home.com"}
+	turnOff := engine.TurnOffAction{Name: "TURN OFF", DeviceId: "heater"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "4",
+		Name:       "test-rule-1",
+		Conditions: []engine.Condition{{DeviceID: "device", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{email, turnOff},
+	}
+	rulesRepo.Save(rule)
+
+	deliveryErr := errors.New("delivery failed")
+
+	publishErr := errors.New("publish failed")
+
+	cases := []struct {
+		event      writer.Message
+		mailErr    error
+		controlErr error
+		sent       int
+		turnedOff  int
+		err        error
+	}{
+		{writer.Message{Publisher: "device", Name: "temp", Value: 20}, nil, nil, 0, 0, nil},
+		{writer.Message{Publisher: "device", Name: "temp", Value: 35}, nil, nil, 1, 1, nil},
+		{writer.Message{Publisher: "device", Name: "temp", Value: 40}, deliveryErr, nil, 1, 2, deliveryErr},
+		{writer.Message{Publisher: "device", Name: "temp", Value: 40}, nil, publishErr, 2, 2, publishErr},
+	}
+
+	for i, tc := range cases {
+		mailer.Error = tc.mailErr
+		controller.Error = tc.controlErr
+		err := svc.ApplyRules(context.Background(), "4", []writer.Message{tc.event})
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("failed at %d\n", i))
+		assert.Equal(t, tc.turnedOff, controller.TurnedOff(turnOff.DeviceId), fmt.Sprintf("failed at %d\n", i))
+	}
+}
+
+func TestApplyRulesWithoutExecutor(t *testing.T) {
+	svc, rulesRepo, _, _ := newService()
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "5",
+		Name:       "test-rule-1",
+		Conditions: []engine.Condition{{DeviceID: "device", Property: "active", Operator: engine.Eq, Type: engine.Bool, Value: true}},
+		Actions:    []engine.Action{logAction{"device activated"}},
+	}
+	rulesRepo.Save(rule)
+
+	event := writer.Message{Publisher: "device", Name: "active", BoolValue: true}
+	err := svc.ApplyRules(context.Background(), "5", []writer.Message{event})
+	assert.Equal(t, engine.ErrUnsupportedAction, err, "expected unsupported action error")
+}
+
+func TestApplyRulesCancelled(t *testing.T) {
+	svc, rulesRepo, mailer, _ := newService()
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Device activated!", Recipient: "person02@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "6",
+		Name:       "test-rule-1",
+		Conditions: []engine.Condition{{DeviceID: "device", Property: "active", Operator: engine.Eq, Type: engine.Bool, Value: true}},
+		Actions:    []engine.Action{email},
+	}
+	rulesRepo.Save(rule)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	event := writer.Message{Publisher: "device", Name: "active", BoolValue: true}
+	err := svc.ApplyRules(ctx, "6", []writer.Message{event})
+	assert.Equal(t, context.Canceled, err, "expected cancellation error")
+	assert.Equal(t, 0, len(mailer.Sent(email.Recipient)), "expected no emails to be sent")
+}
+
+func TestApplyRulesMultipleDevices(t *testing.T) {
+	now := time.Now()
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithStaleness(time.Minute),
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Heaters are on in hot room!", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:     "1",
+		UserId: "1",
+		Name:   "rule02",
+		Conditions: []engine.Condition{
+			{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lte, Type: engine.Numeric, Value: float64(30)},
+			{DeviceID: "heaters", Property: "active", Operator: engine.Btw, Type: engine.Between, Value: engine.Range{From: 1, To: 4}},
+		},
+		Actions: []engine.Action{email},
+	}
+	repo.Save(rule)
+
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		event   writer.Message
+		sent    int
+	}{
+		{"single condition satisfied", 0, writer.Message{Publisher: "thermometer", Name: "temperature", Value: 35}, 0},
+		{"unrelated event", time.Second, writer.Message{Publisher: "thermometer", Name: "humidity", Value: 35}, 0},
+		{"both conditions satisfied", 2 * time.Second, writer.Message{Publisher: "heaters", Name: "active", Value: 2}, 1},
+		{"latest reading unsatisfied", 3 * time.Second, writer.Message{Publisher: "thermometer", Name: "temperature", Value: 25}, 1},
+		{"latest reading satisfied", 4 * time.Second, writer.Message{Publisher: "thermometer", Name: "temperature", Value: 32}, 2},
+		{"stale reading", 3 * time.Minute, writer.Message{Publisher: "thermometer", Name: "temperature", Value: 33}, 2},
+		{"fresh readings", 3*time.Minute + time.Second, writer.Message{Publisher: "heaters", Name: "active", Value: 3}, 3},
+	}
+
+	start := now
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{tc.event})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails", tc.desc))
+	}
+}
+
+func TestApplyRulesHeldCondition(t *testing.T) {
+	now := time.Now()
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high for 5 minutes!", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:     "1",
+		UserId: "1",
+		Name:   "rule01",
+		Conditions: []engine.Condition{
+			{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), For: engine.Duration(5 * time.Minute)},
+		},
+		Actions: []engine.Action{email},
+	}
+	repo.Save(rule)
+
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		value   float64
+		sent    int
+	}{
+		{"condition became satisfied", 0, 35, 0},
+		{"condition pending", 3 * time.Minute, 36, 0},
+		{"condition held", 5 * time.Minute, 34, 1},
+		{"condition still held", 6 * time.Minute, 37, 2},
+		{"condition interrupted", 7 * time.Minute, 25, 2},
+		{"condition satisfied again", 8 * time.Minute, 35, 2},
+		{"condition pending again", 12 * time.Minute, 35, 2},
+		{"condition held again", 13 * time.Minute, 35, 3},
+	}
+
+	start := now
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		event := writer.Message{Publisher: "thermometer", Name: "temperature", Value: tc.value}
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails", tc.desc))
+	}
+
+	svc.RemoveRule("1", "1")
+	svc.SaveRule(rule)
+	now = start.Add(14 * time.Minute)
+	event := writer.Message{Publisher: "thermometer", Name: "temperature", Value: 35}
+	svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	assert.Equal(t, 3, len(mailer.Sent(email.Recipient)), "expected pending state to be dropped with the rule")
+}
+
+func TestCheckPending(t *testing.T) {
+	now := time.Now()
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high for 5 minutes!", Recipient: "person01@home.com"}
+	repo.Save(engine.Rule{
+		ID:     "1",
+		UserId: "1",
+		Name:   "rule01",
+		Conditions: []engine.Condition{
+			{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), For: engine.Duration(5 * time.Minute)},
+		},
+		Actions: []engine.Action{email},
+	})
+
+	// Thermometer reports only when the temperature changes.
+	cases := []struct {
+		desc    string
+		elapsed time.Duration
+		value   *float64
+		sent    int
+	}{
+		{"condition became satisfied", 0, ptr(35), 0},
+		{"condition pending", 3 * time.Minute, nil, 0},
+		{"condition held", 5 * time.Minute, nil, 1},
+		{"condition checked once", 6 * time.Minute, nil, 1},
+		{"condition interrupted", 7 * time.Minute, ptr(25), 1},
+		{"condition not satisfied", 13 * time.Minute, nil, 1},
+		{"condition satisfied again", 14 * time.Minute, ptr(35), 1},
+		{"condition held by the reading", 19 * time.Minute, ptr(36), 2},
+		{"condition checked by the reading", 20 * time.Minute, nil, 2},
+	}
+
+	start := now
+	for _, tc := range cases {
+		now = start.Add(tc.elapsed)
+		var err error
+		if tc.value != nil {
+			err = svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: *tc.value}})
+		} else {
+			err = svc.CheckPending(context.Background())
+		}
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails", tc.desc))
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestApplyRulesTriggerModes(t *testing.T) {
+	values := []float64{35, 36, 25, 20, 35}
+
+	cases := []struct {
+		desc     string
+		trigger  engine.TriggerMode
+		alerts   []int
+		resolved []int
+	}{
+		{"default trigger", "", []int{1, 2, 2, 2, 3}, []int{0, 0, 1, 1, 1}},
+		{"level trigger", engine.Level, []int{1, 2, 2, 2, 3}, []int{0, 0, 1, 1, 1}},
+		{"rising trigger", engine.Rising, []int{1, 1, 1, 1, 2}, []int{0, 0, 1, 1, 1}},
+		{"falling trigger", engine.Falling, []int{0, 0, 1, 1, 1}, []int{0, 0, 1, 1, 1}},
+	}
+
+	for _, tc := range cases {
+		repo := mocks.NewRuleRepository()
+		mailer := mocks.NewMailer()
+		svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+		alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "alerts@home.com"}
+		resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is back to normal.", Recipient: "resolved@home.com"}
+		repo.Save(engine.Rule{
+			ID:         "1",
+			UserId:     "1",
+			Name:       "rule01",
+			Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+			Trigger:    tc.trigger,
+			Actions:    []engine.Action{alert},
+			Resolved:   []engine.Action{resolved},
+		})
+
+		for i, value := range values {
+			event := writer.Message{Publisher: "thermometer", Name: "temperature", Value: value}
+			err := svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+			assert.Nil(t, err, fmt.Sprintf("%s: unexpected error at %d", tc.desc, i))
+			assert.Equal(t, tc.alerts[i], len(mailer.Sent(alert.Recipient)), fmt.Sprintf("%s: unexpected number of alerts at %d", tc.desc, i))
+			assert.Equal(t, tc.resolved[i], len(mailer.Sent(resolved.Recipient)), fmt.Sprintf("%s: unexpected number of resolutions at %d", tc.desc, i))
+		}
+	}
+}
+
+func TestApplyRulesThrottle(t *testing.T) {
+	cases := []struct {
+		desc     string
+		throttle engine.Throttle
+		elapsed  []time.Duration
+		sent     []int
+	}{
+		{
+			"cooldown",
+			engine.Throttle{Cooldown: engine.Duration(time.Minute)},
+			[]time.Duration{0, 30 * time.Second, time.Minute, 90 * time.Second, 3 * time.Minute},
+			[]int{1, 1, 2, 2, 3},
+		},
+		{
+			"limit per window",
+			engine.Throttle{Limit: 2, Window: engine.Duration(time.Hour)},
+			[]time.Duration{0, time.Minute, 2 * time.Minute, 59 * time.Minute, time.Hour, 61 * time.Minute, 62 * time.Minute},
+			[]int{1, 2, 2, 2, 3, 4, 4},
+		},
+		{
+			"cooldown and limit",
+			engine.Throttle{Cooldown: engine.Duration(time.Minute), Limit: 2, Window: engine.Duration(time.Hour)},
+			[]time.Duration{0, 30 * time.Second, time.Minute, 2 * time.Minute, time.Hour + time.Minute},
+			[]int{1, 1, 2, 2, 3},
+		},
+	}
+
+	for _, tc := range cases {
+		now := time.Now()
+		repo := mocks.NewRuleRepository()
+		mailer := mocks.NewMailer()
+		svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+			engine.WithClock(func() time.Time { return now }),
+		)
+
+		th := tc.throttle
+		email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "person01@home.com"}
+		repo.Save(engine.Rule{
+			ID:         "1",
+			UserId:     "1",
+			Name:       "rule01",
+			Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+			Throttle:   &th,
+			Actions:    []engine.Action{email},
+		})
+
+		start := now
+		for i, elapsed := range tc.elapsed {
+			now = start.Add(elapsed)
+			event := writer.Message{Publisher: "thermometer", Name: "temperature", Value: 35}
+			err := svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+			assert.Nil(t, err, fmt.Sprintf("%s: unexpected error at %d", tc.desc, i))
+			assert.Equal(t, tc.sent[i], len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails at %d", tc.desc, i))
+		}
+	}
+}
+
+func TestUpdateRule(t *testing.T) {
+	svc, rulesRepo, _, _ := newService()
+	existingRule := engine.Rule{ID: "10", UserId: "1", Name: "test-rule-10", Conditions: make([]engine.Condition, 0), Actions: make([]engine.Action, 0)}
+	rulesRepo.Save(existingRule)
+
+	cases := []struct {
+		rule engine.Rule
+		err  error
+	}{
+		{engine.Rule{ID: "10", UserId: "1", Name: "updated-rule-10"}, nil},
+		{engine.Rule{ID: "11", UserId: "1", Name: "missing-rule"}, engine.ErrNotFound},
+		{engine.Rule{ID: "10", UserId: "2", Name: "foreign-rule"}, engine.ErrNotFound},
+	}
+
+	for i, tc := range cases {
+		err := svc.UpdateRule(tc.rule)
+		assert.Equal(t, tc.err, err, fmt.Sprintf("failed at %d\n", i))
+	}
+
+	rule, _ := svc.ViewRule("1", "10")
+	assert.Equal(t, "updated-rule-10", rule.Name, "rule not updated")
+}
+
+func TestDisableRule(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Temperature is high!", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Name:       "rule01",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{email},
+	}
+	svc.SaveRule(rule)
+	svc.SaveRule(engine.Rule{ID: "2", UserId: "1", Name: "rule02"})
+
+	apply := func(value float64) {
+		event := writer.Message{Publisher: "thermometer", Name: "temperature", Value: value}
+		svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	}
+
+	apply(35)
+	assert.Equal(t, 1, len(mailer.Sent(email.Recipient)), "expected enabled rule to be applied")
+
+	err := svc.DisableRule("1", "1")
+	assert.Nil(t, err, "unexpected error")
+	apply(25)
+	apply(35)
+	assert.Equal(t, 1, len(mailer.Sent(email.Recipient)), "expected disabled rule not to be applied")
+
+	rule.Name = "updated-rule01"
+	err = svc.UpdateRule(rule)
+	assert.Nil(t, err, "unexpected error")
+	apply(36)
+	assert.Equal(t, 1, len(mailer.Sent(email.Recipient)), "expected updated rule to stay disabled")
+
+	enabled, disabled := false, true
+	cases := []struct {
+		desc   string
+		filter engine.RuleFilter
+		ids    []string
+	}{
+		{"all rules", engine.RuleFilter{}, []string{"1", "2"}},
+		{"disabled rules", engine.RuleFilter{Disabled: &disabled}, []string{"1"}},
+		{"enabled rules", engine.RuleFilter{Disabled: &enabled}, []string{"2"}},
+	}
+
+	for _, tc := range cases {
+		rules, err := svc.ListRules("1", tc.filter)
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+
+		var ids []string
+		for _, r := range rules {
+			ids = append(ids, r.ID)
+		}
+		assert.ElementsMatch(t, tc.ids, ids, fmt.Sprintf("%s: unexpected rules", tc.desc))
+	}
+
+	// Enabled rule does not rely on the state from before it was disabled,
+	// so rising trigger fires for the first matching event.
+	err = svc.EnableRule("1", "1")
+	assert.Nil(t, err, "unexpected error")
+	apply(37)
+	assert.Equal(t, 2, len(mailer.Sent(email.Recipient)), "expected enabled rule to be applied")
+
+	err = svc.DisableRule("1", "3")
+	assert.Equal(t, engine.ErrNotFound, err, "expected non-existent rule")
+}
+
+func TestSimulateRule(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+	hot := engine.Condition{DeviceID: "thermometer", Property: "temperature", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30), For: engine.Duration(time.Minute)}
+	on := engine.Condition{DeviceID: "heater", Property: "on", Operator: engine.Eq, Type: engine.Bool, Value: true}
+	alert := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Heater is on in hot room!", Recipient: "person01@home.com"}
+	resolved := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Room is fine.", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{hot, on},
+		Trigger:    engine.Rising,
+		Actions:    []engine.Action{alert},
+		Resolved:   []engine.Action{resolved},
+	}
+
+	events := []writer.Message{
+		{Publisher: "heater", Name: "on", BoolValue: true, Time: 1000},
+		{Publisher: "thermometer", Name: "humidity", Value: 50, Time: 1010},
+		{Publisher: "thermometer", Name: "temperature", Value: 35, Time: 1020},
+		{Publisher: "thermometer", Name: "temperature", Value: 36, Time: 1080},
+		{Publisher: "thermometer", Name: "temperature", Value: 37, Time: 1090},
+		{Publisher: "heater", Name: "on", BoolValue: false, Time: 1100},
+	}
+
+	cases := []struct {
+		desc       string
+		evaluated  bool
+		conditions []engine.ConditionTrace
+		matched    bool
+		fired      bool
+		resolved   bool
+		actions    []engine.Action
+	}{
+		{
+			"unknown reading", true,
+			[]engine.ConditionTrace{{Condition: hot}, {Condition: on, Known: true, Value: true, Satisfied: true}},
+			false, false, false, nil,
+		},
+		{"unrelated event", false, nil, false, false, false, nil},
+		{
+			"pending condition", true,
+			[]engine.ConditionTrace{{Condition: hot, Known: true, Value: float64(35), Pending: true}, {Condition: on, Known: true, Value: true, Satisfied: true}},
+			false, false, false, nil,
+		},
+		{
+			"held condition", true,
+			[]engine.ConditionTrace{{Condition: hot, Known: true, Value: float64(36), Satisfied: true}, {Condition: on, Known: true, Value: true, Satisfied: true}},
+			true, true, false, []engine.Action{alert},
+		},
+		{
+			"still matched", true,
+			[]engine.ConditionTrace{{Condition: hot, Known: true, Value: float64(37), Satisfied: true}, {Condition: on, Known: true, Value: true, Satisfied: true}},
+			true, false, false, nil,
+		},
+		{
+			"resolved", true,
+			[]engine.ConditionTrace{{Condition: hot, Known: true, Value: float64(37), Satisfied: true}, {Condition: on, Known: true, Value: false}},
+			false, false, true, []engine.Action{resolved},
+		},
+	}
+
+	traces, err := svc.SimulateRule(rule, events)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, len(cases), len(traces), "unexpected number of traces")
+
+	for i, tc := range cases {
+		tr := traces[i]
+		assert.Equal(t, events[i], tr.Event, fmt.Sprintf("%s: unexpected event", tc.desc))
+		assert.Equal(t, tc.evaluated, tr.Evaluated, fmt.Sprintf("%s: unexpected evaluation", tc.desc))
+		assert.Equal(t, tc.conditions, tr.Conditions, fmt.Sprintf("%s: unexpected conditions", tc.desc))
+		assert.Equal(t, tc.matched, tr.Matched, fmt.Sprintf("%s: unexpected match", tc.desc))
+		assert.Equal(t, tc.fired, tr.Fired, fmt.Sprintf("%s: unexpected firing", tc.desc))
+		assert.Equal(t, tc.resolved, tr.Resolved, fmt.Sprintf("%s: unexpected resolution", tc.desc))
+		assert.Equal(t, tc.actions, tr.Actions, fmt.Sprintf("%s: unexpected actions", tc.desc))
+	}
+
+	assert.Empty(t, mailer.Sent(alert.Recipient), "simulation performed actions")
+
+	repo.Save(rule)
+	err = svc.ApplyRules(context.Background(), "1", []writer.Message{events[0]})
+	assert.Nil(t, err, "unexpected error")
+	err = svc.ApplyRules(context.Background(), "1", []writer.Message{{Publisher: "thermometer", Name: "temperature", Value: 35}})
+	assert.Nil(t, err, "unexpected error")
+	assert.Empty(t, mailer.Sent(alert.Recipient), "simulation affected evaluation state")
+}
+
+func TestApplyRulesIndex(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "High temperature!", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{email},
+	}
+	repo.Save(rule)
+
+	moved := rule
+	moved.Conditions = []engine.Condition{{DeviceID: "thermostat", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}}
+
+	cases := []struct {
+		desc   string
+		update func()
+		event  writer.Message
+		sent   int
+	}{
+		{"stored rule", func() {}, writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}, 1},
+		{"unreferred property", func() {}, writer.Message{Publisher: "thermometer", Name: "humidity", Value: 35}, 1},
+		{"updated rule", func() { svc.UpdateRule(moved) }, writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}, 1},
+		{"updated rule reference", func() {}, writer.Message{Publisher: "thermostat", Name: "temp", Value: 35}, 2},
+		{"removed rule", func() { svc.RemoveRule("1", "1") }, writer.Message{Publisher: "thermostat", Name: "temp", Value: 35}, 2},
+		{"saved rule", func() { svc.SaveRule(rule) }, writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}, 3},
+	}
+
+	for _, tc := range cases {
+		tc.update()
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{tc.event})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails", tc.desc))
+	}
+}
+
+func TestInvalidateRules(t *testing.T) {
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)})
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "High temperature!", Recipient: "person01@home.com"}
+	rule := engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "thermometer", Property: "temp", Operator: engine.Lt, Type: engine.Numeric, Value: float64(30)}},
+		Actions:    []engine.Action{email},
+	}
+	event := writer.Message{Publisher: "thermometer", Name: "temp", Value: 35}
+
+	svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	repo.Save(rule)
+	svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	assert.Empty(t, mailer.Sent(email.Recipient), "expected rules to be kept in memory")
+
+	svc.InvalidateRules("1")
+	svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+	assert.Equal(t, 1, len(mailer.Sent(email.Recipient)), "expected rules to be reloaded")
+}
+
+func TestApplyRulesDocumentedExamples(t *testing.T) {
+	device := "8837ffdf-2bec-42f7-9c2d-b8cfa67661a9"
+	heater := "45ab8a94-e0f1-4ea4-b99c-c51e16566f83"
+	temperature := func(v float64) writer.Message {
+		return writer.Message{Publisher: device, Name: "temperature", Value: v}
+	}
+
+	type step struct {
+		elapsed time.Duration
+		event   writer.Message
+		sent    int
+	}
+
+	cases := []struct {
+		desc      string
+		src       string
+		steps     []step
+		turnedOff int
+	}{
+		{
+			"trigger mode",
+			`RULE overheating:
+				8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["temperature"] > 30
+			TRIGGERS ON RISE
+				SEND EMAIL "Temperature is high!" TO "person01@home.com"
+			RESOLVED
+				SEND EMAIL "Temperature is back to normal." TO "person01@home.com"`,
+			[]step{
+				{0, temperature(20), 0},
+				{time.Minute, temperature(35), 1},
+				{2 * time.Minute, temperature(36), 1},
+				{3 * time.Minute, temperature(25), 2},
+			},
+			0,
+		},
+		{
+			"duration",
+			`RULE held: 8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["temperature"] > 30 FOR 5m
+			TRIGGERS SEND EMAIL "Temperature is high!" TO "person01@home.com"`,
+			[]step{
+				{0, temperature(35), 0},
+				{3 * time.Minute, temperature(20), 0},
+				{4 * time.Minute, temperature(35), 0},
+				{9 * time.Minute, temperature(32), 1},
+			},
+			0,
+		},
+		{
+			"logical operators",
+			`RULE alarm:
+				8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["temperature"] > 30 OR
+					(8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["smoke"] = true AND NOT 8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["mode"] = "test")
+			TRIGGERS SEND EMAIL "Alarm!" TO "person01@home.com"`,
+			[]step{
+				{0, temperature(20), 0},
+				{time.Second, temperature(35), 1},
+				{2 * time.Second, temperature(25), 1},
+				{3 * time.Second, writer.Message{Publisher: device, Name: "mode", StringValue: "test"}, 1},
+				{4 * time.Second, writer.Message{Publisher: device, Name: "smoke", BoolValue: true}, 1},
+				{5 * time.Second, writer.Message{Publisher: device, Name: "mode", StringValue: "live"}, 2},
+			},
+			0,
+		},
+		{
+			"example rule",
+			`RULE rule01:
+				8837ffdf-2bec-42f7-9c2d-b8cfa67661a9["temperature"] >= 30
+			TRIGGERS
+				TURN OFF 45ab8a94-e0f1-4ea4-b99c-c51e16566f83
+				SEND EMAIL "Alert! High temperature in the living room!" TO "person01@home.com"`,
+			[]step{
+				{0, temperature(29), 0},
+				{time.Second, temperature(30), 1},
+				{2 * time.Second, temperature(45), 2},
+				{3 * time.Second, temperature(-5), 2},
+			},
+			2,
+		},
+	}
+
+	for _, tc := range cases {
+		start := time.Now()
+		now := start
+		repo := mocks.NewRuleRepository()
+		mailer := mocks.NewMailer()
+		controller := mocks.NewDeviceController()
+		svc := engine.NewService(repo, engine.Executors{
+			engine.SendEmail: engine.NewSendEmailExecutor(mailer),
+			engine.TurnOff:   engine.NewTurnOffExecutor(controller),
+		}, engine.WithClock(func() time.Time { return now }))
+
+		rules, err := dsl.Parse(tc.src)
+		if !assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc)) {
+			continue
+		}
+		for i, r := range rules {
+			r.ID = fmt.Sprintf("%d", i+1)
+			r.UserId = "1"
+			repo.Save(r)
+		}
+
+		for i, s := range tc.steps {
+			now = start.Add(s.elapsed)
+			err := svc.ApplyRules(context.Background(), "1", []writer.Message{s.event})
+			assert.Nil(t, err, fmt.Sprintf("%s: unexpected error at %d", tc.desc, i))
+			assert.Equal(t, s.sent, len(mailer.Sent("person01@home.com")), fmt.Sprintf("%s: unexpected number of emails at %d", tc.desc, i))
+		}
+
+		assert.Equal(t, tc.turnedOff, controller.TurnedOff(heater), fmt.Sprintf("%s: unexpected number of turn off commands", tc.desc))
+	}
 }
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/MainfluxLabs/rules-engine/engine/mocks"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleActive(t *testing.T) {
+	belgrade, err := time.LoadLocation("Europe/Belgrade")
+	if !assert.Nil(t, err, "failed to load time zone") {
+		return
+	}
+
+	// 2018-05-07 is Monday.
+	at := func(day, h, m int) time.Time {
+		return time.Date(2018, 5, 7+day, h, m, 0, 0, belgrade)
+	}
+
+	afterHours := engine.Window{Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, From: 18 * 60, To: 8 * 60}
+	weekend := engine.Window{Days: []string{"SAT", "SUN"}}
+	evening := engine.Window{From: 20 * 60}
+
+	cases := []struct {
+		desc     string
+		schedule engine.Schedule
+		time     time.Time
+		active   bool
+	}{
+		{"within window", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(0, 19, 0), true},
+		{"window start", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(0, 18, 0), true},
+		{"before window", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(0, 17, 59), false},
+		{"after midnight", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(1, 7, 59), true},
+		{"window end", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(1, 8, 0), false},
+		{"after midnight of another day", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(0, 7, 0), false},
+		{"after midnight of last day", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{afterHours}}, at(5, 7, 0), true},
+		{"whole day", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{weekend}}, at(6, 0, 0), true},
+		{"another day", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{weekend}}, at(0, 12, 0), false},
+		{"until midnight", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{evening}}, at(2, 23, 59), true},
+		{"any of windows", engine.Schedule{Zone: "Europe/Belgrade", Windows: []engine.Window{weekend, evening}}, at(2, 21, 0), true},
+		{"time zone", engine.Schedule{Zone: "America/New_York", Windows: []engine.Window{afterHours}}, at(0, 19, 0), false},
+		{"default time zone", engine.Schedule{Windows: []engine.Window{afterHours}}, at(0, 20, 0), true},
+		{"unknown time zone", engine.Schedule{Zone: "Mars/Olympus", Windows: []engine.Window{weekend}}, at(6, 12, 0), false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.active, tc.schedule.Active(tc.time), fmt.Sprintf("%s: unexpected activity", tc.desc))
+	}
+}
+
+func TestApplyRulesSchedule(t *testing.T) {
+	// Monday noon.
+	now := time.Date(2018, 5, 7, 12, 0, 0, 0, time.UTC)
+	repo := mocks.NewRuleRepository()
+	mailer := mocks.NewMailer()
+	svc := engine.NewService(repo, engine.Executors{engine.SendEmail: engine.NewSendEmailExecutor(mailer)},
+		engine.WithClock(func() time.Time { return now }),
+	)
+
+	email := engine.SendEmailAction{Name: "SEND EMAIL", Content: "Door is open after hours!", Recipient: "security@home.com"}
+	repo.Save(engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Conditions: []engine.Condition{{DeviceID: "door", Property: "open", Operator: engine.Eq, Type: engine.Bool, Value: true}},
+		Schedule:   &engine.Schedule{Windows: []engine.Window{{From: 18 * 60, To: 8 * 60}}},
+		Actions:    []engine.Action{email},
+	})
+
+	cases := []struct {
+		desc  string
+		now   time.Time
+		event time.Time
+		sent  int
+	}{
+		{"outside of schedule", now, time.Time{}, 0},
+		{"within schedule", now.Add(7 * time.Hour), time.Time{}, 1},
+		{"event time within schedule", now, now.Add(-6 * time.Hour), 2},
+		{"event time outside of schedule", now.Add(7 * time.Hour), now, 2},
+	}
+
+	for _, tc := range cases {
+		now = tc.now
+		event := writer.Message{Publisher: "door", Name: "open", BoolValue: true}
+		if !tc.event.IsZero() {
+			event.Time = float64(tc.event.Unix())
+		}
+
+		err := svc.ApplyRules(context.Background(), "1", []writer.Message{event})
+		assert.Nil(t, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		assert.Equal(t, tc.sent, len(mailer.Sent(email.Recipient)), fmt.Sprintf("%s: unexpected number of emails", tc.desc))
+	}
+
+	traces, _ := svc.SimulateRule(engine.Rule{
+		Conditions: []engine.Condition{{DeviceID: "door", Property: "open", Operator: engine.Eq, Type: engine.Bool, Value: true}},
+		Schedule:   &engine.Schedule{Windows: []engine.Window{{Days: []string{"SAT", "SUN"}}}},
+		Actions:    []engine.Action{email},
+	}, []writer.Message{{Publisher: "door", Name: "open", BoolValue: true, Time: float64(now.Unix())}})
+	assert.True(t, traces[0].Inactive, "expected rule outside of schedule to be inactive")
+	assert.False(t, traces[0].Evaluated, "unexpected evaluation of inactive rule")
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits how often actions of the rule are performed. Cooldown is
+// the minimum interval between two firings, while limit is the maximum number
+// of firings within the sliding window. Zero values disable the respective
+// restriction.
+type Throttle struct {
+	Cooldown Duration `json:"cooldown,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+	Window   Duration `json:"window,omitempty"`
+}
+
+// firingStore keeps the moments of recent firings of each throttled rule.
+type firingStore struct {
+	mu      sync.Mutex
+	firings map[ruleRef][]time.Time
+}
+
+func newFiringStore() *firingStore {
+	return &firingStore{
+		firings: make(map[ruleRef][]time.Time),
+	}
+}
+
+// allow checks whether the rule may fire at the specified moment, recording
+// the firing if it is allowed. Rules without throttle are always allowed.
+func (fs *firingStore) allow(rule Rule, now time.Time) bool {
+	if rule.Throttle == nil {
+		return true
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	th := rule.Throttle
+
+	// Keep only the firings which are still relevant for either restriction.
+	keep := time.Duration(th.Cooldown)
+	if w := time.Duration(th.Window); w > keep {
+		keep = w
+	}
+
+	var recent []time.Time
+	for _, f := range fs.firings[ref] {
+		if now.Sub(f) < keep {
+			recent = append(recent, f)
+		}
+	}
+
+	if len(recent) > 0 && now.Sub(recent[len(recent)-1]) < time.Duration(th.Cooldown) {
+		fs.firings[ref] = recent
+		return false
+	}
+
+	if th.Limit > 0 && th.Window > 0 {
+		inWindow := 0
+		for _, f := range recent {
+			if now.Sub(f) < time.Duration(th.Window) {
+				inWindow++
+			}
+		}
+
+		if inWindow >= th.Limit {
+			fs.firings[ref] = recent
+			return false
+		}
+	}
+
+	fs.firings[ref] = append(recent, now)
+	return true
+}
+
+// remove drops recorded firings of the rule.
+func (fs *firingStore) remove(userId, ruleId string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.firings, ruleRef{userId, ruleId})
+}
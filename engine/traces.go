@@ -0,0 +1,95 @@
+package engine
+
+import "github.com/mainflux/mainflux/writer"
+
+// ConditionTrace explains evaluation of the single condition.
+type ConditionTrace struct {
+	Condition Condition `json:"condition"`
+
+	// Known indicates whether there is a reading of the referred property
+	// to evaluate condition against.
+	Known bool `json:"known"`
+
+	// Value is the value of the reading compared by the condition.
+	Value interface{} `json:"value,omitempty"`
+
+	// Satisfied indicates whether the condition is satisfied, including the
+	// duration for which it needs to stay satisfied.
+	Satisfied bool `json:"satisfied"`
+
+	// Pending indicates that the reading satisfies the condition, which has
+	// not stayed satisfied for its duration yet.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Trace explains evaluation of the rule triggered by the single event.
+type Trace struct {
+	Event writer.Message `json:"-"`
+
+	// Evaluated indicates whether the rule refers to the event and was
+	// therefore evaluated.
+	Evaluated bool `json:"evaluated"`
+
+	// Inactive indicates that the rule was not evaluated, since the event
+	// happened outside of the rule's schedule.
+	Inactive bool `json:"inactive,omitempty"`
+
+	Conditions []ConditionTrace `json:"conditions,omitempty"`
+	Matched    bool             `json:"matched"`
+
+	// Fired indicates whether the rule's actions are performed, according
+	// to its trigger mode and throttle.
+	Fired bool `json:"fired"`
+
+	// Throttled indicates whether firing of the rule was suppressed by its
+	// throttle.
+	Throttled bool `json:"throttled,omitempty"`
+
+	// Resolved indicates whether the rule went from matched to unmatched,
+	// performing the resolved actions.
+	Resolved bool `json:"resolved"`
+
+	// Actions lists the actions which are performed.
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// Explain evaluates rule against the single event, explaining the result
+// of each condition.
+func (rule Rule) Explain(event writer.Message) (bool, []ConditionTrace) {
+	return rule.explain(func(cnd Condition) ConditionTrace {
+		if !cnd.refersTo(event) {
+			return ConditionTrace{Condition: cnd}
+		}
+
+		v, ok := cnd.compared(event)
+		if !ok {
+			return ConditionTrace{Condition: cnd}
+		}
+
+		return ConditionTrace{Condition: cnd, Known: true, Value: v, Satisfied: cnd.isSatisfied(event)}
+	})
+}
+
+// explain evaluates all conditions of the rule using the specified check,
+// and then the rule itself using their results.
+func (rule Rule) explain(check func(Condition) ConditionTrace) (bool, []ConditionTrace) {
+	var traces []ConditionTrace
+	results := make(map[string]bool)
+
+	for _, cnd := range rule.AllConditions() {
+		key := conditionKey(cnd)
+		if _, ok := results[key]; ok {
+			continue
+		}
+
+		t := check(cnd)
+		results[key] = t.Satisfied
+		traces = append(traces, t)
+	}
+
+	matched := rule.evaluate(func(cnd Condition) bool {
+		return results[conditionKey(cnd)]
+	})
+
+	return matched, traces
+}
@@ -0,0 +1,79 @@
+package engine
+
+import "sync"
+
+// TriggerMode determines when the actions of the rule are performed.
+type TriggerMode string
+
+const (
+	// Level performs actions on every event matching the rule.
+	Level TriggerMode = "level"
+
+	// Rising performs actions when the rule becomes matched.
+	Rising TriggerMode = "rising"
+
+	// Falling performs actions when the rule stops being matched.
+	Falling TriggerMode = "falling"
+)
+
+// transition represents the change of the rule's matched state caused by
+// the single evaluation.
+type transition struct {
+	matched bool
+	changed bool
+}
+
+// fires checks whether actions of the rule should be performed for the
+// transition, according to the trigger mode.
+func (t transition) fires(mode TriggerMode) bool {
+	switch mode {
+	case Rising:
+		return t.changed && t.matched
+	case Falling:
+		return t.changed && !t.matched
+	default:
+		return t.matched
+	}
+}
+
+// resolves checks whether the rule went from matched back to unmatched.
+func (t transition) resolves() bool {
+	return t.changed && !t.matched
+}
+
+// matchStore keeps the last evaluation result of each rule.
+type matchStore struct {
+	mu      sync.Mutex
+	matched map[ruleRef]bool
+}
+
+func newMatchStore() *matchStore {
+	return &matchStore{
+		matched: make(map[ruleRef]bool),
+	}
+}
+
+// update records evaluation result of the rule. Rules are considered
+// unmatched until evaluated for the first time.
+func (ms *matchStore) update(rule Rule, matched bool) transition {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ref := ruleRef{rule.UserId, rule.ID}
+	changed := ms.matched[ref] != matched
+	if matched {
+		ms.matched[ref] = true
+	} else {
+		delete(ms.matched, ref)
+	}
+
+	return transition{matched, changed}
+}
+
+// remove drops evaluation result of the rule.
+func (ms *matchStore) remove(userId, ruleId string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.matched, ruleRef{userId, ruleId})
+}
@@ -0,0 +1,125 @@
+// Package webhook contains executor which performs rule actions by sending
+// HTTP requests to the external systems.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/mainflux/mainflux/writer"
+)
+
+const (
+	contentType string        = "application/json"
+	defTimeout  time.Duration = 10 * time.Second
+)
+
+// ErrInvalidBody indicates that rendered request body is not valid JSON.
+var ErrInvalidBody error = errors.New("webhook body is not valid JSON")
+
+// StatusError indicates that webhook responded with an unsuccessful status.
+type StatusError int
+
+func (code StatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", int(code))
+}
+
+// Permanent reports whether the request can not succeed if sent again, which
+// holds for all unsuccessful statuses except 5xx and 429.
+func (code StatusError) Permanent() bool {
+	return code < http.StatusInternalServerError && code != http.StatusTooManyRequests
+}
+
+var _ engine.ActionExecutor = (*executor)(nil)
+
+type executor struct {
+	client *http.Client
+}
+
+// NewExecutor instantiates executor which sends webhook requests using the
+// specified client. Failed requests are retried by the dispatcher, unless
+// they fail permanently, e.g. due to the invalid body or 4xx response.
+func NewExecutor(client *http.Client) engine.ActionExecutor {
+	return &executor{client}
+}
+
+func (e *executor) Execute(ctx context.Context, action engine.Action, event writer.Message, rule engine.Rule) error {
+	a, ok := action.(engine.WebhookAction)
+	if !ok {
+		return engine.ErrUnsupportedAction
+	}
+
+	body, err := requestBody(a, rule, event)
+	if err != nil {
+		return engine.Permanent(err)
+	}
+
+	return e.send(ctx, a, body)
+}
+
+func (e *executor) send(ctx context.Context, a engine.WebhookAction, body []byte) error {
+	timeout := time.Duration(a.Timeout)
+	if timeout == 0 {
+		timeout = defTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var payload io.Reader
+	if a.Method != http.MethodGet {
+		payload = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(a.Method, a.URL, payload)
+	if err != nil {
+		return engine.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range a.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return StatusError(res.StatusCode)
+	}
+
+	return nil
+}
+
+func requestBody(a engine.WebhookAction, rule engine.Rule, event writer.Message) ([]byte, error) {
+	data := engine.NewTemplateData(rule, event)
+	if a.Body == "" {
+		return json.Marshal(data)
+	}
+
+	body, err := engine.RenderTemplate(a.Body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(body) {
+		return nil, ErrInvalidBody
+	}
+
+	return body, nil
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MainfluxLabs/rules-engine/engine"
+	"github.com/mainflux/mainflux/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	rule = engine.Rule{
+		ID:         "1",
+		UserId:     "1",
+		Name:       "rule01",
+		Conditions: []engine.Condition{{DeviceID: "device", Property: "temp", Operator: engine.Gt, Type: engine.Numeric, Value: float64(30)}},
+	}
+	event = writer.Message{Publisher: "device", Name: "temp", Value: 25}
+)
+
+type request struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// serve starts server which responds with the specified status codes in
+// order, repeating the last one once they are exhausted.
+func serve(codes ...int) (*httptest.Server, chan request) {
+	requests := make(chan request, 10)
+	attempt := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{r.Method, r.Header, string(body)}
+
+		code := codes[len(codes)-1]
+		if attempt < len(codes) {
+			code = codes[attempt]
+		}
+		attempt++
+
+		w.WriteHeader(code)
+	}))
+
+	return srv, requests
+}
+
+func TestExecute(t *testing.T) {
+	cases := []struct {
+		desc      string
+		codes     []int
+		action    engine.WebhookAction
+		attempts  int
+		body      string
+		err       error
+		permanent bool
+	}{
+		{
+			desc:     "templated body",
+			codes:    []int{http.StatusOK},
+			action:   engine.WebhookAction{Method: http.MethodPost, Body: `{"text":"{{.Property}} of {{.Publisher}} is {{.Value}}"}`},
+			attempts: 1,
+			body:     `{"text":"temp of device is 25"}`,
+			err:      nil,
+		},
+		{
+			desc:     "json function",
+			codes:    []int{http.StatusCreated},
+			action:   engine.WebhookAction{Method: http.MethodPut, Body: `{"rule":{{json .RuleName}}}`},
+			attempts: 1,
+			body:     `{"rule":"rule01"}`,
+			err:      nil,
+		},
+		{
+			desc:      "server error",
+			codes:     []int{http.StatusBadGateway},
+			action:    engine.WebhookAction{Method: http.MethodPost, Body: `{}`},
+			attempts:  1,
+			body:      `{}`,
+			err:       StatusError(http.StatusBadGateway),
+			permanent: false,
+		},
+		{
+			desc:      "too many requests",
+			codes:     []int{http.StatusTooManyRequests},
+			action:    engine.WebhookAction{Method: http.MethodPost, Body: `{}`},
+			attempts:  1,
+			body:      `{}`,
+			err:       StatusError(http.StatusTooManyRequests),
+			permanent: false,
+		},
+		{
+			desc:      "client error",
+			codes:     []int{http.StatusBadRequest},
+			action:    engine.WebhookAction{Method: http.MethodPost, Body: `{}`},
+			attempts:  1,
+			body:      `{}`,
+			err:       StatusError(http.StatusBadRequest),
+			permanent: true,
+		},
+		{
+			desc:      "invalid body",
+			codes:     []int{http.StatusOK},
+			action:    engine.WebhookAction{Method: http.MethodPost, Body: `{"value": {{.Property}}}`},
+			attempts:  0,
+			err:       engine.Permanent(ErrInvalidBody),
+			permanent: true,
+		},
+		{
+			desc:      "invalid template",
+			codes:     []int{http.StatusOK},
+			action:    engine.WebhookAction{Method: http.MethodPost, Body: `{"value": {{.Missing}}}`},
+			attempts:  0,
+			permanent: true,
+		},
+	}
+
+	for _, tc := range cases {
+		srv, requests := serve(tc.codes...)
+		tc.action.URL = srv.URL
+		tc.action.Headers = map[string]string{"Authorization": "Bearer token"}
+
+		err := NewExecutor(http.DefaultClient).Execute(context.Background(), tc.action, event, rule)
+		srv.Close()
+		close(requests)
+
+		if tc.err != nil || !tc.permanent {
+			assert.Equal(t, tc.err, err, fmt.Sprintf("%s: unexpected error", tc.desc))
+		}
+		assert.Equal(t, tc.permanent, engine.IsPermanent(err), fmt.Sprintf("%s: unexpected permanence of error", tc.desc))
+		assert.Equal(t, tc.attempts, len(requests), fmt.Sprintf("%s: unexpected number of attempts", tc.desc))
+
+		for req := range requests {
+			assert.Equal(t, tc.action.Method, req.method, fmt.Sprintf("%s: unexpected method", tc.desc))
+			assert.Equal(t, "Bearer token", req.header.Get("Authorization"), fmt.Sprintf("%s: missing header", tc.desc))
+			assert.Equal(t, tc.body, req.body, fmt.Sprintf("%s: unexpected body", tc.desc))
+		}
+	}
+}
+
+func TestExecuteDefaultBody(t *testing.T) {
+	srv, requests := serve(http.StatusOK)
+	defer srv.Close()
+
+	action := engine.WebhookAction{URL: srv.URL, Method: http.MethodPost}
+	err := NewExecutor(http.DefaultClient).Execute(context.Background(), action, event, rule)
+	assert.Nil(t, err, "unexpected error")
+
+	var data engine.TemplateData
+	req := <-requests
+	json.Unmarshal([]byte(req.body), &data)
+
+	assert.Equal(t, contentType, req.header.Get("Content-Type"), "unexpected content type")
+	assert.Equal(t, engine.NewTemplateData(rule, event), data, "unexpected default body")
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	action := engine.WebhookAction{
+		URL:     srv.URL,
+		Method:  http.MethodPost,
+		Timeout: engine.Duration(10 * time.Millisecond),
+	}
+	err := NewExecutor(http.DefaultClient).Execute(context.Background(), action, event, rule)
+	assert.NotNil(t, err, "expected timeout error")
+}
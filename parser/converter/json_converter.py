@@ -2,6 +2,10 @@ import json
 
 _primitives = [int, float, str, unicode, bool]
 
+# Reading is the left-hand side of the DSL's ordering operators, while the
+# engine compares the value against the reading, so they are mirrored.
+_mirrored = {"<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
 
 def convert(model):
     return json.dumps(_general_handler(model), indent=4)
@@ -24,6 +28,8 @@ def _object_handler(to_handle):
 
 
 def _element_creator(source):
+    if "operator" == source[0]:
+        return {source[0]: _mirrored.get(source[1], source[1])}
     return {source[0]: _general_handler(source[1])}
 
 
@@ -10,7 +10,7 @@ converted_positive = """
         {
             "conditions": [
                 {
-                    "operator": ">=", 
+                    "operator": "<=", 
                     "parameter": {
                         "property": "temperature", 
                         "deviceId": "a32db207-7236-4e75-abad-7c972f4cfd18"
@@ -68,6 +68,11 @@ class JsonConverter(TestCase):
     def test_element_creator(self):
         self.assertEqual({"attribute": "value"}, converter._element_creator(("attribute", "value")))
 
+    def test_element_creator_operator(self):
+        self.assertEqual({"operator": "<"}, converter._element_creator(("operator", ">")))
+        self.assertEqual({"operator": ">="}, converter._element_creator(("operator", "<=")))
+        self.assertEqual({"operator": "BETWEEN"}, converter._element_creator(("operator", "BETWEEN")))
+
     def test_object_handler(self):
         self.assertEqual({"a": "test", "b": 0}, converter._object_handler(self.object_example))
 